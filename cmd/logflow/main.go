@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/logflow"
+)
+
+func main() {
+	configPath := flag.String("config", "config.yaml", "Path to the configuration file")
+	flag.Parse()
+
+	cfg, err := logflow.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Log anomalies unless the config chooses its own sinks
+	if len(cfg.Sinks) == 0 {
+		cfg.Sinks = []logflow.SinkConfig{{Type: "log"}}
+	}
+
+	opts := []logflow.Option{
+		logflow.WithFileSource(),
+		logflow.WithDashboard(),
+	}
+	if cfg.ForwardConfig.Enabled {
+		opts = append(opts, logflow.WithForwardSource())
+	}
+
+	pipeline, err := logflow.New(cfg, opts...)
+	if err != nil {
+		log.Fatalf("Failed to build pipeline: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("LogFlow watching %s (%s format)", watchTarget(cfg), cfg.LogFormat)
+	if err := pipeline.Run(ctx); err != nil {
+		log.Fatalf("Pipeline error: %v", err)
+	}
+	log.Printf("LogFlow stopped")
+}
+
+func watchTarget(cfg *logflow.Config) string {
+	if cfg.WatchConfig.Dir != "" {
+		return cfg.WatchConfig.Dir
+	}
+	return cfg.LogPath
+}
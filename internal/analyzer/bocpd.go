@@ -0,0 +1,287 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// Each run's predictive distribution is a mixture with a wide Cauchy
+// component, so one outlier ends up as an outlier of the current run rather
+// than the start of a new one. Values mostly explained by the outlier
+// component do not update the run's statistics.
+const (
+	outlierWeight      = 0.01
+	outlierScaleFactor = 10.0
+)
+
+// BOCPDOptions configures the Bayesian online changepoint detector, decoded
+// from detector.algorithm_options
+type BOCPDOptions struct {
+	HazardLambda float64 `yaml:"hazard_lambda"`  // Expected evaluations between changepoints
+	Threshold    float64 `yaml:"threshold"`      // Changepoint probability to alert at (0-1)
+	Delay        int     `yaml:"delay"`          // Run lengths below this count as a recent changepoint
+	MaxRunLength int     `yaml:"max_run_length"` // Longest run length tracked
+	Warmup       int     `yaml:"warmup"`         // Evaluations used to set each metric's prior
+}
+
+// DefaultBOCPDOptions returns the options used when none are configured
+func DefaultBOCPDOptions() BOCPDOptions {
+	return BOCPDOptions{
+		HazardLambda: 250,
+		Threshold:    0.5,
+		Delay:        5,
+		MaxRunLength: 500,
+		Warmup:       10,
+	}
+}
+
+// BOCPDDetector implements Bayesian online changepoint detection (Adams &
+// MacKay, 2007). For each metric it keeps a posterior over the run length,
+// the number of evaluations since the last changepoint, with a Normal-Gamma
+// model of every run so the predictive distribution learns the metric's own
+// mean and variance. The changepoint probability is the posterior mass on
+// runs shorter than Delay; no per-metric scale has to be configured.
+type BOCPDDetector struct {
+	options BOCPDOptions
+	hazard  float64
+	metrics []trackedMetric
+	models  []*changepointModel // One per tracked metric
+}
+
+// normalGamma holds the posterior parameters for one run length
+type normalGamma struct {
+	mean  float64
+	kappa float64
+	alpha float64
+	beta  float64
+}
+
+// changepointModel is the run-length posterior for one metric
+type changepointModel struct {
+	warmup []float64
+	prior  normalGamma
+	probs  []float64     // P(run length = i | data)
+	params []normalGamma // Posterior for each run length
+
+	changing bool    // Probability is above the threshold
+	expected float64 // Mean of the most probable run before the change
+	stdDev   float64
+}
+
+// NewBOCPDDetector creates a Bayesian online changepoint detector
+func NewBOCPDDetector(options BOCPDOptions) (*BOCPDDetector, error) {
+	if options.HazardLambda <= 1 {
+		return nil, fmt.Errorf("hazard_lambda must be greater than 1, got %v", options.HazardLambda)
+	}
+	if options.Threshold <= 0 || options.Threshold >= 1 {
+		return nil, fmt.Errorf("threshold must be between 0 and 1, got %v", options.Threshold)
+	}
+	if options.Delay < 1 || options.MaxRunLength <= options.Delay {
+		return nil, fmt.Errorf("delay must be at least 1 and below max_run_length, got %d and %d", options.Delay, options.MaxRunLength)
+	}
+	if options.Warmup < 2 || options.Warmup <= options.Delay {
+		return nil, fmt.Errorf("warmup must be at least 2 and above delay, got %d", options.Warmup)
+	}
+
+	detector := &BOCPDDetector{options: options, hazard: 1 / options.HazardLambda}
+	detector.track(trackedMetrics)
+	return detector, nil
+}
+
+// track sets the metrics modelled, each starting from scratch
+func (d *BOCPDDetector) track(metrics []trackedMetric) {
+	d.metrics = metrics
+	d.models = make([]*changepointModel, len(metrics))
+	for i := range metrics {
+		d.models[i] = &changepointModel{}
+	}
+}
+
+func (d *BOCPDDetector) Detect(current *models.Metrics, historical []models.Metrics) []models.Anomaly {
+	anomalies := []models.Anomaly{}
+
+	for i, metric := range d.metrics {
+		model := d.models[i]
+		value := metric.value(current)
+
+		probability, ok := model.observe(value, d.hazard, d.options)
+		if !ok {
+			continue
+		}
+
+		if probability < d.options.Threshold {
+			model.changing = false
+			model.expected, model.stdDev = model.mostProbableRun()
+			continue
+		}
+		if model.changing {
+			continue // Already reported this changepoint
+		}
+		model.changing = true
+
+		residual := value - model.expected
+		if metric.increasesOnly && residual <= 0 {
+			continue
+		}
+
+		anomalies = append(anomalies, models.Anomaly{
+			Timestamp:     time.Now(),
+			Type:          metric.anomalyType,
+			Severity:      calculateSeverity(value, model.expected, model.stdDev),
+			Description:   fmt.Sprintf("%s (changepoint probability %.2f)", metric.description, probability),
+			Metric:        metric.name,
+			ActualValue:   value,
+			ExpectedValue: model.expected,
+			Deviation:     math.Abs(residual),
+			Probability:   probability,
+		})
+	}
+
+	return anomalies
+}
+
+// observe updates the run-length posterior with a value and returns the
+// changepoint probability. It returns false while the prior is warming up.
+func (m *changepointModel) observe(value, hazard float64, options BOCPDOptions) (float64, bool) {
+	if m.probs == nil {
+		m.warmup = append(m.warmup, value)
+		if len(m.warmup) < options.Warmup {
+			return 0, false
+		}
+		m.start()
+		return 0, false
+	}
+
+	// Grow every run by one, or end it with probability hazard
+	next := make([]float64, len(m.probs)+1)
+	nextParams := make([]normalGamma, len(m.params)+1)
+	nextParams[0] = m.prior
+	total := 0.0
+	for i, p := range m.probs {
+		if p == 0 {
+			continue
+		}
+		density, inlierShare := m.params[i].predictive(value)
+		weighted := p * density
+		next[0] += weighted * hazard
+		next[i+1] = weighted * (1 - hazard)
+		nextParams[i+1] = m.params[i]
+		if inlierShare >= 0.5 {
+			nextParams[i+1] = m.params[i].update(value)
+		}
+		total += weighted
+	}
+
+	// Every run found the value impossible; start again from the prior
+	if total == 0 || math.IsNaN(total) {
+		m.probs = []float64{1}
+		m.params = []normalGamma{m.prior.update(value)}
+		return 1, true
+	}
+
+	// Fold the longest run into the one below it to bound the state
+	if last := len(next) - 1; last >= options.MaxRunLength {
+		next[last-1] += next[last]
+		nextParams[last-1] = nextParams[last]
+		next, nextParams = next[:last], nextParams[:last]
+	}
+	normalize(next)
+	m.probs, m.params = next, nextParams
+
+	probability := 0.0
+	for i := 0; i < options.Delay && i < len(m.probs); i++ {
+		probability += m.probs[i]
+	}
+	return probability, true
+}
+
+// start sets the prior from the warmup values, so each metric is modelled
+// on its own scale
+func (m *changepointModel) start() {
+	mean, stdDev := 0.0, 0.0
+	for _, v := range m.warmup {
+		mean += v
+	}
+	mean /= float64(len(m.warmup))
+	for _, v := range m.warmup {
+		stdDev += (v - mean) * (v - mean)
+	}
+	stdDev = math.Sqrt(stdDev / float64(len(m.warmup)-1))
+
+	// A flat warmup still needs some spread to compare against
+	if floor := 1e-3 * math.Max(math.Abs(mean), 1e-3); stdDev < floor {
+		stdDev = floor
+	}
+
+	// The warmup is the first run; later runs start from the prior
+	m.prior = normalGamma{mean: mean, kappa: 1, alpha: 1, beta: stdDev * stdDev}
+	m.probs = make([]float64, len(m.warmup)+1)
+	m.params = make([]normalGamma, len(m.warmup)+1)
+	run := m.prior
+	for _, v := range m.warmup {
+		run = run.update(v)
+	}
+	m.probs[len(m.warmup)] = 1
+	m.params[len(m.warmup)] = run
+	m.expected, m.stdDev = mean, stdDev
+	m.warmup = nil
+}
+
+// mostProbableRun returns the mean and noise standard deviation of the run
+// length with the highest posterior probability
+func (m *changepointModel) mostProbableRun() (float64, float64) {
+	best := 0
+	for i, p := range m.probs {
+		if p > m.probs[best] {
+			best = i
+		}
+	}
+	params := m.params[best]
+	return params.mean, math.Sqrt(params.beta / params.alpha)
+}
+
+// update returns the posterior after observing a value
+func (ng normalGamma) update(value float64) normalGamma {
+	diff := value - ng.mean
+	return normalGamma{
+		mean:  (ng.kappa*ng.mean + value) / (ng.kappa + 1),
+		kappa: ng.kappa + 1,
+		alpha: ng.alpha + 0.5,
+		beta:  ng.beta + ng.kappa*diff*diff/(2*(ng.kappa+1)),
+	}
+}
+
+// predictive returns the posterior predictive density of a value and the
+// share of it explained by the run's Student's t distribution rather than
+// the outlier component
+func (ng normalGamma) predictive(value float64) (float64, float64) {
+	df := 2 * ng.alpha
+	scale2 := ng.beta * (ng.kappa + 1) / (ng.alpha * ng.kappa)
+	diff := value - ng.mean
+
+	lgammaHigh, _ := math.Lgamma((df + 1) / 2)
+	lgammaLow, _ := math.Lgamma(df / 2)
+	logDensity := lgammaHigh - lgammaLow - 0.5*math.Log(df*math.Pi*scale2) -
+		(df+1)/2*math.Log1p(diff*diff/(df*scale2))
+	inlier := (1 - outlierWeight) * math.Exp(logDensity)
+
+	outlierScale := outlierScaleFactor * math.Sqrt(scale2)
+	outlier := outlierWeight / (math.Pi * outlierScale * (1 + diff*diff/(outlierScale*outlierScale)))
+
+	density := inlier + outlier
+	return density, inlier / density
+}
+
+// normalize scales probabilities to sum to one
+func normalize(probs []float64) {
+	total := 0.0
+	for _, p := range probs {
+		total += p
+	}
+	for i := range probs {
+		probs[i] /= total
+	}
+}
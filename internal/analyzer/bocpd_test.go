@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// runBOCPD feeds windows to the detector and returns the anomalies per step
+func runBOCPD(detector DetectionAlgorithm, windows []*models.Metrics) [][]models.Anomaly {
+	results := make([][]models.Anomaly, len(windows))
+	for i, metrics := range windows {
+		results[i] = detector.Detect(metrics, nil)
+	}
+	return results
+}
+
+// createShiftWindows creates windows whose request and error rates shift
+// at step 200, with Gaussian noise proportional to each metric's scale
+func createShiftWindows(reqBefore, reqAfter, errBefore, errAfter float64) []*models.Metrics {
+	rng := rand.New(rand.NewSource(1))
+	windows := make([]*models.Metrics, 260)
+	for i := range windows {
+		reqPerSec, errorRate := reqBefore, errBefore
+		if i >= 200 {
+			reqPerSec, errorRate = reqAfter, errAfter
+		}
+		reqPerSec += rng.NormFloat64() * reqBefore * 0.01
+		errorRate += rng.NormFloat64() * errBefore * 0.1
+		windows[i] = createTestMetrics(reqPerSec, errorRate, 50.0)
+	}
+	return windows
+}
+
+// TestBOCPDDetector_ScaleFree tests that shifts are found on metrics of very
+// different scales with the default options
+func TestBOCPDDetector_ScaleFree(t *testing.T) {
+	algo, err := NewAlgorithm("bocpd", config.DetectorConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create bocpd: %v", err)
+	}
+
+	results := runBOCPD(algo, createShiftWindows(5000, 5300, 0.01, 0.02))
+
+	found := map[string]int{}
+	for step, anomalies := range results {
+		for _, anomaly := range anomalies {
+			if step < 200 {
+				t.Fatalf("Expected no changepoints before the shift, got %+v at step %d", anomaly, step)
+			}
+			if found[anomaly.Metric] > 0 {
+				t.Errorf("Expected one report per changepoint, %s reported again at step %d", anomaly.Metric, step)
+				continue
+			}
+			found[anomaly.Metric] = step
+			if anomaly.Probability < 0.5 {
+				t.Errorf("Expected changepoint probability above the threshold, got %v", anomaly.Probability)
+			}
+		}
+	}
+
+	for _, metric := range []string{"requests_per_sec", "error_rate"} {
+		step, ok := found[metric]
+		if !ok {
+			t.Errorf("Expected a changepoint for %s", metric)
+		} else if step > 205 {
+			t.Errorf("Expected %s changepoint within 5 windows of the shift, found at %d", metric, step)
+		}
+	}
+}
+
+// TestBOCPDDetector_SingleSpike tests that an isolated outlier is not
+// reported as a changepoint
+func TestBOCPDDetector_SingleSpike(t *testing.T) {
+	detector, err := NewBOCPDDetector(DefaultBOCPDOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	windows := createShiftWindows(100, 100, 0.05, 0.05)
+	windows[150].RequestsPerSec = 130
+
+	for step, anomalies := range runBOCPD(detector, windows) {
+		if len(anomalies) > 0 {
+			t.Fatalf("Expected no changepoints, got %+v at step %d", anomalies[0], step)
+		}
+	}
+}
+
+// TestNewBOCPDDetector_Validation tests option validation
+func TestNewBOCPDDetector_Validation(t *testing.T) {
+	options := DefaultBOCPDOptions()
+	options.HazardLambda = 0
+	if _, err := NewBOCPDDetector(options); err == nil {
+		t.Error("Expected error for hazard_lambda of 0")
+	}
+
+	options = DefaultBOCPDOptions()
+	options.Delay = options.MaxRunLength
+	if _, err := NewBOCPDDetector(options); err == nil {
+		t.Error("Expected error for delay not below max_run_length")
+	}
+}
@@ -0,0 +1,91 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// BotSurgeDetector flags windows where the share of automated clients
+// (crawlers, tools, headless browsers, scanners, missing user agents) rises
+// well above its baseline. It relies on user-agent enrichment.
+type BotSurgeDetector struct {
+	sensitivity float64 // Standard deviations above the baseline bot rate
+	minIncrease float64 // Minimum absolute increase in bot rate (0-1)
+	minRequests int     // Minimum classified requests in the current window
+}
+
+// NewBotSurgeDetector creates a bot surge detector
+func NewBotSurgeDetector(sensitivity, minIncrease float64, minRequests int) *BotSurgeDetector {
+	// Default values if not specified
+	if sensitivity <= 0 {
+		sensitivity = 2.0
+	}
+	if minIncrease <= 0 || minIncrease >= 1 {
+		minIncrease = 0.15
+	}
+	if minRequests <= 0 {
+		minRequests = 20
+	}
+
+	return &BotSurgeDetector{
+		sensitivity: sensitivity,
+		minIncrease: minIncrease,
+		minRequests: minRequests,
+	}
+}
+
+func (d *BotSurgeDetector) Detect(current *models.Metrics, historical []models.Metrics) []models.Anomaly {
+	anomalies := []models.Anomaly{}
+
+	baseline := baselineWindows(current, historical)
+	if len(baseline) < 10 {
+		return anomalies // Not enough data for baseline
+	}
+
+	if sumCounts(current.ClientClasses) < d.minRequests {
+		return anomalies
+	}
+
+	mean, stdDev := calculateStats(baseline, func(m models.Metrics) float64 {
+		return m.BotRate
+	})
+	increase := current.BotRate - mean
+	if increase < d.minIncrease || increase <= d.sensitivity*stdDev {
+		return anomalies
+	}
+
+	description := "Surge in automated traffic"
+	if class, count := dominantBotClass(current.ClientClasses); count > 0 {
+		description = fmt.Sprintf("Surge in automated traffic (mostly %s)", class)
+	}
+
+	anomalies = append(anomalies, models.Anomaly{
+		Timestamp:     time.Now(),
+		Type:          models.AnomalyTypeBotSurge,
+		Severity:      calculateShareShiftSeverity(increase, d.minIncrease),
+		Description:   description,
+		Metric:        "bot_rate",
+		ActualValue:   current.BotRate,
+		ExpectedValue: mean,
+		Deviation:     math.Abs(increase),
+	})
+
+	return anomalies
+}
+
+// dominantBotClass returns the most common non-human client class
+func dominantBotClass(classes map[string]int) (string, int) {
+	best, bestCount := "", 0
+	for class, count := range classes {
+		if class == "human" {
+			continue
+		}
+		if count > bestCount || (count == bestCount && class < best) {
+			best, bestCount = class, count
+		}
+	}
+	return best, bestCount
+}
@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// createBotMetrics creates metrics with the given human and bot counts
+func createBotMetrics(timestamp time.Time, human, crawler, scanner int) models.Metrics {
+	metrics := *createTestMetrics(100.0, 0.05, 50.0)
+	metrics.Timestamp = timestamp
+	metrics.ClientClasses = map[string]int{"human": human, "crawler": crawler, "scanner": scanner}
+	metrics.BotRate = float64(crawler+scanner) / float64(human+crawler+scanner)
+	return metrics
+}
+
+// TestBotSurgeDetector_Surge tests detection of a rise in automated traffic
+func TestBotSurgeDetector_Surge(t *testing.T) {
+	detector := NewBotSurgeDetector(2.0, 0.15, 20)
+
+	start := time.Now()
+	historical := make([]models.Metrics, 0, 20)
+	for i := 0; i < 20; i++ {
+		historical = append(historical, createBotMetrics(start.Add(time.Duration(i)*time.Second), 90-i%3, 10+i%3, 0))
+	}
+
+	normal := createBotMetrics(start.Add(20*time.Second), 89, 11, 0)
+	if anomalies := detector.Detect(&normal, historical); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies for normal bot rate, got %d", len(anomalies))
+	}
+
+	surge := createBotMetrics(start.Add(21*time.Second), 40, 10, 50)
+	anomalies := detector.Detect(&surge, historical)
+
+	if len(anomalies) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %d", len(anomalies))
+	}
+	if anomalies[0].Type != models.AnomalyTypeBotSurge {
+		t.Errorf("Expected bot_surge anomaly, got %s", anomalies[0].Type)
+	}
+	if anomalies[0].Description != "Surge in automated traffic (mostly scanner)" {
+		t.Errorf("Unexpected description: %s", anomalies[0].Description)
+	}
+}
+
+// TestMetricsCollector_ClientClasses tests bot rate and UA family aggregation
+func TestMetricsCollector_ClientClasses(t *testing.T) {
+	collector := NewMetricsCollector(100)
+
+	add := func(class, browser string) {
+		entry := createTestLogEntry(200, "/", 10)
+		entry.Extra = map[string]interface{}{
+			models.ExtraUAClass:   class,
+			models.ExtraUABrowser: browser,
+			models.ExtraUAOS:      "Linux",
+			models.ExtraUADevice:  "desktop",
+		}
+		collector.AddLogEntry(entry)
+	}
+	add("human", "Firefox")
+	add("human", "Firefox")
+	add("human", "Firefox")
+	add("tool", "curl")
+	collector.AddLogEntry(createTestLogEntry(200, "/", 10)) // Not classified
+
+	metrics := collector.GetCurrentMetrics()
+
+	if metrics.BotRate != 0.25 {
+		t.Errorf("Expected bot rate 0.25, got %f", metrics.BotRate)
+	}
+	if metrics.ClientClasses["tool"] != 1 || metrics.ClientClasses["human"] != 3 {
+		t.Errorf("Unexpected client classes: %v", metrics.ClientClasses)
+	}
+	if len(metrics.TopUAFamilies) != 2 || metrics.TopUAFamilies[0].UserAgent != "Firefox / Linux / desktop" {
+		t.Errorf("Unexpected UA families: %+v", metrics.TopUAFamilies)
+	}
+}
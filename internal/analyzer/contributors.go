@@ -0,0 +1,294 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// Defaults for detector.contributors
+const (
+	defaultMaxContributors = 5
+	defaultMinContribution = 0.1
+
+	// maxBreakdownValues is how many values per dimension a window keeps for
+	// each of requests, errors and response time
+	maxBreakdownValues = 20
+)
+
+// breakdownDimensions are the fields anomalies are broken down by, in the
+// order contributors are reported when their shares tie
+var breakdownDimensions = []struct {
+	name string
+	key  func(entry *models.LogEntry) string
+}{
+	{"path", func(entry *models.LogEntry) string { return entry.Path }},
+	{"status", func(entry *models.LogEntry) string {
+		if entry.StatusCode <= 0 {
+			return ""
+		}
+		return strconv.Itoa(entry.StatusCode)
+	}},
+	{"ip", func(entry *models.LogEntry) string { return entry.IPAddress }},
+	{"user_agent", func(entry *models.LogEntry) string { return entry.UserAgent }},
+	{"source", func(entry *models.LogEntry) string { return entry.Source }},
+}
+
+// breakdownCounts are a dimension value's totals in a window
+type breakdownCounts struct {
+	requests     int
+	errors       int
+	responseTime float64
+}
+
+// addBreakdowns counts an entry under its value of each dimension
+func (w *MetricsWindow) addBreakdowns(entry *models.LogEntry) {
+	for _, dimension := range breakdownDimensions {
+		value := dimension.key(entry)
+		if value == "" {
+			continue
+		}
+		values, ok := w.breakdowns[dimension.name]
+		if !ok {
+			values = make(map[string]*breakdownCounts)
+			w.breakdowns[dimension.name] = values
+		}
+		counts, ok := values[value]
+		if !ok {
+			counts = &breakdownCounts{}
+			values[value] = counts
+		}
+		counts.requests++
+		if isErrorEntry(entry) {
+			counts.errors++
+		}
+		counts.responseTime += entry.ResponseTime
+	}
+}
+
+// topBreakdowns keeps, for each dimension, the values with the most
+// requests, errors or total response time
+func topBreakdowns(breakdowns map[string]map[string]*breakdownCounts) map[string][]models.BreakdownCount {
+	if len(breakdowns) == 0 {
+		return nil
+	}
+
+	result := make(map[string][]models.BreakdownCount, len(breakdowns))
+	for dimension, values := range breakdowns {
+		all := make([]models.BreakdownCount, 0, len(values))
+		for value, counts := range values {
+			all = append(all, models.BreakdownCount{
+				Value:        value,
+				Requests:     counts.requests,
+				Errors:       counts.errors,
+				ResponseTime: counts.responseTime,
+			})
+		}
+
+		kept := make(map[string]bool)
+		var top []models.BreakdownCount
+		for _, name := range []string{"requests", "errors", "latency"} {
+			quantity := contributionQuantities[name]
+			sort.Slice(all, func(i, j int) bool {
+				if quantity.value(all[i]) != quantity.value(all[j]) {
+					return quantity.value(all[i]) > quantity.value(all[j])
+				}
+				return all[i].Value < all[j].Value
+			})
+			for i := 0; i < len(all) && i < maxBreakdownValues; i++ {
+				if !kept[all[i].Value] {
+					kept[all[i].Value] = true
+					top = append(top, all[i])
+				}
+			}
+		}
+		result[dimension] = top
+	}
+	return result
+}
+
+// contributionQuantity is what an anomaly is an excess of
+type contributionQuantity struct {
+	name   string // Used in descriptions, e.g. "extra errors"
+	value  func(counts models.BreakdownCount) float64
+	scaled bool // Baseline scaled by the change in overall traffic
+}
+
+var contributionQuantities = map[string]contributionQuantity{
+	"errors":   {"errors", func(c models.BreakdownCount) float64 { return float64(c.Errors) }, true},
+	"requests": {"requests", func(c models.BreakdownCount) float64 { return float64(c.Requests) }, false},
+	"latency":  {"latency", func(c models.BreakdownCount) float64 { return c.ResponseTime }, true},
+}
+
+// quantityFor returns the quantity an anomaly type is broken down by; the
+// boolean is false for types without a breakdown, such as log patterns
+func quantityFor(anomalyType models.AnomalyType) (contributionQuantity, bool) {
+	switch anomalyType {
+	case models.AnomalyTypeErrorRate, models.AnomalyTypeStatusCode:
+		return contributionQuantities["errors"], true
+	case models.AnomalyTypeTrafficSpike, models.AnomalyTypeGeoShift, models.AnomalyTypeBotSurge:
+		return contributionQuantities["requests"], true
+	case models.AnomalyTypeResponseTime:
+		return contributionQuantities["latency"], true
+	}
+	return contributionQuantity{}, false
+}
+
+// resolveContributors applies the defaults to the contributor settings
+func resolveContributors(cfg config.ContributorsConfig) config.ContributorsConfig {
+	if cfg.MaxContributors <= 0 {
+		cfg.MaxContributors = defaultMaxContributors
+	}
+	if cfg.MinShare <= 0 || cfg.MinShare > 1 {
+		cfg.MinShare = defaultMinContribution
+	}
+	return cfg
+}
+
+// attachContributors ranks, for each anomaly, the dimension values that
+// account for most of its excess over the baseline windows, and names the
+// leading ones in its description
+func attachContributors(anomalies []models.Anomaly, current *models.Metrics, historical []models.Metrics, cfg config.ContributorsConfig) {
+	baseline := baselineWindows(current, historical)
+	if len(baseline) == 0 || len(current.Breakdowns) == 0 {
+		return
+	}
+
+	for i := range anomalies {
+		quantity, ok := quantityFor(anomalies[i].Type)
+		if !ok {
+			continue
+		}
+		drop := anomalies[i].ActualValue < anomalies[i].ExpectedValue
+		contributors := rankContributors(current, baseline, quantity, drop, cfg)
+		if len(contributors) == 0 {
+			continue
+		}
+		anomalies[i].Contributors = contributors
+		anomalies[i].Description = fmt.Sprintf("%s (%s)", anomalies[i].Description, describeContributors(contributors, quantity, drop))
+	}
+}
+
+// rankContributors compares each value's quantity in the current window
+// with its baseline mean. A value's share is its part of the dimension's
+// total excess (or, for drops, deficit); it over-contributes when that share
+// is above its share of the traffic, so a value carrying all traffic, such
+// as the only source, is not listed.
+func rankContributors(current *models.Metrics, baseline []models.Metrics, quantity contributionQuantity, drop bool, cfg config.ContributorsConfig) []models.Contributor {
+	scale := 1.0
+	if quantity.scaled {
+		if mean, _ := calculateStats(baseline, func(m models.Metrics) float64 { return m.RequestsPerSec }); mean > 0 {
+			scale = current.RequestsPerSec / mean
+		}
+	}
+
+	var contributors []models.Contributor
+	for _, dimension := range breakdownDimensions {
+		actual := make(map[string]float64)
+		for _, counts := range current.Breakdowns[dimension.name] {
+			actual[counts.Value] = quantity.value(counts)
+		}
+		expected := make(map[string]float64)
+		for i := range baseline {
+			for _, counts := range baseline[i].Breakdowns[dimension.name] {
+				expected[counts.Value] += quantity.value(counts) / float64(len(baseline)) * scale
+			}
+		}
+
+		// Traffic before a drop, or including a rise
+		traffic := make(map[string]float64)
+		trafficTotal := 0.0
+		windows := []models.Metrics{*current}
+		if drop {
+			windows = baseline
+		}
+		for i := range windows {
+			for _, counts := range windows[i].Breakdowns[dimension.name] {
+				traffic[counts.Value] += float64(counts.Requests)
+				trafficTotal += float64(counts.Requests)
+			}
+		}
+
+		values := make(map[string]bool, len(actual)+len(expected))
+		for value := range actual {
+			values[value] = true
+		}
+		for value := range expected {
+			values[value] = true
+		}
+
+		changes := make(map[string]float64)
+		total := 0.0
+		for value := range values {
+			change := actual[value] - expected[value]
+			if drop {
+				change = -change
+			}
+			if change > 0 {
+				changes[value] = change
+				total += change
+			}
+		}
+		if total == 0 {
+			continue
+		}
+
+		for value, change := range changes {
+			share := change / total
+			if share < cfg.MinShare || (trafficTotal > 0 && share <= traffic[value]/trafficTotal) {
+				continue
+			}
+			contributors = append(contributors, models.Contributor{
+				Dimension: dimension.name,
+				Value:     value,
+				Share:     share,
+				Actual:    actual[value],
+				Expected:  expected[value],
+			})
+		}
+	}
+
+	order := make(map[string]int, len(breakdownDimensions))
+	for i, dimension := range breakdownDimensions {
+		order[dimension.name] = i
+	}
+	sort.Slice(contributors, func(i, j int) bool {
+		a, b := contributors[i], contributors[j]
+		if a.Share != b.Share {
+			return a.Share > b.Share
+		}
+		if a.Dimension != b.Dimension {
+			return order[a.Dimension] < order[b.Dimension]
+		}
+		return a.Value < b.Value
+	})
+	if len(contributors) > cfg.MaxContributors {
+		contributors = contributors[:cfg.MaxContributors]
+	}
+	return contributors
+}
+
+// describeContributors summarizes the leading contributor and the next one
+// from another dimension, e.g. "82% of extra errors from path /api/checkout,
+// 90% from status 502"
+func describeContributors(contributors []models.Contributor, quantity contributionQuantity, drop bool) string {
+	direction := "extra"
+	if drop {
+		direction = "missing"
+	}
+
+	lead := contributors[0]
+	parts := []string{fmt.Sprintf("%.0f%% of %s %s from %s %s", math.Round(lead.Share*100), direction, quantity.name, lead.Dimension, lead.Value)}
+	for _, contributor := range contributors[1:] {
+		if contributor.Dimension != lead.Dimension {
+			parts = append(parts, fmt.Sprintf("%.0f%% from %s %s", math.Round(contributor.Share*100), contributor.Dimension, contributor.Value))
+			break
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,104 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// checkoutWindow adds a window of 100 requests from 10 IPs: 50 to
+// /api/users with one 500, 30 to /api/orders and 20 to /api/checkout. The
+// given number of checkout and orders requests fail with 502 and 503.
+func checkoutWindow(collector *MetricsCollector, checkoutErrors, ordersErrors int) *models.Metrics {
+	add := func(path string, count, errors, status int) {
+		for i := 0; i < count; i++ {
+			code := 200
+			if i < errors {
+				code = status
+			}
+			entry := createTestLogEntry(code, path, 20)
+			entry.IPAddress = fmt.Sprintf("10.0.0.%d", i%10)
+			collector.AddLogEntry(entry)
+		}
+	}
+	add("/api/users", 50, 1, 500)
+	add("/api/orders", 30, ordersErrors, 503)
+	add("/api/checkout", 20, checkoutErrors, 502)
+
+	metrics := collector.GetCurrentMetrics()
+	metrics.RequestsPerSec = 100
+	return metrics
+}
+
+// TestAttachContributors tests that extra errors are attributed to the path
+// and status responsible, not to values that carry all traffic
+func TestAttachContributors(t *testing.T) {
+	collector := NewMetricsCollector(100)
+	for i := 0; i < 20; i++ {
+		checkoutWindow(collector, 0, 0)
+	}
+	current := checkoutWindow(collector, 8, 2)
+	historical := collector.GetHistoricalMetrics()
+	for i := range historical {
+		historical[i].RequestsPerSec = 100
+	}
+
+	anomalies := []models.Anomaly{
+		{Type: models.AnomalyTypeErrorRate, Description: "Abnormal error rate detected", ActualValue: 0.11, ExpectedValue: 0.01},
+		{Type: models.AnomalyTypePattern, Description: "New log pattern"},
+	}
+	attachContributors(anomalies, current, historical, resolveContributors(config.ContributorsConfig{Enabled: true}))
+
+	contributors := anomalies[0].Contributors
+	// /api/orders has 20% of the extra errors but 30% of the traffic, and
+	// every request has the same user agent
+	for _, contributor := range contributors {
+		if contributor.Value == "/api/orders" || contributor.Dimension == "user_agent" {
+			t.Errorf("Unexpected contributor %+v", contributor)
+		}
+	}
+	if len(contributors) != 5 {
+		t.Fatalf("Expected 5 contributors, got %+v", contributors)
+	}
+	lead := contributors[0]
+	if lead.Dimension != "path" || lead.Value != "/api/checkout" || lead.Actual != 8 || lead.Expected != 0 {
+		t.Errorf("Expected /api/checkout to lead, got %+v", lead)
+	}
+	if contributors[1].Dimension != "status" || contributors[1].Value != "502" || contributors[1].Share != 0.8 {
+		t.Errorf("Expected status 502 with 80%%, got %+v", contributors[1])
+	}
+	expected := "Abnormal error rate detected (80% of extra errors from path /api/checkout, 80% from status 502)"
+	if anomalies[0].Description != expected {
+		t.Errorf("Expected %q, got %q", expected, anomalies[0].Description)
+	}
+
+	if anomalies[1].Contributors != nil || anomalies[1].Description != "New log pattern" {
+		t.Errorf("Expected no contributors for patterns, got %+v", anomalies[1])
+	}
+}
+
+// TestAttachContributors_Drop tests that a traffic drop is attributed to the
+// path that went missing
+func TestAttachContributors_Drop(t *testing.T) {
+	collector := NewMetricsCollector(100)
+	for i := 0; i < 20; i++ {
+		checkoutWindow(collector, 0, 0)
+	}
+	for i := 0; i < 50; i++ {
+		collector.AddLogEntry(createTestLogEntry(200, "/api/users", 20))
+	}
+	current := collector.GetCurrentMetrics()
+
+	anomalies := []models.Anomaly{{Type: models.AnomalyTypeTrafficSpike, Description: "Traffic spike or drop detected", ActualValue: 50, ExpectedValue: 100}}
+	attachContributors(anomalies, current, collector.GetHistoricalMetrics(), resolveContributors(config.ContributorsConfig{Enabled: true}))
+
+	if len(anomalies[0].Contributors) == 0 || anomalies[0].Contributors[0].Value != "/api/orders" {
+		t.Fatalf("Expected /api/orders to lead, got %+v", anomalies[0].Contributors)
+	}
+	if !strings.Contains(anomalies[0].Description, "60% of missing requests from path /api/orders") {
+		t.Errorf("Unexpected description %q", anomalies[0].Description)
+	}
+}
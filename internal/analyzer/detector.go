@@ -2,10 +2,13 @@ package analyzer
 
 import (
 	"context"
+	"fmt"
 	"math"
+	"sync"
 	"time"
 
 	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/internal/registry"
 	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
 )
 
@@ -14,6 +17,16 @@ type AnomalyDetector struct {
 	config           config.DetectorConfig
 	metricsCollector *MetricsCollector
 	algorithm        DetectionAlgorithm
+	additional       []DetectionAlgorithm // Run alongside algorithm, e.g. geo shift detection
+	dimensions       []*dimensionTracker  // Per-key series, e.g. per path
+	patterns         *patternTracker      // Log templates; nil when disabled
+	relatedLogs      config.RelatedLogsConfig
+	contributors     config.ContributorsConfig
+	incidents        *incidentManager // nil when anomalies are emitted individually
+
+	statsMu      sync.Mutex
+	stats        models.DetectorStats
+	totalLatency time.Duration
 }
 
 // DetectionAlgorithm interface for different detection strategies
@@ -21,27 +34,161 @@ type DetectionAlgorithm interface {
 	Detect(metrics *models.Metrics, historical []models.Metrics) []models.Anomaly
 }
 
-// NewAnomalyDetector creates a new anomaly detector
-func NewAnomalyDetector(cfg config.DetectorConfig) *AnomalyDetector {
-	var algo DetectionAlgorithm
-	switch cfg.Algorithm {
-	case "moving_average":
-		algo = NewMovingAverageDetector(cfg.SensitivityLevel, cfg.SmoothingFactor)
-	case "cusum":
-		algo = NewCUSUMDetector(cfg.CUSUMSlack, cfg.CUSUMThreshold)
-	default:
-		algo = &StdDevDetector{threshold: cfg.SensitivityLevel}
+// AlgorithmFactory creates a detection algorithm from the detector settings,
+// decoding any algorithm-specific options
+type AlgorithmFactory func(cfg config.DetectorConfig, decode registry.DecodeFunc) (DetectionAlgorithm, error)
+
+var algorithms = registry.New[AlgorithmFactory]("detection algorithm")
+
+func init() {
+	RegisterAlgorithm("stddev", func(cfg config.DetectorConfig, _ registry.DecodeFunc) (DetectionAlgorithm, error) {
+		return &StdDevDetector{threshold: cfg.SensitivityLevel}, nil
+	})
+	RegisterAlgorithm("moving_average", func(cfg config.DetectorConfig, _ registry.DecodeFunc) (DetectionAlgorithm, error) {
+		return NewMovingAverageDetector(cfg.SensitivityLevel, cfg.SmoothingFactor), nil
+	})
+	RegisterAlgorithm("cusum", func(cfg config.DetectorConfig, _ registry.DecodeFunc) (DetectionAlgorithm, error) {
+		detector := NewCUSUMDetector(cfg.CUSUMSlack, cfg.CUSUMThreshold)
+		for metric, limits := range cfg.CUSUMMetrics {
+			if err := detector.SetMetricLimits(metric, limits.Slack, limits.Threshold); err != nil {
+				return nil, err
+			}
+		}
+		detector.SetReestimateInterval(cfg.CUSUMReestimateInterval)
+		return detector, nil
+	})
+	RegisterAlgorithm("mad", func(cfg config.DetectorConfig, decode registry.DecodeFunc) (DetectionAlgorithm, error) {
+		var options MADOptions
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		threshold := options.Threshold
+		if threshold <= 0 {
+			threshold = cfg.SensitivityLevel
+		}
+		return NewMADDetector(threshold, options.MinBaseline), nil
+	})
+	RegisterAlgorithm("shesd", func(cfg config.DetectorConfig, decode registry.DecodeFunc) (DetectionAlgorithm, error) {
+		options := DefaultSHESDOptions()
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return NewSHESDDetector(options)
+	})
+	RegisterAlgorithm("bocpd", func(cfg config.DetectorConfig, decode registry.DecodeFunc) (DetectionAlgorithm, error) {
+		options := DefaultBOCPDOptions()
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return NewBOCPDDetector(options)
+	})
+	RegisterAlgorithm("holt_winters", func(cfg config.DetectorConfig, decode registry.DecodeFunc) (DetectionAlgorithm, error) {
+		options := DefaultHoltWintersOptions()
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return NewHoltWintersDetector(cfg.SensitivityLevel, options)
+	})
+	RegisterAlgorithm("ensemble", func(cfg config.DetectorConfig, decode registry.DecodeFunc) (DetectionAlgorithm, error) {
+		options := DefaultEnsembleOptions()
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return newEnsembleFromConfig(cfg, options)
+	})
+}
+
+// RegisterAlgorithm makes a detection algorithm available by name. It panics
+// if the name is already registered.
+func RegisterAlgorithm(name string, factory AlgorithmFactory) {
+	algorithms.Register(name, factory)
+}
+
+// Algorithms returns the registered algorithm names
+func Algorithms() []string {
+	return algorithms.Names()
+}
+
+// NewAlgorithm creates a registered detection algorithm. Options are decoded
+// from cfg.AlgorithmOptions.
+func NewAlgorithm(name string, cfg config.DetectorConfig) (DetectionAlgorithm, error) {
+	return newAlgorithm(name, cfg, registry.NodeDecoder(cfg.AlgorithmOptions))
+}
+
+// newAlgorithm creates a registered algorithm with the given options and
+// points it at cfg.Metrics. Algorithms that can't choose their series are an
+// error when cfg.Metrics is set.
+func newAlgorithm(name string, cfg config.DetectorConfig, decode registry.DecodeFunc) (DetectionAlgorithm, error) {
+	factory, err := algorithms.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := resolveMetrics(cfg.Metrics)
+	if err != nil {
+		return nil, err
 	}
 
-	return &AnomalyDetector{
+	algo, err := factory(cfg, decode)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Metrics) > 0 {
+		tracker, ok := algo.(metricTracker)
+		if !ok {
+			return nil, fmt.Errorf("algorithm %s does not support detector.metrics", name)
+		}
+		tracker.track(metrics)
+	}
+	return algo, nil
+}
+
+// NewAnomalyDetector creates a new anomaly detector. An empty algorithm name
+// selects stddev; unknown names are an error.
+func NewAnomalyDetector(cfg config.DetectorConfig) (*AnomalyDetector, error) {
+	name := cfg.Algorithm
+	if name == "" {
+		name = "stddev"
+	}
+	algo, err := NewAlgorithm(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	dimensions, err := newDimensionTrackers(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+
+	collector := NewMetricsCollector(cfg.WindowSize)
+	relatedLogs := resolveRelatedLogs(cfg.RelatedLogs)
+	if relatedLogs.Enabled {
+		collector.SetSampling(relatedLogs.SampleSize, relatedLogs.MaxMessageBytes)
+	}
+
+	detector := &AnomalyDetector{
 		config:           cfg,
-		metricsCollector: NewMetricsCollector(cfg.WindowSize),
+		metricsCollector: collector,
 		algorithm:        algo,
+		dimensions:       dimensions,
+		patterns:         newPatternTracker(cfg),
+		relatedLogs:      relatedLogs,
+		contributors:     resolveContributors(cfg.Contributors),
+		incidents:        newIncidentManager(cfg.Incidents),
 	}
+	if cfg.StatusCodes.Enabled {
+		detector.AddAlgorithm(NewStatusCodeShiftDetector(cfg.StatusCodes.DivergenceThreshold, cfg.SensitivityLevel, cfg.StatusCodes.MinRequests))
+	}
+	return detector, nil
 }
 
-// Start begins anomaly detection
-func (ad *AnomalyDetector) Start(ctx context.Context, input <-chan interface{}, output chan<- interface{}) {
+// AddAlgorithm registers a detector that runs alongside the configured
+// algorithm on every evaluation. Call it before Start.
+func (ad *AnomalyDetector) AddAlgorithm(algo DetectionAlgorithm) {
+	ad.additional = append(ad.additional, algo)
+}
+
+// Start begins anomaly detection. Log entries are consumed; metrics and
+// anomalies are emitted each second, and other messages pass through.
+func (ad *AnomalyDetector) Start(ctx context.Context, input <-chan models.Message, output chan<- models.Message) {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
@@ -49,33 +196,133 @@ func (ad *AnomalyDetector) Start(ctx context.Context, input <-chan interface{},
 		select {
 		case <-ctx.Done():
 			return
-		case logEntry, ok := <-input:
+		case message, ok := <-input:
 			if !ok {
 				return
 			}
-			if entry, ok := logEntry.(*models.LogEntry); ok {
+			if entry, ok := message.LogEntry(); ok {
 				ad.metricsCollector.AddLogEntry(entry)
+				for _, dimension := range ad.dimensions {
+					dimension.add(entry)
+				}
+				if ad.patterns != nil {
+					ad.patterns.add(entry)
+				}
+				continue
+			}
+			if !send(ctx, output, message) {
+				return
 			}
 		case <-ticker.C:
+			evalStart := time.Now()
+
 			// Compute current metrics
 			metrics := ad.metricsCollector.GetCurrentMetrics()
 			historical := ad.metricsCollector.GetHistoricalMetrics()
 
 			// Detect anomalies
 			anomalies := ad.algorithm.Detect(metrics, historical)
+			for _, algo := range ad.additional {
+				anomalies = append(anomalies, algo.Detect(metrics, historical)...)
+			}
+			if ad.relatedLogs.Enabled {
+				attachRelatedLogs(anomalies, metrics, ad.relatedLogs.MaxEntries)
+			}
+			if ad.contributors.Enabled {
+				attachContributors(anomalies, metrics, historical, ad.contributors)
+			}
+			for _, dimension := range ad.dimensions {
+				anomalies = append(anomalies, dimension.evaluate()...)
+			}
+			if ad.patterns != nil {
+				anomalies = append(anomalies, ad.patterns.evaluate()...)
+			}
+			ad.recordEvaluation(time.Since(evalStart))
 
-			// Send metrics and anomalies to dashboard
-			output <- metrics
-			for _, anomaly := range anomalies {
-				output <- anomaly
+			// Send metrics and anomalies, or incident transitions, to dashboard
+			if !send(ctx, output, models.NewMetricsMessage(metrics)) {
+				return
+			}
+			messages := make([]models.Message, 0, len(anomalies))
+			if ad.incidents != nil {
+				for _, incident := range ad.incidents.update(anomalies, time.Now()) {
+					messages = append(messages, models.NewIncidentMessage(incident))
+				}
+			} else {
+				for _, anomaly := range anomalies {
+					messages = append(messages, models.NewAnomalyMessage(anomaly))
+				}
+			}
+			for _, message := range messages {
+				if !send(ctx, output, message) {
+					return
+				}
 			}
 		}
 	}
 }
 
+// recordEvaluation updates the evaluation latency stats
+func (ad *AnomalyDetector) recordEvaluation(latency time.Duration) {
+	ad.statsMu.Lock()
+	defer ad.statsMu.Unlock()
+
+	latencyMs := float64(latency) / float64(time.Millisecond)
+	ad.stats.Evaluations++
+	ad.stats.LastLatencyMs = latencyMs
+	if latencyMs > ad.stats.MaxLatencyMs {
+		ad.stats.MaxLatencyMs = latencyMs
+	}
+	ad.totalLatency += latency
+	ad.stats.AvgLatencyMs = float64(ad.totalLatency) / float64(time.Millisecond) / float64(ad.stats.Evaluations)
+}
+
+// ReportStats adds evaluation latency, incident counts, the log templates
+// mined and the keys tracked per dimension to a pipeline stats snapshot
+func (ad *AnomalyDetector) ReportStats(stats *models.PipelineStats) {
+	ad.statsMu.Lock()
+	stats.Detector = ad.stats
+	ad.statsMu.Unlock()
+
+	if ad.incidents != nil {
+		counters := make(map[string]uint64)
+		ad.incidents.reportStats(counters)
+		stats.Stages["incidents"] = counters
+	}
+	if ad.patterns != nil {
+		counters := make(map[string]uint64)
+		ad.patterns.reportStats(counters)
+		stats.Stages["patterns"] = counters
+	}
+	if len(ad.dimensions) == 0 {
+		return
+	}
+	counters := make(map[string]uint64)
+	for _, dimension := range ad.dimensions {
+		dimension.reportStats(counters)
+	}
+	stats.Stages["dimensions"] = counters
+}
+
+// send delivers a message unless the context is cancelled first
+func send(ctx context.Context, output chan<- models.Message, message models.Message) bool {
+	select {
+	case output <- message:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 // StdDevDetector uses standard deviation for anomaly detection
 type StdDevDetector struct {
 	threshold float64
+	metrics   []trackedMetric // Defaults to trackedMetrics
+}
+
+// track sets the metrics checked
+func (d *StdDevDetector) track(metrics []trackedMetric) {
+	d.metrics = metrics
 }
 
 func (d *StdDevDetector) Detect(current *models.Metrics, historical []models.Metrics) []models.Anomaly {
@@ -85,58 +332,33 @@ func (d *StdDevDetector) Detect(current *models.Metrics, historical []models.Met
 		return anomalies // Not enough data for baseline
 	}
 
-	// Check error rate
-	errorRateMean, errorRateStdDev := calculateStats(historical, func(m models.Metrics) float64 {
-		return m.ErrorRate
-	})
-
-	if math.Abs(current.ErrorRate-errorRateMean) > d.threshold*errorRateStdDev {
-		anomalies = append(anomalies, models.Anomaly{
-			Timestamp:     time.Now(),
-			Type:          models.AnomalyTypeErrorRate,
-			Severity:      calculateSeverity(current.ErrorRate, errorRateMean, errorRateStdDev),
-			Description:   "Abnormal error rate detected",
-			Metric:        "error_rate",
-			ActualValue:   current.ErrorRate,
-			ExpectedValue: errorRateMean,
-			Deviation:     math.Abs(current.ErrorRate - errorRateMean),
-		})
+	metrics := d.metrics
+	if metrics == nil {
+		metrics = trackedMetrics
 	}
-
-	// Check request rate
-	reqRateMean, reqRateStdDev := calculateStats(historical, func(m models.Metrics) float64 {
-		return m.RequestsPerSec
-	})
-
-	if math.Abs(current.RequestsPerSec-reqRateMean) > d.threshold*reqRateStdDev {
-		anomalies = append(anomalies, models.Anomaly{
-			Timestamp:     time.Now(),
-			Type:          models.AnomalyTypeTrafficSpike,
-			Severity:      calculateSeverity(current.RequestsPerSec, reqRateMean, reqRateStdDev),
-			Description:   "Traffic spike or drop detected",
-			Metric:        "requests_per_sec",
-			ActualValue:   current.RequestsPerSec,
-			ExpectedValue: reqRateMean,
-			Deviation:     math.Abs(current.RequestsPerSec - reqRateMean),
+	for _, metric := range metrics {
+		mean, stdDev := calculateStats(historical, func(m models.Metrics) float64 {
+			return metric.value(&m)
 		})
-	}
-
-	// Check response time
-	respTimeMean, respTimeStdDev := calculateStats(historical, func(m models.Metrics) float64 {
-		return m.AvgResponseTime
-	})
+		value := metric.value(current)
 
-	if current.AvgResponseTime > respTimeMean+d.threshold*respTimeStdDev {
-		anomalies = append(anomalies, models.Anomaly{
-			Timestamp:     time.Now(),
-			Type:          models.AnomalyTypeResponseTime,
-			Severity:      calculateSeverity(current.AvgResponseTime, respTimeMean, respTimeStdDev),
-			Description:   "Response time degradation detected",
-			Metric:        "avg_response_time",
-			ActualValue:   current.AvgResponseTime,
-			ExpectedValue: respTimeMean,
-			Deviation:     current.AvgResponseTime - respTimeMean,
-		})
+		// Response times only alert on increases
+		deviation := value - mean
+		if !metric.increasesOnly {
+			deviation = math.Abs(deviation)
+		}
+		if deviation > d.threshold*stdDev {
+			anomalies = append(anomalies, models.Anomaly{
+				Timestamp:     time.Now(),
+				Type:          metric.anomalyType,
+				Severity:      calculateSeverity(value, mean, stdDev),
+				Description:   metric.description,
+				Metric:        metric.name,
+				ActualValue:   value,
+				ExpectedValue: mean,
+				Deviation:     deviation,
+			})
+		}
 	}
 
 	return anomalies
@@ -277,10 +499,15 @@ func calculateEWMASeverity(deviation, expected float64) models.Severity {
 	return models.SeverityLow
 }
 
-// CUSUMDetector uses CUSUM (Cumulative Sum) algorithm for detecting subtle shifts
+// CUSUMDetector uses CUSUM (Cumulative Sum) algorithm for detecting subtle shifts.
+// Each metric is standardized by its baseline standard deviation, so the slack
+// and threshold are in standard deviations and one setting suits error rates
+// and request rates alike.
 type CUSUMDetector struct {
-	slackParameter     float64 // k: allowable deviation from mean
-	decisionThreshold  float64 // h: threshold for triggering anomaly
+	slackParameter     float64 // k: allowable deviation from mean, in standard deviations
+	decisionThreshold  float64 // h: threshold for triggering anomaly, in standard deviations
+	metricLimits       map[string]cusumLimits // Per-metric overrides of k and h
+	reestimateInterval int // Evaluations between re-estimating the references (0 = never)
 
 	// State tracking for each metric - positive and negative cumulative sums
 	cusumPosErrorRate      float64
@@ -295,9 +522,33 @@ type CUSUMDetector struct {
 	referenceRequestsPerSec float64
 	referenceResponseTime   float64
 
+	// Baseline standard deviations used to standardize each metric
+	stdDevErrorRate      float64
+	stdDevRequestsPerSec float64
+	stdDevResponseTime   float64
+
 	initialized bool
+	evaluations int // Since the references were last estimated
 }
 
+// cusumLimits are the slack and decision threshold for one metric
+type cusumLimits struct {
+	slack     float64
+	threshold float64
+}
+
+const (
+	// minCUSUMBaseline is the number of historical windows needed to estimate references
+	minCUSUMBaseline = 10
+
+	// A flat baseline still needs a spread to standardize by: the larger of
+	// minCUSUMRelativeStdDev of the mean and minCUSUMStdDev
+	minCUSUMRelativeStdDev = 0.05
+	minCUSUMStdDev         = 0.001
+
+	defaultCUSUMReestimateInterval = 60
+)
+
 // NewCUSUMDetector creates a new CUSUM detector with configurable parameters
 func NewCUSUMDetector(slackParameter, decisionThreshold float64) *CUSUMDetector {
 	// Default values if not specified
@@ -309,23 +560,63 @@ func NewCUSUMDetector(slackParameter, decisionThreshold float64) *CUSUMDetector
 	}
 
 	return &CUSUMDetector{
-		slackParameter:    slackParameter,
-		decisionThreshold: decisionThreshold,
-		initialized:       false,
+		slackParameter:     slackParameter,
+		decisionThreshold:  decisionThreshold,
+		metricLimits:       make(map[string]cusumLimits),
+		reestimateInterval: defaultCUSUMReestimateInterval,
+		initialized:        false,
+	}
+}
+
+// SetMetricLimits overrides the slack and threshold for one metric. Zero
+// values keep the detector-wide setting.
+func (d *CUSUMDetector) SetMetricLimits(metric string, slack, threshold float64) error {
+	known := false
+	for _, tracked := range trackedMetrics {
+		known = known || tracked.name == metric
+	}
+	if !known {
+		return fmt.Errorf("unknown CUSUM metric %q", metric)
+	}
+	if slack < 0 || threshold < 0 {
+		return fmt.Errorf("CUSUM slack and threshold for %s must not be negative", metric)
+	}
+
+	limits := cusumLimits{slack: d.slackParameter, threshold: d.decisionThreshold}
+	if slack > 0 {
+		limits.slack = slack
+	}
+	if threshold > 0 {
+		limits.threshold = threshold
+	}
+	d.metricLimits[metric] = limits
+	return nil
+}
+
+// SetReestimateInterval sets how many evaluations pass between re-estimating
+// the reference means and standard deviations; 0 keeps the first estimate
+func (d *CUSUMDetector) SetReestimateInterval(evaluations int) {
+	if evaluations < 0 {
+		evaluations = 0
 	}
+	d.reestimateInterval = evaluations
 }
 
 func (d *CUSUMDetector) Detect(current *models.Metrics, historical []models.Metrics) []models.Anomaly {
 	anomalies := []models.Anomaly{}
 
 	// Need baseline data to establish reference values
+	baseline := baselineWindows(current, historical)
 	if !d.initialized {
-		if len(historical) < 10 {
+		if len(baseline) < minCUSUMBaseline {
 			return anomalies // Not enough data for baseline
 		}
-		d.initializeReferences(historical)
+		d.initializeReferences(baseline)
 		d.initialized = true
+	} else if d.reestimateInterval > 0 && d.evaluations >= d.reestimateInterval && len(baseline) >= minCUSUMBaseline {
+		d.reestimateReferences(baseline)
 	}
+	d.evaluations++
 
 	// Check error rate using CUSUM
 	errorRateAnomaly := d.detectCUSUMAnomaly(
@@ -333,6 +624,7 @@ func (d *CUSUMDetector) Detect(current *models.Metrics, historical []models.Metr
 		&d.cusumPosErrorRate,
 		&d.cusumNegErrorRate,
 		d.referenceErrorRate,
+		d.stdDevErrorRate,
 		"error_rate",
 		models.AnomalyTypeErrorRate,
 		"Persistent error rate shift detected",
@@ -347,6 +639,7 @@ func (d *CUSUMDetector) Detect(current *models.Metrics, historical []models.Metr
 		&d.cusumPosRequestsPerSec,
 		&d.cusumNegRequestsPerSec,
 		d.referenceRequestsPerSec,
+		d.stdDevRequestsPerSec,
 		"requests_per_sec",
 		models.AnomalyTypeTrafficSpike,
 		"Persistent traffic pattern change detected",
@@ -361,6 +654,7 @@ func (d *CUSUMDetector) Detect(current *models.Metrics, historical []models.Metr
 		&d.cusumPosResponseTime,
 		&d.cusumNegResponseTime,
 		d.referenceResponseTime,
+		d.stdDevResponseTime,
 		"avg_response_time",
 		models.AnomalyTypeResponseTime,
 		"Persistent response time degradation detected",
@@ -372,30 +666,41 @@ func (d *CUSUMDetector) Detect(current *models.Metrics, historical []models.Metr
 	return anomalies
 }
 
+// limits returns the slack and threshold for a metric
+func (d *CUSUMDetector) limits(metricName string) cusumLimits {
+	if limits, ok := d.metricLimits[metricName]; ok {
+		return limits
+	}
+	return cusumLimits{slack: d.slackParameter, threshold: d.decisionThreshold}
+}
+
 // detectCUSUMAnomaly applies CUSUM algorithm to a single metric
 func (d *CUSUMDetector) detectCUSUMAnomaly(
 	currentValue float64,
 	cusumPos *float64,
 	cusumNeg *float64,
 	referenceMean float64,
+	referenceStdDev float64,
 	metricName string,
 	anomalyType models.AnomalyType,
 	description string,
 ) *models.Anomaly {
-	// CUSUM formulas:
-	// S⁺(t) = max(0, S⁺(t-1) + (x(t) - μ - k))
-	// S⁻(t) = max(0, S⁻(t-1) - (x(t) - μ + k))
+	// CUSUM formulas on the standardized value z(t) = (x(t) - μ) / σ:
+	// S⁺(t) = max(0, S⁺(t-1) + (z(t) - k))
+	// S⁻(t) = max(0, S⁻(t-1) - (z(t) + k))
+	limits := d.limits(metricName)
+	z := (currentValue - referenceMean) / referenceStdDev
 
 	// Calculate positive CUSUM (detects upward shifts)
-	*cusumPos = math.Max(0, *cusumPos + (currentValue - referenceMean - d.slackParameter))
+	*cusumPos = math.Max(0, *cusumPos + (z - limits.slack))
 
 	// Calculate negative CUSUM (detects downward shifts)
-	*cusumNeg = math.Max(0, *cusumNeg - (currentValue - referenceMean + d.slackParameter))
+	*cusumNeg = math.Max(0, *cusumNeg - (z + limits.slack))
 
 	// Check if either cumulative sum exceeds the decision threshold
-	if *cusumPos > d.decisionThreshold {
+	if *cusumPos > limits.threshold {
 		// Upward shift detected
-		severity := calculateCUSUMSeverity(*cusumPos, d.decisionThreshold)
+		severity := calculateCUSUMSeverity(*cusumPos, limits.threshold)
 		deviation := currentValue - referenceMean
 
 		// Reset CUSUM after detection
@@ -414,9 +719,9 @@ func (d *CUSUMDetector) detectCUSUMAnomaly(
 		}
 	}
 
-	if *cusumNeg > d.decisionThreshold {
+	if *cusumNeg > limits.threshold {
 		// Downward shift detected
-		severity := calculateCUSUMSeverity(*cusumNeg, d.decisionThreshold)
+		severity := calculateCUSUMSeverity(*cusumNeg, limits.threshold)
 		deviation := referenceMean - currentValue
 
 		// Reset CUSUM after detection
@@ -438,7 +743,8 @@ func (d *CUSUMDetector) detectCUSUMAnomaly(
 	return nil
 }
 
-// initializeReferences calculates reference values (target means) from historical data
+// initializeReferences calculates reference values (target means) and
+// standard deviations from historical data
 func (d *CUSUMDetector) initializeReferences(historical []models.Metrics) {
 	if len(historical) == 0 {
 		return
@@ -458,6 +764,41 @@ func (d *CUSUMDetector) initializeReferences(historical []models.Metrics) {
 	d.referenceErrorRate = sumErrorRate / count
 	d.referenceRequestsPerSec = sumRequestsPerSec / count
 	d.referenceResponseTime = sumResponseTime / count
+
+	d.stdDevErrorRate = cusumStdDev(historical, d.referenceErrorRate, func(m models.Metrics) float64 { return m.ErrorRate })
+	d.stdDevRequestsPerSec = cusumStdDev(historical, d.referenceRequestsPerSec, func(m models.Metrics) float64 { return m.RequestsPerSec })
+	d.stdDevResponseTime = cusumStdDev(historical, d.referenceResponseTime, func(m models.Metrics) float64 { return m.AvgResponseTime })
+	d.evaluations = 0
+}
+
+// reestimateReferences refreshes the references from recent history so they
+// follow slow drift. A metric whose cumulative sums are accumulating keeps its
+// reference, so a shift in progress is not absorbed into the baseline.
+func (d *CUSUMDetector) reestimateReferences(historical []models.Metrics) {
+	previous := *d
+	d.initializeReferences(historical)
+
+	if previous.cusumPosErrorRate > 0 || previous.cusumNegErrorRate > 0 {
+		d.referenceErrorRate, d.stdDevErrorRate = previous.referenceErrorRate, previous.stdDevErrorRate
+	}
+	if previous.cusumPosRequestsPerSec > 0 || previous.cusumNegRequestsPerSec > 0 {
+		d.referenceRequestsPerSec, d.stdDevRequestsPerSec = previous.referenceRequestsPerSec, previous.stdDevRequestsPerSec
+	}
+	if previous.cusumPosResponseTime > 0 || previous.cusumNegResponseTime > 0 {
+		d.referenceResponseTime, d.stdDevResponseTime = previous.referenceResponseTime, previous.stdDevResponseTime
+	}
+}
+
+// cusumStdDev returns the standard deviation of a metric around its
+// reference, floored so a flat baseline does not make every change infinite
+func cusumStdDev(historical []models.Metrics, mean float64, getValue func(models.Metrics) float64) float64 {
+	variance := 0.0
+	for _, m := range historical {
+		diff := getValue(m) - mean
+		variance += diff * diff
+	}
+	stdDev := math.Sqrt(variance / float64(len(historical)))
+	return math.Max(stdDev, math.Max(minCUSUMRelativeStdDev*math.Abs(mean), minCUSUMStdDev))
 }
 
 // calculateCUSUMSeverity determines severity based on how much CUSUM exceeds threshold
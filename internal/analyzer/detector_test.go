@@ -716,3 +716,106 @@ func TestCUSUMDetector_MultipleMetricShifts(t *testing.T) {
 		t.Error("Expected to detect at least one type of anomaly with multiple metric shifts")
 	}
 }
+
+// noisyCUSUMBaseline returns windows around the given means with a repeating
+// spread of two standard deviations of the given sizes
+func noisyCUSUMBaseline(count int, reqPerSec, reqSpread, errorRate, errorSpread float64) []models.Metrics {
+	historical := make([]models.Metrics, count)
+	for i := range historical {
+		offset := float64(i%5 - 2)
+		historical[i] = *createTestMetrics(reqPerSec+offset*reqSpread, errorRate+offset*errorSpread, 50.0)
+	}
+	return historical
+}
+
+// TestCUSUMDetector_PerMetricNormalization tests that one slack and threshold
+// suit metrics on very different scales
+func TestCUSUMDetector_PerMetricNormalization(t *testing.T) {
+	detector := NewCUSUMDetector(0.5, 5.0)
+	historical := noisyCUSUMBaseline(20, 1000.0, 25.0, 0.02, 0.001)
+	_ = detector.Detect(createTestMetrics(1000.0, 0.02, 50.0), historical)
+
+	// Traffic keeps its usual noise of tens of requests/sec while the error
+	// rate moves by one percentage point
+	for i := 0; i < 10; i++ {
+		offset := float64(i%5 - 2)
+		current := createTestMetrics(1000.0+offset*25.0, 0.03, 50.0)
+		for _, anomaly := range detector.Detect(current, historical) {
+			if anomaly.Type != models.AnomalyTypeErrorRate {
+				t.Fatalf("Expected only the error rate shift, got %s", anomaly.Metric)
+			}
+			if i > 3 {
+				t.Errorf("Expected error rate shift within 4 windows, took %d", i+1)
+			}
+			return
+		}
+	}
+
+	t.Error("Expected to detect error rate shift of one percentage point")
+}
+
+// TestCUSUMDetector_MetricLimits tests per-metric slack and threshold overrides
+func TestCUSUMDetector_MetricLimits(t *testing.T) {
+	detector := NewCUSUMDetector(0.5, 5.0)
+	if err := detector.SetMetricLimits("requests_per_sec", 0, 1000); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := detector.SetMetricLimits("bytes_per_sec", 1, 1); err == nil {
+		t.Error("Expected error for unknown metric")
+	}
+	if err := detector.SetMetricLimits("error_rate", -1, 1); err == nil {
+		t.Error("Expected error for negative slack")
+	}
+
+	limits := detector.limits("requests_per_sec")
+	if limits.slack != 0.5 || limits.threshold != 1000 {
+		t.Errorf("Expected slack 0.5 and threshold 1000, got %v and %v", limits.slack, limits.threshold)
+	}
+
+	historical := make([]models.Metrics, 10)
+	for i := range historical {
+		historical[i] = *createTestMetrics(100.0, 0.05, 50.0)
+	}
+	_ = detector.Detect(createTestMetrics(100.0, 0.05, 50.0), historical)
+
+	for i := 0; i < 15; i++ {
+		for _, anomaly := range detector.Detect(createTestMetrics(110.0, 0.05, 60.0), historical) {
+			if anomaly.Type == models.AnomalyTypeTrafficSpike {
+				t.Fatal("Expected raised requests_per_sec threshold to suppress traffic anomaly")
+			}
+			if anomaly.Type == models.AnomalyTypeResponseTime {
+				return
+			}
+		}
+	}
+	t.Error("Expected response time shift to use the detector-wide threshold")
+}
+
+// TestCUSUMDetector_ReestimateReferences tests that references follow the
+// baseline except for metrics with a shift in progress
+func TestCUSUMDetector_ReestimateReferences(t *testing.T) {
+	detector := NewCUSUMDetector(0.5, 5.0)
+	detector.SetReestimateInterval(5)
+
+	historical := make([]models.Metrics, 10)
+	for i := range historical {
+		historical[i] = *createTestMetrics(100.0, 0.05, 50.0)
+	}
+	for i := 0; i < 5; i++ {
+		// Response time creeps up without crossing the threshold yet
+		_ = detector.Detect(createTestMetrics(100.0, 0.05, 52.0), historical)
+	}
+
+	drifted := make([]models.Metrics, 10)
+	for i := range drifted {
+		drifted[i] = *createTestMetrics(104.0, 0.05, 54.0)
+	}
+	_ = detector.Detect(createTestMetrics(104.0, 0.05, 54.0), drifted)
+
+	if detector.referenceRequestsPerSec != 104.0 {
+		t.Errorf("Expected reference requests per sec to follow the baseline to 104, got %f", detector.referenceRequestsPerSec)
+	}
+	if detector.referenceResponseTime != 50.0 {
+		t.Errorf("Expected reference response time to stay at 50 during a shift, got %f", detector.referenceResponseTime)
+	}
+}
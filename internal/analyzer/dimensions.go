@@ -0,0 +1,212 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// Defaults for detector.dimensions
+const (
+	defaultDimensionMaxKeys     = 100
+	defaultDimensionMinRequests = 10
+
+	// dimensionIdleEvaluations is how many evaluations without new requests a
+	// key survives before its series is dropped, freeing a slot
+	dimensionIdleEvaluations = 300
+)
+
+// dimensionKeys return an entry's key for each supported dimension; an
+// empty key leaves the entry out of that dimension
+var dimensionKeys = map[string]func(entry *models.LogEntry) string{
+	"path":   func(entry *models.LogEntry) string { return entry.Path },
+	"source": func(entry *models.LogEntry) string { return entry.Source },
+	"method": func(entry *models.LogEntry) string { return entry.Method },
+	"host": func(entry *models.LogEntry) string {
+		host, _ := entry.Extra["host"].(string)
+		return host
+	},
+	"status_class": func(entry *models.LogEntry) string {
+		if entry.StatusCode <= 0 {
+			return ""
+		}
+		return fmt.Sprintf("%dxx", entry.StatusCode/100)
+	},
+}
+
+// dimensionTracker keeps a metrics series and a detection algorithm for each
+// key of one dimension, up to maxKeys at a time
+type dimensionTracker struct {
+	name         string
+	key          func(entry *models.LogEntry) string
+	maxKeys      int
+	minRequests  int
+	windowSize   int
+	newAlgorithm func() (DetectionAlgorithm, error)
+	relatedLogs  config.RelatedLogsConfig
+	contributors config.ContributorsConfig
+
+	mu       sync.Mutex
+	series   map[string]*dimensionSeries
+	overflow uint64 // Entries whose key arrived while the dimension was full
+}
+
+// dimensionSeries is the state for one key
+type dimensionSeries struct {
+	collector *MetricsCollector
+	algorithm DetectionAlgorithm
+	idle      int // Consecutive evaluations without new requests
+	pending   int // Requests in the open window at the last evaluation
+}
+
+// newDimensionTrackers creates a tracker for each configured dimension. Each
+// key gets its own instance of the detector's algorithm.
+func newDimensionTrackers(cfg config.DetectorConfig, algorithm string) ([]*dimensionTracker, error) {
+	var trackers []*dimensionTracker
+	seen := make(map[string]bool)
+	for _, dimension := range cfg.Dimensions {
+		key, ok := dimensionKeys[dimension.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown dimension %q (available: %s)", dimension.Name, strings.Join(dimensionNames(), ", "))
+		}
+		if seen[dimension.Name] {
+			return nil, fmt.Errorf("dimension %q configured twice", dimension.Name)
+		}
+		seen[dimension.Name] = true
+
+		maxKeys := dimension.MaxKeys
+		if maxKeys <= 0 {
+			maxKeys = defaultDimensionMaxKeys
+		}
+		minRequests := dimension.MinRequests
+		if minRequests <= 0 {
+			minRequests = defaultDimensionMinRequests
+		}
+
+		trackers = append(trackers, &dimensionTracker{
+			name:        dimension.Name,
+			key:         key,
+			maxKeys:     maxKeys,
+			minRequests: minRequests,
+			windowSize:  cfg.WindowSize,
+			newAlgorithm: func() (DetectionAlgorithm, error) {
+				return NewAlgorithm(algorithm, cfg)
+			},
+			relatedLogs:  resolveRelatedLogs(cfg.RelatedLogs),
+			contributors: resolveContributors(cfg.Contributors),
+			series:       make(map[string]*dimensionSeries),
+		})
+	}
+	return trackers, nil
+}
+
+// dimensionNames returns the supported dimensions in sorted order
+func dimensionNames() []string {
+	names := make([]string, 0, len(dimensionKeys))
+	for name := range dimensionKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// add records an entry in its key's series. Entries with a new key are
+// counted as overflow once maxKeys keys are tracked.
+func (t *dimensionTracker) add(entry *models.LogEntry) {
+	key := t.key(entry)
+	if key == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	series, ok := t.series[key]
+	if !ok {
+		if len(t.series) >= t.maxKeys {
+			t.overflow++
+			return
+		}
+		algorithm, err := t.newAlgorithm()
+		if err != nil {
+			t.overflow++
+			return
+		}
+		collector := NewMetricsCollector(t.windowSize)
+		if t.relatedLogs.Enabled {
+			collector.SetSampling(t.relatedLogs.SampleSize, t.relatedLogs.MaxMessageBytes)
+		}
+		series = &dimensionSeries{collector: collector, algorithm: algorithm}
+		t.series[key] = series
+	}
+	series.collector.AddLogEntry(entry)
+}
+
+// evaluate runs each key's algorithm on its current window and tags the
+// anomalies with the dimension and key. A key's window keeps collecting
+// until it has minRequests requests, so quiet keys are judged over longer
+// windows rather than on a handful of requests.
+func (t *dimensionTracker) evaluate() []models.Anomaly {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var anomalies []models.Anomaly
+	for _, key := range t.sortedKeys() {
+		series := t.series[key]
+		requests := series.collector.CurrentRequests()
+		if requests == series.pending {
+			series.idle++
+			if series.idle >= dimensionIdleEvaluations {
+				delete(t.series, key)
+			}
+			continue
+		}
+		series.idle = 0
+		series.pending = requests
+		if requests < t.minRequests {
+			continue
+		}
+		series.pending = 0
+
+		metrics := series.collector.GetCurrentMetrics()
+		historical := series.collector.GetHistoricalMetrics()
+		found := series.algorithm.Detect(metrics, historical)
+		if t.relatedLogs.Enabled {
+			attachRelatedLogs(found, metrics, t.relatedLogs.MaxEntries)
+		}
+		if t.contributors.Enabled {
+			attachContributors(found, metrics, historical, t.contributors)
+		}
+		for _, anomaly := range found {
+			anomaly.Dimension = t.name
+			anomaly.Key = key
+			anomaly.Description = fmt.Sprintf("%s [%s=%s]", anomaly.Description, t.name, key)
+			anomalies = append(anomalies, anomaly)
+		}
+	}
+	return anomalies
+}
+
+// sortedKeys returns the tracked keys in order, so anomalies are reported
+// in a stable order
+func (t *dimensionTracker) sortedKeys() []string {
+	keys := make([]string, 0, len(t.series))
+	for key := range t.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reportStats adds the number of tracked keys and overflowed entries
+func (t *dimensionTracker) reportStats(counters map[string]uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	counters["keys:"+t.name] = uint64(len(t.series))
+	counters["overflow:"+t.name] = t.overflow
+}
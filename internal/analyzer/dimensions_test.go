@@ -0,0 +1,177 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// addRequests adds count entries for a path, the first errors of them with
+// status 500
+func addRequests(tracker *dimensionTracker, path string, count, errors int) {
+	for i := 0; i < count; i++ {
+		status := 200
+		if i < errors {
+			status = 500
+		}
+		tracker.add(createTestLogEntry(status, path, 20))
+	}
+}
+
+// newPathTracker creates a path dimension running the mad algorithm
+func newPathTracker(t *testing.T, dimension config.DimensionConfig) *dimensionTracker {
+	dimension.Name = "path"
+	trackers, err := newDimensionTrackers(config.DetectorConfig{
+		WindowSize:       100,
+		SensitivityLevel: 3.5,
+		Dimensions:       []config.DimensionConfig{dimension},
+	}, "mad")
+	if err != nil || len(trackers) != 1 {
+		t.Fatalf("Failed to create path dimension: %v", err)
+	}
+	return trackers[0]
+}
+
+// TestDimensionTracker_LowVolumeKey tests that a quiet endpoint failing
+// completely next to a busy one is reported for its own key
+func TestDimensionTracker_LowVolumeKey(t *testing.T) {
+	tracker := newPathTracker(t, config.DimensionConfig{MinRequests: 5})
+
+	for i := 0; i < 20; i++ {
+		addRequests(tracker, "/api/users", 200, i%3)
+		addRequests(tracker, "/api/export", 5, 0)
+		for _, anomaly := range tracker.evaluate() {
+			if anomaly.Metric == "error_rate" {
+				t.Fatalf("Expected no error rate anomalies in the baseline, got %+v", anomaly)
+			}
+		}
+	}
+
+	addRequests(tracker, "/api/users", 200, 1)
+	addRequests(tracker, "/api/export", 5, 5)
+
+	var found []models.Anomaly
+	for _, anomaly := range tracker.evaluate() {
+		if anomaly.Metric == "error_rate" {
+			found = append(found, anomaly)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("Expected one error rate anomaly, got %+v", found)
+	}
+	anomaly := found[0]
+	if anomaly.Dimension != "path" || anomaly.Key != "/api/export" || anomaly.ActualValue != 1 {
+		t.Errorf("Expected /api/export at 100%% errors, got %s=%s at %v", anomaly.Dimension, anomaly.Key, anomaly.ActualValue)
+	}
+	if !strings.HasSuffix(anomaly.Description, "[path=/api/export]") {
+		t.Errorf("Expected the key in the description, got %q", anomaly.Description)
+	}
+}
+
+// TestDimensionTracker_MarkupKey tests that a key taken from a request is
+// reported verbatim, for the dashboard to render as text
+func TestDimensionTracker_MarkupKey(t *testing.T) {
+	tracker := newPathTracker(t, config.DimensionConfig{MinRequests: 5})
+	key := "/<img src=x onerror=alert(1)>"
+
+	for i := 0; i < 20; i++ {
+		addRequests(tracker, "/api/users", 200, i%3)
+		addRequests(tracker, key, 5, 0)
+		tracker.evaluate()
+	}
+	addRequests(tracker, key, 5, 5)
+
+	for _, anomaly := range tracker.evaluate() {
+		if anomaly.Metric != "error_rate" {
+			continue
+		}
+		if anomaly.Key != key || !strings.HasSuffix(anomaly.Description, "[path="+key+"]") {
+			t.Errorf("Expected the key unchanged, got %q in %q", anomaly.Key, anomaly.Description)
+		}
+		return
+	}
+	t.Error("Expected an error rate anomaly for the key")
+}
+
+// TestDimensionTracker_MinRequests tests that a key's window keeps
+// collecting until it has enough requests
+func TestDimensionTracker_MinRequests(t *testing.T) {
+	tracker := newPathTracker(t, config.DimensionConfig{MinRequests: 5})
+
+	addRequests(tracker, "/health", 3, 0)
+	tracker.evaluate()
+	if requests := tracker.series["/health"].collector.CurrentRequests(); requests != 3 {
+		t.Fatalf("Expected the window to stay open with 3 requests, got %d", requests)
+	}
+
+	addRequests(tracker, "/health", 3, 0)
+	tracker.evaluate()
+	collector := tracker.series["/health"].collector
+	if collector.CurrentRequests() != 0 || len(collector.GetHistoricalMetrics()) != 1 {
+		t.Errorf("Expected one evaluated window of 6 requests, got %d historical", len(collector.GetHistoricalMetrics()))
+	}
+}
+
+// TestDimensionTracker_Cardinality tests the key cap, overflow counting and
+// eviction of idle keys
+func TestDimensionTracker_Cardinality(t *testing.T) {
+	tracker := newPathTracker(t, config.DimensionConfig{MaxKeys: 2})
+
+	addRequests(tracker, "/a", 1, 0)
+	addRequests(tracker, "/b", 1, 0)
+	addRequests(tracker, "/c", 4, 0)
+	addRequests(tracker, "/a", 1, 0)
+	tracker.add(createTestLogEntry(200, "", 20)) // No key: not tracked, not overflow
+
+	counters := make(map[string]uint64)
+	tracker.reportStats(counters)
+	if counters["keys:path"] != 2 || counters["overflow:path"] != 4 {
+		t.Errorf("Expected 2 keys and 4 overflowed entries, got %v", counters)
+	}
+
+	// /b stays busy while /a goes quiet and is evicted, freeing a slot
+	for i := 0; i <= dimensionIdleEvaluations; i++ {
+		addRequests(tracker, "/b", 1, 0)
+		tracker.evaluate()
+	}
+	if _, ok := tracker.series["/a"]; ok {
+		t.Error("Expected idle key /a to be evicted")
+	}
+	addRequests(tracker, "/c", 1, 0)
+	if _, ok := tracker.series["/c"]; !ok {
+		t.Error("Expected /c to take the free slot")
+	}
+}
+
+// TestNewAnomalyDetector_Dimensions tests dimension configuration errors
+func TestNewAnomalyDetector_Dimensions(t *testing.T) {
+	for _, dimensions := range [][]config.DimensionConfig{
+		{{Name: "country"}},
+		{{Name: "path"}, {Name: "path"}},
+	} {
+		if _, err := NewAnomalyDetector(config.DetectorConfig{WindowSize: 100, Dimensions: dimensions}); err == nil {
+			t.Errorf("Expected error for %+v", dimensions)
+		}
+	}
+
+	detector, err := NewAnomalyDetector(config.DetectorConfig{
+		WindowSize: 100,
+		Dimensions: []config.DimensionConfig{{Name: "status_class"}, {Name: "host"}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	entry := createTestLogEntry(503, "/", 20)
+	entry.Extra = map[string]interface{}{"host": "web-1"}
+	for _, dimension := range detector.dimensions {
+		dimension.add(entry)
+	}
+	if _, ok := detector.dimensions[0].series["5xx"]; !ok {
+		t.Error("Expected status class 5xx")
+	}
+	if _, ok := detector.dimensions[1].series["web-1"]; !ok {
+		t.Error("Expected host web-1")
+	}
+}
@@ -0,0 +1,226 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/internal/registry"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Voting rules for the ensemble detector
+const (
+	VoteAny      = "any"      // One member is enough
+	VoteAll      = "all"      // Every member must agree
+	VoteMajority = "majority" // More than half of the members
+	VoteWeighted = "weighted" // Members' weights must reach min_weight of the total
+)
+
+// EnsembleOptions configures the ensemble detector, decoded from
+// detector.algorithm_options
+type EnsembleOptions struct {
+	Voting    string                 `yaml:"voting"`     // "any", "all", "majority" or "weighted"
+	MinWeight float64                `yaml:"min_weight"` // Share of the total weight needed with weighted voting (0-1)
+	Agreement bool                   `yaml:"agreement"`  // Set severity from the share of members that agree
+	Members   []EnsembleMemberConfig `yaml:"members"`
+}
+
+// EnsembleMemberConfig selects one registered algorithm for the ensemble
+type EnsembleMemberConfig struct {
+	Algorithm string    `yaml:"algorithm"`
+	Name      string    `yaml:"name"`    // Reported in Anomaly.Detectors; defaults to the algorithm name
+	Weight    float64   `yaml:"weight"`  // Defaults to 1
+	Options   yaml.Node `yaml:"options"` // The member's algorithm_options
+}
+
+// DefaultEnsembleOptions returns majority voting over stddev, moving_average
+// and cusum
+func DefaultEnsembleOptions() EnsembleOptions {
+	return EnsembleOptions{
+		Voting:    VoteMajority,
+		MinWeight: 0.5,
+		Members: []EnsembleMemberConfig{
+			{Algorithm: "stddev"},
+			{Algorithm: "moving_average"},
+			{Algorithm: "cusum"},
+		},
+	}
+}
+
+// EnsembleMember is a detection algorithm taking part in an ensemble
+type EnsembleMember struct {
+	Name      string
+	Weight    float64
+	Algorithm DetectionAlgorithm
+}
+
+// EnsembleDetector runs several algorithms on every evaluation and reports a
+// metric only when enough of them flag it. Members always see every
+// evaluation, so stateful algorithms keep their baselines current.
+type EnsembleDetector struct {
+	voting    string
+	minWeight float64
+	agreement bool
+	members   []EnsembleMember
+}
+
+// NewEnsembleDetector creates an ensemble of members with a voting rule.
+// minWeight is only used by weighted voting.
+func NewEnsembleDetector(voting string, minWeight float64, agreement bool, members []EnsembleMember) (*EnsembleDetector, error) {
+	switch voting {
+	case VoteAny, VoteAll, VoteMajority:
+	case VoteWeighted:
+		if minWeight <= 0 || minWeight > 1 {
+			return nil, fmt.Errorf("min_weight must be between 0 and 1, got %v", minWeight)
+		}
+	default:
+		return nil, fmt.Errorf("unknown voting %q (available: any, all, majority, weighted)", voting)
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("an ensemble needs at least one member")
+	}
+
+	names := make(map[string]bool)
+	for _, member := range members {
+		if member.Algorithm == nil || member.Name == "" {
+			return nil, fmt.Errorf("ensemble members need a name and an algorithm")
+		}
+		if names[member.Name] {
+			return nil, fmt.Errorf("ensemble member %q appears twice; give it a distinct name", member.Name)
+		}
+		if member.Weight <= 0 {
+			return nil, fmt.Errorf("weight of ensemble member %q must be positive, got %v", member.Name, member.Weight)
+		}
+		names[member.Name] = true
+	}
+
+	return &EnsembleDetector{
+		voting:    voting,
+		minWeight: minWeight,
+		agreement: agreement,
+		members:   members,
+	}, nil
+}
+
+// newEnsembleFromConfig creates the members from the registry, each with the
+// shared detector settings and its own options
+func newEnsembleFromConfig(cfg config.DetectorConfig, options EnsembleOptions) (*EnsembleDetector, error) {
+	members := make([]EnsembleMember, 0, len(options.Members))
+	for _, member := range options.Members {
+		memberCfg := cfg
+		memberCfg.Algorithm = member.Algorithm
+		memberCfg.AlgorithmOptions = member.Options
+
+		algo, err := newAlgorithm(member.Algorithm, memberCfg, registry.NodeDecoder(member.Options))
+		if err != nil {
+			return nil, fmt.Errorf("ensemble member %s: %w", member.Algorithm, err)
+		}
+
+		name := member.Name
+		if name == "" {
+			name = member.Algorithm
+		}
+		weight := member.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		members = append(members, EnsembleMember{Name: name, Weight: weight, Algorithm: algo})
+	}
+	return NewEnsembleDetector(options.Voting, options.MinWeight, options.Agreement, members)
+}
+
+// ensembleVote collects the members that flagged one metric
+type ensembleVote struct {
+	anomaly   models.Anomaly // The most severe member anomaly
+	detectors []string
+	weight    float64
+}
+
+// track points the members that can choose their series at the metrics
+func (d *EnsembleDetector) track(metrics []trackedMetric) {
+	for _, member := range d.members {
+		if tracker, ok := member.Algorithm.(metricTracker); ok {
+			tracker.track(metrics)
+		}
+	}
+}
+
+func (d *EnsembleDetector) Detect(current *models.Metrics, historical []models.Metrics) []models.Anomaly {
+	anomalies := []models.Anomaly{}
+
+	var order []string
+	votes := make(map[string]*ensembleVote)
+	totalWeight := 0.0
+	for _, member := range d.members {
+		totalWeight += member.Weight
+
+		voted := make(map[string]bool)
+		for _, anomaly := range member.Algorithm.Detect(current, historical) {
+			key := anomaly.Metric
+			if key == "" {
+				key = string(anomaly.Type)
+			}
+
+			vote, ok := votes[key]
+			if !ok {
+				vote = &ensembleVote{anomaly: anomaly}
+				votes[key] = vote
+				order = append(order, key)
+			} else if anomaly.Severity.Rank() > vote.anomaly.Severity.Rank() {
+				vote.anomaly = anomaly
+			}
+
+			// A member counts once per metric however many anomalies it reports
+			if !voted[key] {
+				voted[key] = true
+				vote.detectors = append(vote.detectors, member.Name)
+				vote.weight += member.Weight
+			}
+		}
+	}
+
+	for _, key := range order {
+		vote := votes[key]
+		if !d.passes(vote, totalWeight) {
+			continue
+		}
+
+		anomaly := vote.anomaly
+		anomaly.Detectors = vote.detectors
+		anomaly.Description = fmt.Sprintf("%s (ensemble: %s)", anomaly.Description, strings.Join(vote.detectors, ", "))
+		if d.agreement {
+			anomaly.Severity = agreementSeverity(vote.weight / totalWeight)
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+
+	return anomalies
+}
+
+// passes applies the voting rule to the members that flagged a metric
+func (d *EnsembleDetector) passes(vote *ensembleVote, totalWeight float64) bool {
+	switch d.voting {
+	case VoteAll:
+		return len(vote.detectors) == len(d.members)
+	case VoteMajority:
+		return 2*len(vote.detectors) > len(d.members)
+	case VoteWeighted:
+		return vote.weight >= d.minWeight*totalWeight-1e-9
+	}
+	return len(vote.detectors) > 0
+}
+
+// agreementSeverity maps the share of the ensemble's weight that flagged a
+// metric to a severity
+func agreementSeverity(share float64) models.Severity {
+	if share >= 1-1e-9 {
+		return models.SeverityCritical
+	} else if share >= 0.75 {
+		return models.SeverityHigh
+	} else if share >= 0.5 {
+		return models.SeverityMedium
+	}
+	return models.SeverityLow
+}
@@ -0,0 +1,173 @@
+package analyzer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// fixedAlgorithm reports the same anomalies on every evaluation and counts
+// how often it was called
+type fixedAlgorithm struct {
+	anomalies []models.Anomaly
+	calls     int
+}
+
+func (f *fixedAlgorithm) Detect(current *models.Metrics, historical []models.Metrics) []models.Anomaly {
+	f.calls++
+	return f.anomalies
+}
+
+// flagging returns an algorithm reporting the given metrics
+func flagging(severity models.Severity, metrics ...string) *fixedAlgorithm {
+	algo := &fixedAlgorithm{}
+	for _, metric := range metrics {
+		algo.anomalies = append(algo.anomalies, models.Anomaly{
+			Type:        models.AnomalyTypeErrorRate,
+			Severity:    severity,
+			Description: "flagged",
+			Metric:      metric,
+		})
+	}
+	return algo
+}
+
+// TestEnsembleDetector_Voting tests each voting rule against members that
+// agree on error_rate and disagree on requests_per_sec
+func TestEnsembleDetector_Voting(t *testing.T) {
+	testCases := []struct {
+		voting    string
+		minWeight float64
+		expected  []string
+	}{
+		{VoteAny, 0, []string{"error_rate", "requests_per_sec", "avg_response_time"}},
+		{VoteAll, 0, []string{"error_rate"}},
+		{VoteMajority, 0, []string{"error_rate", "requests_per_sec"}},
+		{VoteWeighted, 0.5, []string{"error_rate", "avg_response_time"}},
+		{VoteWeighted, 0.9, []string{"error_rate"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.voting, func(t *testing.T) {
+			members := []EnsembleMember{
+				{Name: "a", Weight: 1, Algorithm: flagging(models.SeverityLow, "error_rate", "requests_per_sec")},
+				{Name: "b", Weight: 1, Algorithm: flagging(models.SeverityLow, "error_rate", "requests_per_sec")},
+				{Name: "c", Weight: 3, Algorithm: flagging(models.SeverityLow, "error_rate", "avg_response_time")},
+			}
+			detector, err := NewEnsembleDetector(tc.voting, tc.minWeight, false, members)
+			if err != nil {
+				t.Fatalf("Failed to create ensemble: %v", err)
+			}
+
+			var metrics []string
+			for _, anomaly := range detector.Detect(createTestMetrics(100, 0.05, 50), nil) {
+				metrics = append(metrics, anomaly.Metric)
+			}
+			if !reflect.DeepEqual(metrics, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, metrics)
+			}
+		})
+	}
+}
+
+// TestEnsembleDetector_RecordsMembers tests that the reported anomaly names
+// the members that fired and keeps the most severe member anomaly
+func TestEnsembleDetector_RecordsMembers(t *testing.T) {
+	quiet := flagging(models.SeverityLow)
+	members := []EnsembleMember{
+		{Name: "stddev", Weight: 1, Algorithm: flagging(models.SeverityMedium, "error_rate")},
+		{Name: "ewma", Weight: 1, Algorithm: quiet},
+		{Name: "cusum", Weight: 1, Algorithm: flagging(models.SeverityHigh, "error_rate")},
+	}
+	detector, err := NewEnsembleDetector(VoteMajority, 0, false, members)
+	if err != nil {
+		t.Fatalf("Failed to create ensemble: %v", err)
+	}
+
+	anomalies := detector.Detect(createTestMetrics(100, 0.05, 50), nil)
+	if len(anomalies) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %d", len(anomalies))
+	}
+	anomaly := anomalies[0]
+	if !reflect.DeepEqual(anomaly.Detectors, []string{"stddev", "cusum"}) {
+		t.Errorf("Expected detectors [stddev cusum], got %v", anomaly.Detectors)
+	}
+	if anomaly.Severity != models.SeverityHigh {
+		t.Errorf("Expected the most severe member's severity, got %s", anomaly.Severity)
+	}
+	if !strings.HasSuffix(anomaly.Description, "(ensemble: stddev, cusum)") {
+		t.Errorf("Unexpected description %q", anomaly.Description)
+	}
+	if quiet.calls != 1 {
+		t.Errorf("Expected every member to be evaluated, quiet member ran %d times", quiet.calls)
+	}
+
+	// With agreement severity two of three members is medium
+	detector.agreement = true
+	anomalies = detector.Detect(createTestMetrics(100, 0.05, 50), nil)
+	if anomalies[0].Severity != models.SeverityMedium {
+		t.Errorf("Expected medium severity from agreement, got %s", anomalies[0].Severity)
+	}
+}
+
+// TestEnsembleDetector_FromConfig tests building members from
+// algorithm_options and rejecting bad configurations
+func TestEnsembleDetector_FromConfig(t *testing.T) {
+	var node yaml.Node
+	err := yaml.Unmarshal([]byte(`
+voting: weighted
+min_weight: 0.6
+members:
+  - algorithm: mad
+    weight: 2
+    options:
+      min_baseline: 20
+  - algorithm: cusum
+  - algorithm: cusum
+    name: cusum_tight
+    weight: 0.5
+`), &node)
+	if err != nil {
+		t.Fatalf("Failed to parse options: %v", err)
+	}
+
+	algo, err := NewAlgorithm("ensemble", config.DetectorConfig{SensitivityLevel: 3, AlgorithmOptions: *node.Content[0]})
+	if err != nil {
+		t.Fatalf("Failed to create ensemble: %v", err)
+	}
+	ensemble := algo.(*EnsembleDetector)
+	if ensemble.voting != VoteWeighted || ensemble.minWeight != 0.6 || len(ensemble.members) != 3 {
+		t.Fatalf("Unexpected ensemble %+v", ensemble)
+	}
+	if mad := ensemble.members[0].Algorithm.(*MADDetector); mad.minBaseline != 20 || ensemble.members[0].Weight != 2 {
+		t.Errorf("Expected mad member with its own options and weight 2, got %+v", ensemble.members[0])
+	}
+	if ensemble.members[1].Weight != 1 || ensemble.members[2].Name != "cusum_tight" {
+		t.Errorf("Unexpected members %+v", ensemble.members)
+	}
+
+	if _, err := NewAlgorithm("ensemble", config.DetectorConfig{}); err != nil {
+		t.Errorf("Expected default members to be valid, got %v", err)
+	}
+
+	invalid := []string{
+		`voting: plurality`,
+		`members: []`,
+		`members: [{algorithm: nope}]`,
+		`members: [{algorithm: cusum}, {algorithm: cusum}]`,
+		`{voting: weighted, min_weight: 0}`,
+	}
+	for _, options := range invalid {
+		var node yaml.Node
+		if err := yaml.Unmarshal([]byte(options), &node); err != nil {
+			t.Fatalf("Failed to parse options: %v", err)
+		}
+		if _, err := NewAlgorithm("ensemble", config.DetectorConfig{AlgorithmOptions: *node.Content[0]}); err == nil {
+			t.Errorf("Expected error for %s", options)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// residualSmoothing weights each new residual in the smoothed residual size
+const residualSmoothing = 0.02
+
+// minResiduals is how many evaluations are scored before alerting, so the
+// smoothed residual size has settled
+const minResiduals = int(1 / residualSmoothing)
+
+// HoltWintersOptions configures the seasonal detector, decoded from
+// detector.algorithm_options
+type HoltWintersOptions struct {
+	Seasons    []time.Duration `yaml:"seasons"`     // Season lengths, e.g. 24h and 168h
+	Bucket     time.Duration   `yaml:"bucket"`      // Resolution of the seasonal profile
+	Alpha      float64         `yaml:"alpha"`       // Level smoothing (0-1)
+	Beta       float64         `yaml:"beta"`        // Trend smoothing (0-1)
+	Gamma      float64         `yaml:"gamma"`       // Seasonal smoothing (0-1)
+	MinBuckets int             `yaml:"min_buckets"` // Completed buckets before alerting, in addition to the first season
+}
+
+// DefaultHoltWintersOptions returns daily and weekly seasons at 5 minute
+// resolution
+func DefaultHoltWintersOptions() HoltWintersOptions {
+	return HoltWintersOptions{
+		Seasons:    []time.Duration{24 * time.Hour, 7 * 24 * time.Hour},
+		Bucket:     5 * time.Minute,
+		Alpha:      0.05,
+		Beta:       0.01,
+		Gamma:      0.3,
+		MinBuckets: 12,
+	}
+}
+
+// HoltWintersDetector uses additive Holt-Winters (triple exponential)
+// smoothing with one or more seasons, so the expected value follows the
+// time-of-day and day-of-week profile of each metric. Metrics are averaged
+// into buckets that update the level, trend and seasonal components, and
+// each evaluation is compared with the forecast for its bucket.
+//
+// The first full cycle of the shortest season initializes the level and that
+// season's profile, and nothing is reported before it; longer seasons are
+// learned from then on and join the forecast after two full cycles.
+type HoltWintersDetector struct {
+	threshold float64 // Alert when the residual exceeds this many standard deviations
+	options   HoltWintersOptions
+	metrics   []trackedMetric
+	models    []*seasonalModel // One per tracked metric
+}
+
+// seasonalModel is the Holt-Winters state for one metric
+type seasonalModel struct {
+	level       float64
+	trend       float64
+	seasonals   [][]float64 // Per season, one value per bucket in the season
+	shortest    int         // Index of the shortest season
+	observed    []bool      // Slots of the shortest season seen in the first cycle
+	first       int64       // First bucket folded into the model
+	initialized bool
+
+	bucket  int64   // Current bucket number since the Unix epoch
+	sum     float64 // Sum of observations in the current bucket
+	count   int
+	buckets int // Completed buckets folded into the model
+
+	deviation float64 // Smoothed absolute residual
+	residuals int     // Evaluations scored since initialization
+}
+
+// NewHoltWintersDetector creates a seasonal detector. Every season must be a
+// whole number of buckets.
+func NewHoltWintersDetector(threshold float64, options HoltWintersOptions) (*HoltWintersDetector, error) {
+	if threshold <= 0 {
+		threshold = 3.0
+	}
+	if options.Bucket <= 0 {
+		return nil, fmt.Errorf("bucket must be positive, got %s", options.Bucket)
+	}
+	if len(options.Seasons) == 0 {
+		return nil, fmt.Errorf("at least one season is required")
+	}
+	for _, season := range options.Seasons {
+		if season < options.Bucket || season%options.Bucket != 0 {
+			return nil, fmt.Errorf("season %s must be a multiple of bucket %s", season, options.Bucket)
+		}
+	}
+	for name, factor := range map[string]float64{"alpha": options.Alpha, "beta": options.Beta, "gamma": options.Gamma} {
+		if factor <= 0 || factor >= 1 {
+			return nil, fmt.Errorf("%s must be between 0 and 1, got %v", name, factor)
+		}
+	}
+
+	detector := &HoltWintersDetector{threshold: threshold, options: options}
+	detector.track(trackedMetrics)
+	return detector, nil
+}
+
+// track sets the metrics modelled, each starting from scratch
+func (d *HoltWintersDetector) track(metrics []trackedMetric) {
+	d.metrics = metrics
+	d.models = make([]*seasonalModel, len(metrics))
+	for i := range metrics {
+		model := &seasonalModel{}
+		for j, season := range d.options.Seasons {
+			model.seasonals = append(model.seasonals, make([]float64, season/d.options.Bucket))
+			if season < d.options.Seasons[model.shortest] {
+				model.shortest = j
+			}
+		}
+		model.observed = make([]bool, len(model.seasonals[model.shortest]))
+		d.models[i] = model
+	}
+}
+
+func (d *HoltWintersDetector) Detect(current *models.Metrics, historical []models.Metrics) []models.Anomaly {
+	anomalies := []models.Anomaly{}
+
+	timestamp := current.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	bucket := timestamp.UnixNano() / int64(d.options.Bucket)
+
+	for i, metric := range d.metrics {
+		model := d.models[i]
+		value := metric.value(current)
+		model.advance(bucket, d.options)
+
+		if model.initialized {
+			expected := model.forecast(bucket)
+			residual := value - expected
+			stdDev := model.deviation * meanAbsToStdDev
+
+			if model.buckets >= d.options.MinBuckets && model.residuals >= minResiduals && exceedsResidual(residual, stdDev, d.threshold, metric.increasesOnly) {
+				anomalies = append(anomalies, models.Anomaly{
+					Timestamp:     time.Now(),
+					Type:          metric.anomalyType,
+					Severity:      calculateSeverity(value, expected, stdDev),
+					Description:   metric.description + " (seasonal baseline)",
+					Metric:        metric.name,
+					ActualValue:   value,
+					ExpectedValue: expected,
+					Deviation:     math.Abs(residual),
+				})
+			}
+
+			model.deviation = residualSmoothing*math.Abs(residual) + (1-residualSmoothing)*model.deviation
+			model.residuals++
+		}
+
+		model.sum += value
+		model.count++
+	}
+
+	return anomalies
+}
+
+// advance folds the finished bucket into the model when a new one starts
+func (m *seasonalModel) advance(bucket int64, options HoltWintersOptions) {
+	if bucket != m.bucket && m.count > 0 {
+		m.update(m.bucket, m.sum/float64(m.count), options)
+		m.sum, m.count = 0, 0
+	}
+	m.bucket = bucket
+}
+
+// update applies the Holt-Winters equations for one bucket average. Bucket
+// averages of the first cycle of the shortest season are stored in its
+// profile until the cycle is complete.
+func (m *seasonalModel) update(bucket int64, value float64, options HoltWintersOptions) {
+	m.buckets++
+	if !m.initialized {
+		if m.buckets == 1 {
+			m.first = bucket
+		}
+		shortest := m.seasonals[m.shortest]
+		end := m.first + int64(len(shortest)) - 1 // Last bucket of the first cycle
+		if bucket <= end {
+			slot := seasonSlot(bucket, len(shortest))
+			shortest[slot], m.observed[slot] = value, true
+			if bucket == end {
+				m.initialize()
+			}
+			return
+		}
+		// The end of the first cycle had no observations
+		m.initialize()
+	}
+
+	seasonal := m.seasonal(bucket)
+	previousLevel := m.level
+	m.level = options.Alpha*(value-seasonal) + (1-options.Alpha)*(m.level+m.trend)
+	m.trend = options.Beta*(m.level-previousLevel) + (1-options.Beta)*m.trend
+
+	for i, season := range m.seasonals {
+		slot := seasonSlot(bucket, len(season))
+		others := seasonal
+		if m.active(i, bucket) {
+			others -= season[slot]
+		}
+		season[slot] = options.Gamma*(value-m.level-others) + (1-options.Gamma)*season[slot]
+	}
+}
+
+// initialize sets the level to the mean of the first cycle and the shortest
+// season's profile to each bucket's difference from it. Slots without
+// observations start at the level.
+func (m *seasonalModel) initialize() {
+	shortest := m.seasonals[m.shortest]
+	total, count := 0.0, 0
+	for slot, seen := range m.observed {
+		if seen {
+			total += shortest[slot]
+			count++
+		}
+	}
+	m.level = total / float64(count)
+	for slot, seen := range m.observed {
+		if seen {
+			shortest[slot] -= m.level
+		} else {
+			shortest[slot] = 0
+		}
+	}
+	m.observed = nil
+	m.initialized = true
+}
+
+// active reports whether a season is part of the forecast for a bucket: the
+// shortest season always is, longer seasons after two full cycles
+func (m *seasonalModel) active(season int, bucket int64) bool {
+	return season == m.shortest || bucket-m.first >= 2*int64(len(m.seasonals[season]))
+}
+
+// forecast returns the expected value for a bucket
+func (m *seasonalModel) forecast(bucket int64) float64 {
+	return m.level + m.trend + m.seasonal(bucket)
+}
+
+// seasonal returns the sum of the active seasonal components for a bucket
+func (m *seasonalModel) seasonal(bucket int64) float64 {
+	total := 0.0
+	for i, season := range m.seasonals {
+		if m.active(i, bucket) {
+			total += season[seasonSlot(bucket, len(season))]
+		}
+	}
+	return total
+}
+
+func seasonSlot(bucket int64, length int) int {
+	slot := bucket % int64(length)
+	if slot < 0 {
+		slot += int64(length)
+	}
+	return int(slot)
+}
@@ -0,0 +1,168 @@
+package analyzer
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// seasonalRequestRate follows a 60 second cycle between 50 and 150 req/s
+// with a little deterministic noise
+func seasonalRequestRate(second int) float64 {
+	noise := float64((second*7919)%7 - 3)
+	return 100 + 50*math.Sin(2*math.Pi*float64(second)/60) + noise
+}
+
+// newTestHoltWinters creates a detector with a 60 second season of 1 second buckets
+func newTestHoltWinters(t *testing.T) *HoltWintersDetector {
+	t.Helper()
+
+	options := DefaultHoltWintersOptions()
+	options.Seasons = []time.Duration{time.Minute}
+	options.Bucket = time.Second
+	options.MinBuckets = 120
+	detector, err := NewHoltWintersDetector(3.0, options)
+	if err != nil {
+		t.Fatalf("Failed to create detector: %v", err)
+	}
+	return detector
+}
+
+// TestHoltWintersDetector_FollowsSeason tests that the regular cycle raises
+// no anomalies once learned, while a spike against it does
+func TestHoltWintersDetector_FollowsSeason(t *testing.T) {
+	detector := newTestHoltWinters(t)
+	start := time.Unix(1_700_000_040, 0) // A season boundary
+
+	second := 0
+	for ; second < 20*60; second++ {
+		metrics := createTestMetrics(seasonalRequestRate(second), 0.05, 50.0)
+		metrics.Timestamp = start.Add(time.Duration(second) * time.Second)
+
+		anomalies := detector.Detect(metrics, nil)
+		if second >= 15*60 && len(anomalies) > 0 {
+			t.Fatalf("Expected no anomalies for the learned cycle at %ds, got %+v", second, anomalies[0])
+		}
+	}
+
+	// A value that is normal at the peak of the cycle is a spike at its trough
+	trough := second + 45 - second%60
+	for ; second < trough; second++ {
+		metrics := createTestMetrics(seasonalRequestRate(second), 0.05, 50.0)
+		metrics.Timestamp = start.Add(time.Duration(second) * time.Second)
+		detector.Detect(metrics, nil)
+	}
+
+	metrics := createTestMetrics(150, 0.05, 50.0)
+	metrics.Timestamp = start.Add(time.Duration(second) * time.Second)
+	anomalies := detector.Detect(metrics, nil)
+
+	if len(anomalies) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %d", len(anomalies))
+	}
+	if anomalies[0].Type != models.AnomalyTypeTrafficSpike || anomalies[0].Metric != "requests_per_sec" {
+		t.Errorf("Unexpected anomaly: %+v", anomalies[0])
+	}
+	if math.Abs(anomalies[0].ExpectedValue-50) > 10 {
+		t.Errorf("Expected a seasonal baseline near 50 req/s at the trough, got %.2f", anomalies[0].ExpectedValue)
+	}
+}
+
+// TestHoltWintersDetector_Warmup tests that nothing is reported before
+// MinBuckets buckets have been observed
+func TestHoltWintersDetector_Warmup(t *testing.T) {
+	detector := newTestHoltWinters(t)
+	start := time.Unix(1_700_000_040, 0)
+
+	for second := 0; second < 100; second++ {
+		metrics := createTestMetrics(float64(100+second*10), 0.05, 50.0)
+		metrics.Timestamp = start.Add(time.Duration(second) * time.Second)
+		if anomalies := detector.Detect(metrics, nil); len(anomalies) != 0 {
+			t.Fatalf("Expected no anomalies during warmup, got %d at %ds", len(anomalies), second)
+		}
+	}
+}
+
+// dailyRequestRate is 20 req/s at night, ramping up to 120 req/s between 06:00
+// and 09:00 and back down between 18:00 and 21:00, with a little noise
+func dailyRequestRate(minute int) float64 {
+	hour := float64(minute%(24*60)) / 60
+	rate := 20.0
+	switch {
+	case hour >= 6 && hour < 9:
+		rate += 100 * (hour - 6) / 3
+	case hour >= 9 && hour < 18:
+		rate += 100
+	case hour >= 18 && hour < 21:
+		rate += 100 * (21 - hour) / 3
+	}
+	return rate + float64((minute*7919)%5-2)
+}
+
+// TestHoltWintersDetector_DailyRamp tests that with the default options the
+// morning ramp learned on the first day does not alert on the second, while
+// a spike at night does
+func TestHoltWintersDetector_DailyRamp(t *testing.T) {
+	detector, err := NewHoltWintersDetector(3.0, DefaultHoltWintersOptions())
+	if err != nil {
+		t.Fatalf("Failed to create detector: %v", err)
+	}
+	start := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+	minute := 0
+	for ; minute < 2*24*60; minute++ {
+		metrics := createTestMetrics(dailyRequestRate(minute), 0.05, 50.0)
+		metrics.Timestamp = start.Add(time.Duration(minute) * time.Minute)
+		if anomalies := detector.Detect(metrics, nil); len(anomalies) > 0 {
+			t.Fatalf("Expected no anomalies on days 1 and 2, got %+v at minute %d", anomalies[0], minute)
+		}
+	}
+
+	metrics := createTestMetrics(120, 0.05, 50.0) // A daytime rate at midnight
+	metrics.Timestamp = start.Add(time.Duration(minute) * time.Minute)
+	anomalies := detector.Detect(metrics, nil)
+	if len(anomalies) != 1 || anomalies[0].Metric != "requests_per_sec" {
+		t.Fatalf("Expected a requests_per_sec anomaly, got %+v", anomalies)
+	}
+	if math.Abs(anomalies[0].ExpectedValue-20) > 5 {
+		t.Errorf("Expected a night baseline near 20 req/s, got %.2f", anomalies[0].ExpectedValue)
+	}
+}
+
+// TestHoltWintersDetector_Options tests option decoding and validation
+func TestHoltWintersDetector_Options(t *testing.T) {
+	var cfg config.DetectorConfig
+	yamlConfig := "algorithm_options:\n  seasons: [1h, 24h]\n  bucket: 10m\n  min_buckets: 6\n"
+	if err := yaml.Unmarshal([]byte(yamlConfig), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	algo, err := NewAlgorithm("holt_winters", cfg)
+	if err != nil {
+		t.Fatalf("Failed to create holt_winters: %v", err)
+	}
+	detector := algo.(*HoltWintersDetector)
+	if len(detector.models[0].seasonals) != 2 || len(detector.models[0].seasonals[1]) != 144 {
+		t.Errorf("Expected hourly and daily seasons of 10m buckets, got %d seasons", len(detector.models[0].seasonals))
+	}
+	if detector.options.MinBuckets != 6 || detector.options.Alpha != 0.05 {
+		t.Errorf("Expected decoded options merged over defaults, got %+v", detector.options)
+	}
+
+	options := DefaultHoltWintersOptions()
+	options.Seasons = []time.Duration{7 * time.Minute}
+	if _, err := NewHoltWintersDetector(3.0, options); err == nil || !strings.Contains(err.Error(), "multiple of bucket") {
+		t.Errorf("Expected season/bucket mismatch error, got %v", err)
+	}
+
+	options = DefaultHoltWintersOptions()
+	options.Gamma = 1.5
+	if _, err := NewHoltWintersDetector(3.0, options); err == nil {
+		t.Error("Expected error for gamma outside (0, 1)")
+	}
+}
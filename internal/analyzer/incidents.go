@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// defaultIncidentQuietPeriod is how long an incident goes without anomalies
+// before it is resolved
+const defaultIncidentQuietPeriod = 60 * time.Second
+
+// incidentManager groups consecutive anomalies into incidents and reports
+// their state transitions: opened, detected again or escalated, and resolved
+// after a quiet period
+type incidentManager struct {
+	quietPeriod time.Duration
+
+	mu       sync.Mutex
+	open     map[string]*models.Incident // By incidentKey
+	sequence uint64
+	resolved uint64
+}
+
+// newIncidentManager creates the incident manager, or returns nil when
+// incidents are disabled
+func newIncidentManager(cfg config.IncidentConfig) *incidentManager {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	// Default values if not specified
+	quietPeriod := time.Duration(cfg.QuietPeriodSeconds) * time.Second
+	if quietPeriod <= 0 {
+		quietPeriod = defaultIncidentQuietPeriod
+	}
+
+	return &incidentManager{
+		quietPeriod: quietPeriod,
+		open:        make(map[string]*models.Incident),
+	}
+}
+
+// incidentKey identifies the incident an anomaly belongs to
+func incidentKey(anomaly models.Anomaly) string {
+	return fmt.Sprintf("%s|%s|%s|%s", anomaly.Type, anomaly.Metric, anomaly.Dimension, anomaly.Key)
+}
+
+// update adds an evaluation's anomalies to their incidents and returns the
+// transitions: new incidents, incidents detected for the second time or
+// escalated to a higher severity, and incidents quiet since before
+// now - quietPeriod, which are resolved
+func (m *incidentManager) update(anomalies []models.Anomaly, now time.Time) []models.Incident {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var transitions []models.Incident
+	for _, anomaly := range anomalies {
+		key := incidentKey(anomaly)
+		incident, ok := m.open[key]
+		if !ok {
+			m.sequence++
+			incident = &models.Incident{
+				ID:           fmt.Sprintf("%s-%d", now.UTC().Format("20060102-150405"), m.sequence),
+				State:        models.IncidentOpen,
+				Type:         anomaly.Type,
+				Metric:       anomaly.Metric,
+				Dimension:    anomaly.Dimension,
+				Key:          anomaly.Key,
+				StartedAt:    now,
+				LastSeen:     now,
+				PeakSeverity: anomaly.Severity,
+				Detections:   1,
+				Anomaly:      anomaly,
+			}
+			m.open[key] = incident
+			transitions = append(transitions, *incident)
+			continue
+		}
+
+		incident.LastSeen = now
+		incident.Detections++
+		incident.Anomaly = anomaly
+		escalated := anomaly.Severity.Rank() > incident.PeakSeverity.Rank()
+		if escalated {
+			incident.PeakSeverity = anomaly.Severity
+		}
+		if incident.State == models.IncidentOpen || escalated {
+			incident.State = models.IncidentOngoing
+			transitions = append(transitions, *incident)
+		}
+	}
+
+	keys := make([]string, 0, len(m.open))
+	for key := range m.open {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		incident := m.open[key]
+		if now.Sub(incident.LastSeen) < m.quietPeriod {
+			continue
+		}
+		resolvedAt := now
+		incident.State = models.IncidentResolved
+		incident.ResolvedAt = &resolvedAt
+		transitions = append(transitions, *incident)
+		delete(m.open, key)
+		m.resolved++
+	}
+	return transitions
+}
+
+// reportStats adds the number of open, opened and resolved incidents
+func (m *incidentManager) reportStats(counters map[string]uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counters["open"] = uint64(len(m.open))
+	counters["opened"] = m.sequence
+	counters["resolved"] = m.resolved
+}
@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// errorRateAnomaly returns an error rate anomaly with the given severity
+func errorRateAnomaly(severity models.Severity) models.Anomaly {
+	return models.Anomaly{Type: models.AnomalyTypeErrorRate, Metric: "error_rate", Severity: severity}
+}
+
+// TestIncidentManager_Lifecycle tests that a run of detections produces
+// open, ongoing, escalation and resolved transitions only
+func TestIncidentManager_Lifecycle(t *testing.T) {
+	manager := newIncidentManager(config.IncidentConfig{Enabled: true, QuietPeriodSeconds: 30})
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	var transitions []models.Incident
+	for second := 0; second < 300; second++ {
+		severity := models.SeverityMedium
+		if second >= 120 {
+			severity = models.SeverityHigh
+		}
+		if second == 200 {
+			severity = models.SeverityLow // Lower severity does not change the peak
+		}
+		transitions = append(transitions, manager.update([]models.Anomaly{errorRateAnomaly(severity)}, at(second))...)
+	}
+	for second := 300; second <= 330; second++ {
+		transitions = append(transitions, manager.update(nil, at(second))...)
+	}
+
+	expected := []models.IncidentState{models.IncidentOpen, models.IncidentOngoing, models.IncidentOngoing, models.IncidentResolved}
+	if len(transitions) != len(expected) {
+		t.Fatalf("Expected %d transitions, got %+v", len(expected), transitions)
+	}
+	for i, state := range expected {
+		if transitions[i].State != state || transitions[i].ID != "20261018-120000-1" {
+			t.Errorf("Transition %d: expected %s of one incident, got %s %s", i, state, transitions[i].State, transitions[i].ID)
+		}
+	}
+	if transitions[2].PeakSeverity != models.SeverityHigh || !transitions[2].LastSeen.Equal(at(120)) {
+		t.Errorf("Expected escalation to high at 120s, got %+v", transitions[2])
+	}
+
+	resolved := transitions[3]
+	if resolved.Detections != 300 || !resolved.StartedAt.Equal(at(0)) || !resolved.LastSeen.Equal(at(299)) || !resolved.ResolvedAt.Equal(at(329)) {
+		t.Errorf("Unexpected resolved incident %+v", resolved)
+	}
+	if resolved.PeakSeverity != models.SeverityHigh || resolved.Anomaly.Severity != models.SeverityHigh {
+		t.Errorf("Expected peak and latest severity high, got %s and %s", resolved.PeakSeverity, resolved.Anomaly.Severity)
+	}
+
+	// After resolving, a new detection opens a new incident
+	reopened := manager.update([]models.Anomaly{errorRateAnomaly(models.SeverityLow)}, at(400))
+	if len(reopened) != 1 || reopened[0].State != models.IncidentOpen || reopened[0].ID != "20261018-120640-2" {
+		t.Errorf("Expected a new incident, got %+v", reopened)
+	}
+}
+
+// TestIncidentManager_Grouping tests that anomalies are grouped by type,
+// metric and dimension key
+func TestIncidentManager_Grouping(t *testing.T) {
+	manager := newIncidentManager(config.IncidentConfig{Enabled: true})
+	now := time.Now()
+
+	checkout := errorRateAnomaly(models.SeverityHigh)
+	checkout.Dimension, checkout.Key = "path", "/api/checkout"
+	anomalies := []models.Anomaly{
+		errorRateAnomaly(models.SeverityHigh),
+		checkout,
+		{Type: models.AnomalyTypeResponseTime, Metric: "avg_response_time", Severity: models.SeverityLow},
+	}
+
+	opened := manager.update(anomalies, now)
+	if len(opened) != 3 {
+		t.Fatalf("Expected 3 incidents, got %+v", opened)
+	}
+	if opened[1].Dimension != "path" || opened[1].Key != "/api/checkout" {
+		t.Errorf("Expected the path incident to keep its key, got %+v", opened[1])
+	}
+
+	counters := make(map[string]uint64)
+	manager.reportStats(counters)
+	if counters["open"] != 3 || counters["opened"] != 3 || counters["resolved"] != 0 {
+		t.Errorf("Unexpected counters %v", counters)
+	}
+
+	// Still within the default quiet period
+	if transitions := manager.update(nil, now.Add(59*time.Second)); len(transitions) != 0 {
+		t.Errorf("Expected no transitions, got %+v", transitions)
+	}
+	if newIncidentManager(config.IncidentConfig{}) != nil {
+		t.Error("Expected no manager when disabled")
+	}
+}
@@ -0,0 +1,108 @@
+package analyzer
+
+import (
+	"math"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// madToStdDev scales a median absolute deviation to a standard deviation for
+// normally distributed data (1 / 0.6745)
+const madToStdDev = 1.4826
+
+// MADOptions configures the median/MAD detector, decoded from
+// detector.algorithm_options
+type MADOptions struct {
+	Threshold   float64 `yaml:"threshold"`    // Modified z-score to alert at; defaults to sensitivity_level
+	MinBaseline int     `yaml:"min_baseline"` // Historical windows needed before alerting
+}
+
+// MADDetector compares each metric with the median of the baseline windows,
+// scaled by the median absolute deviation (the modified z-score). Unlike the
+// mean and standard deviation, neither moves much when the baseline contains
+// earlier incidents, so one spike does not mask the next. The current window
+// is excluded from its own baseline.
+type MADDetector struct {
+	threshold   float64
+	minBaseline int
+	metrics     []trackedMetric
+}
+
+// NewMADDetector creates a median/MAD detector
+func NewMADDetector(threshold float64, minBaseline int) *MADDetector {
+	// Default values if not specified
+	if threshold <= 0 {
+		threshold = 3.5
+	}
+	if minBaseline <= 0 {
+		minBaseline = 10
+	}
+
+	return &MADDetector{
+		threshold:   threshold,
+		minBaseline: minBaseline,
+		metrics:     trackedMetrics,
+	}
+}
+
+// track sets the metrics checked
+func (d *MADDetector) track(metrics []trackedMetric) {
+	d.metrics = metrics
+}
+
+func (d *MADDetector) Detect(current *models.Metrics, historical []models.Metrics) []models.Anomaly {
+	anomalies := []models.Anomaly{}
+
+	baseline := baselineWindows(current, historical)
+	if len(baseline) < d.minBaseline {
+		return anomalies // Not enough data for baseline
+	}
+
+	values := make([]float64, len(baseline))
+	for _, metric := range d.metrics {
+		for i := range baseline {
+			values[i] = metric.value(&baseline[i])
+		}
+		center, scale := robustStats(values)
+
+		value := metric.value(current)
+		residual := value - center
+		if !exceedsResidual(residual, scale, d.threshold, metric.increasesOnly) {
+			continue
+		}
+
+		anomalies = append(anomalies, models.Anomaly{
+			Timestamp:     time.Now(),
+			Type:          metric.anomalyType,
+			Severity:      calculateSeverity(value, center, scale),
+			Description:   metric.description,
+			Metric:        metric.name,
+			ActualValue:   value,
+			ExpectedValue: center,
+			Deviation:     math.Abs(residual),
+		})
+	}
+
+	return anomalies
+}
+
+// robustStats returns the median of values and a robust standard deviation
+// estimate from the median absolute deviation. When more than half the values
+// are identical the MAD is zero, so the mean absolute deviation from the
+// median is used instead. values is reordered.
+func robustStats(values []float64) (center, scale float64) {
+	center = median(values)
+
+	deviations := make([]float64, len(values))
+	total := 0.0
+	for i, v := range values {
+		deviations[i] = math.Abs(v - center)
+		total += deviations[i]
+	}
+
+	if mad := median(deviations); mad > 0 {
+		return center, mad * madToStdDev
+	}
+	return center, total / float64(len(values)) * meanAbsToStdDev
+}
@@ -0,0 +1,91 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// createBaselineWithIncident creates 20 normal windows around 100 req/s and
+// two windows of an earlier 400 req/s incident
+func createBaselineWithIncident(start time.Time) []models.Metrics {
+	historical := make([]models.Metrics, 0, 22)
+	for i := 0; i < 22; i++ {
+		reqPerSec := 100.0 + float64(i%5-2)
+		if i == 8 || i == 9 {
+			reqPerSec = 400
+		}
+		metrics := *createTestMetrics(reqPerSec, 0.05, 50.0)
+		metrics.Timestamp = start.Add(time.Duration(i) * time.Second)
+		historical = append(historical, metrics)
+	}
+	return historical
+}
+
+// TestMADDetector_OutlierInBaseline tests that an earlier incident in the
+// baseline does not mask a new one, unlike mean/stddev
+func TestMADDetector_OutlierInBaseline(t *testing.T) {
+	start := time.Now()
+	historical := createBaselineWithIncident(start)
+
+	// The collector archives the current window before detection runs
+	current := createTestMetrics(160, 0.05, 50.0)
+	current.Timestamp = start.Add(22 * time.Second)
+	historical = append(historical, *current)
+
+	stddev := &StdDevDetector{threshold: 3.0}
+	if anomalies := stddev.Detect(current, historical); len(anomalies) != 0 {
+		t.Fatalf("Expected the inflated stddev to miss the spike, got %d anomalies", len(anomalies))
+	}
+
+	detector := NewMADDetector(3.5, 10)
+	anomalies := detector.Detect(current, historical)
+	if len(anomalies) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %d", len(anomalies))
+	}
+	if anomalies[0].Type != models.AnomalyTypeTrafficSpike || anomalies[0].ExpectedValue != 100 {
+		t.Errorf("Expected traffic spike against a median of 100, got %+v", anomalies[0])
+	}
+
+	normal := createTestMetrics(102, 0.05, 50.0)
+	if anomalies := detector.Detect(normal, historical[:22]); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies within normal variation, got %d", len(anomalies))
+	}
+}
+
+// TestMADDetector_ConstantBaseline tests the fallback when most of the
+// baseline is identical and the MAD is zero
+func TestMADDetector_ConstantBaseline(t *testing.T) {
+	start := time.Now()
+	historical := createBaselineWithIncident(start)
+	detector := NewMADDetector(3.5, 10)
+
+	same := createTestMetrics(100, 0.05, 50.0)
+	for _, anomaly := range detector.Detect(same, historical) {
+		if anomaly.Metric == "error_rate" || anomaly.Metric == "avg_response_time" {
+			t.Errorf("Expected no anomaly for an unchanged constant metric, got %+v", anomaly)
+		}
+	}
+
+	errors := createTestMetrics(100, 0.25, 50.0)
+	found := false
+	for _, anomaly := range detector.Detect(errors, historical) {
+		if anomaly.Metric == "error_rate" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected an error rate anomaly against a constant baseline")
+	}
+}
+
+// TestMADDetector_MinBaseline tests that short baselines are skipped
+func TestMADDetector_MinBaseline(t *testing.T) {
+	historical := createBaselineWithIncident(time.Now())[:5]
+	detector := NewMADDetector(3.5, 10)
+
+	if anomalies := detector.Detect(createTestMetrics(1000, 0.9, 500.0), historical); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies with 5 baseline windows, got %d", len(anomalies))
+	}
+}
@@ -1,11 +1,13 @@
 package analyzer
 
 import (
+	"math/rand"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/sketch"
 )
 
 // responseTimePool reuses slices for response times to reduce allocations
@@ -22,6 +24,9 @@ type MetricsCollector struct {
 	currentWindow      *MetricsWindow
 	historicalMetrics  []models.Metrics
 	maxHistoricalSize  int
+	sampleSize         int // Entries sampled per window; 0 disables sampling
+	maxMessageBytes    int
+	rng                *rand.Rand
 	mu                 sync.RWMutex
 }
 
@@ -30,11 +35,17 @@ type MetricsWindow struct {
 	startTime       time.Time
 	totalRequests   int
 	errorCount      int
-	responseTimes   []float64
+	responseTimes   *sketch.DDSketch
 	statusCodes     map[int]int
 	paths           map[string]int
 	ips             map[string]int
 	userAgents      map[string]int
+	countries       map[string]int
+	asns            map[string]int
+	uaFamilies      map[string]int
+	clientClasses   map[string]int
+	samples         *entrySampler // nil unless sampling is enabled
+	breakdowns      map[string]map[string]*breakdownCounts // Per dimension and value
 }
 
 // NewMetricsCollector creates a new metrics collector
@@ -55,10 +66,39 @@ func newMetricsWindow() *MetricsWindow {
 		paths:         make(map[string]int, 50),
 		ips:           make(map[string]int, 100),
 		userAgents:    make(map[string]int, 20),
-		responseTimes: make([]float64, 0, 1000),
+		countries:     make(map[string]int, 10),
+		asns:          make(map[string]int, 10),
+		uaFamilies:    make(map[string]int, 20),
+		clientClasses: make(map[string]int, 6),
+		responseTimes: sketch.New(sketch.DefaultRelativeAccuracy),
+		breakdowns:    make(map[string]map[string]*breakdownCounts, len(breakdownDimensions)),
 	}
 }
 
+// SetSampling keeps up to size entries of each window as Metrics.Samples,
+// biased toward errors and slow requests. Messages are truncated to
+// maxMessageBytes. A size of 0 disables sampling.
+func (mc *MetricsCollector) SetSampling(size, maxMessageBytes int) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.sampleSize = size
+	mc.maxMessageBytes = maxMessageBytes
+	if mc.rng == nil {
+		mc.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	mc.currentWindow.samples = mc.newSampler()
+}
+
+// newSampler returns a sampler for a new window, or nil when sampling is
+// disabled
+func (mc *MetricsCollector) newSampler() *entrySampler {
+	if mc.sampleSize <= 0 {
+		return nil
+	}
+	return newEntrySampler(mc.sampleSize, mc.maxMessageBytes)
+}
+
 // AddLogEntry adds a log entry to the current window
 func (mc *MetricsCollector) AddLogEntry(entry *models.LogEntry) {
 	mc.mu.Lock()
@@ -87,7 +127,30 @@ func (mc *MetricsCollector) AddLogEntry(entry *models.LogEntry) {
 	}
 
 	if entry.ResponseTime > 0 {
-		mc.currentWindow.responseTimes = append(mc.currentWindow.responseTimes, entry.ResponseTime)
+		mc.currentWindow.responseTimes.Add(entry.ResponseTime)
+	}
+
+	// Set by the GeoIP enrichment stage, when enabled
+	if country, ok := entry.Extra[models.ExtraGeoCountry].(string); ok {
+		mc.currentWindow.countries[country]++
+	}
+	if asn, ok := entry.Extra[models.ExtraGeoASN].(string); ok {
+		mc.currentWindow.asns[asn]++
+	}
+
+	// Set by the user-agent enrichment stage, when enabled
+	if class, ok := entry.Extra[models.ExtraUAClass].(string); ok {
+		mc.currentWindow.clientClasses[class]++
+		browser, _ := entry.Extra[models.ExtraUABrowser].(string)
+		os, _ := entry.Extra[models.ExtraUAOS].(string)
+		device, _ := entry.Extra[models.ExtraUADevice].(string)
+		mc.currentWindow.uaFamilies[browser+" / "+os+" / "+device]++
+	}
+
+	mc.currentWindow.addBreakdowns(entry)
+
+	if mc.currentWindow.samples != nil {
+		mc.currentWindow.samples.add(entry, mc.currentWindow.responseTimes.Mean(), mc.rng)
 	}
 }
 
@@ -98,17 +161,29 @@ func (mc *MetricsCollector) GetCurrentMetrics() *models.Metrics {
 
 	metrics := mc.computeMetrics(mc.currentWindow)
 
-	// Archive current window and start new one
-	mc.historicalMetrics = append(mc.historicalMetrics, *metrics)
+	// Archive current window and start new one. Samples are only needed
+	// for the current window's anomalies.
+	archived := *metrics
+	archived.Samples = nil
+	mc.historicalMetrics = append(mc.historicalMetrics, archived)
 	if len(mc.historicalMetrics) > mc.maxHistoricalSize {
 		mc.historicalMetrics = mc.historicalMetrics[1:]
 	}
 
 	mc.currentWindow = newMetricsWindow()
+	mc.currentWindow.samples = mc.newSampler()
 
 	return metrics
 }
 
+// CurrentRequests returns the number of requests in the current window
+func (mc *MetricsCollector) CurrentRequests() int {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	return mc.currentWindow.totalRequests
+}
+
 // GetHistoricalMetrics returns historical metrics
 func (mc *MetricsCollector) GetHistoricalMetrics() []models.Metrics {
 	mc.mu.RLock()
@@ -132,24 +207,38 @@ func (mc *MetricsCollector) computeMetrics(window *MetricsWindow) *models.Metric
 		errorRate = float64(window.errorCount) / float64(window.totalRequests)
 	}
 
-	avgResponseTime := 0.0
-	if len(window.responseTimes) > 0 {
-		sum := 0.0
-		for _, rt := range window.responseTimes {
-			sum += rt
-		}
-		avgResponseTime = sum / float64(len(window.responseTimes))
+	botRate := 0.0
+	if classified := sumCounts(window.clientClasses); classified > 0 {
+		botRate = float64(classified-window.clientClasses["human"]) / float64(classified)
+	}
+
+	var samples []models.LogEntry
+	if window.samples != nil {
+		samples = window.samples.sorted()
 	}
 
 	return &models.Metrics{
 		Timestamp:       time.Now(),
 		RequestsPerSec:  requestsPerSec,
 		ErrorRate:       errorRate,
-		AvgResponseTime: avgResponseTime,
+		AvgResponseTime: window.responseTimes.Mean(),
+		ResponseTimeP50: window.responseTimes.Quantile(0.5),
+		ResponseTimeP90: window.responseTimes.Quantile(0.9),
+		ResponseTimeP99: window.responseTimes.Quantile(0.99),
+		ResponseTimes:   window.responseTimes,
 		StatusCodes:     window.statusCodes,
 		TopPaths:        getTopPaths(window.paths, 10),
 		TopIPs:          getTopIPs(window.ips, 10),
 		TopUserAgents:   getTopUserAgents(window.userAgents, 10),
+		TopCountries:    getTopCountries(window.countries, 10),
+		TopASNs:         getTopASNs(window.asns, 10),
+		Countries:       window.countries,
+		ASNs:            window.asns,
+		TopUAFamilies:   getTopUAFamilies(window.uaFamilies, 10),
+		ClientClasses:   window.clientClasses,
+		BotRate:         botRate,
+		Samples:         samples,
+		Breakdowns:      topBreakdowns(window.breakdowns),
 	}
 }
 
@@ -263,3 +352,72 @@ func getTopUserAgents(userAgents map[string]int, limit int) []models.UserAgentCo
 
 	return result
 }
+
+func getTopCountries(countries map[string]int, limit int) []models.CountryCount {
+	sorted := topCounts(countries, limit)
+	if sorted == nil {
+		return nil
+	}
+
+	result := make([]models.CountryCount, len(sorted))
+	for i, kv := range sorted {
+		result[i] = models.CountryCount{Country: kv.key, Count: kv.count}
+	}
+	return result
+}
+
+func getTopASNs(asns map[string]int, limit int) []models.ASNCount {
+	sorted := topCounts(asns, limit)
+	if sorted == nil {
+		return nil
+	}
+
+	result := make([]models.ASNCount, len(sorted))
+	for i, kv := range sorted {
+		result[i] = models.ASNCount{ASN: kv.key, Count: kv.count}
+	}
+	return result
+}
+
+func getTopUAFamilies(families map[string]int, limit int) []models.UserAgentCount {
+	sorted := topCounts(families, limit)
+	if sorted == nil {
+		return nil
+	}
+
+	result := make([]models.UserAgentCount, len(sorted))
+	for i, kv := range sorted {
+		result[i] = models.UserAgentCount{UserAgent: kv.key, Count: kv.count}
+	}
+	return result
+}
+
+// keyCount is a map entry used when ranking counts
+type keyCount struct {
+	key   string
+	count int
+}
+
+// topCounts returns the limit highest counts, ordered by count then key
+func topCounts(counts map[string]int, limit int) []keyCount {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	sorted := make([]keyCount, 0, len(counts))
+	for k, v := range counts {
+		sorted = append(sorted, keyCount{k, v})
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].count != sorted[j].count {
+			return sorted[i].count > sorted[j].count
+		}
+		return sorted[i].key < sorted[j].key
+	})
+
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
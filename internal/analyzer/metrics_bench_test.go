@@ -7,8 +7,8 @@ import (
 	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
 )
 
-// createTestLogEntry creates a test log entry
-func createTestLogEntry(statusCode int, path string, responseTime float64) *models.LogEntry {
+// createBenchLogEntry creates a test log entry
+func createBenchLogEntry(statusCode int, path string, responseTime float64) *models.LogEntry {
 	return &models.LogEntry{
 		Timestamp:    time.Now(),
 		IPAddress:    "192.168.1.100",
@@ -25,7 +25,7 @@ func createTestLogEntry(statusCode int, path string, responseTime float64) *mode
 // BenchmarkMetricsCollection measures metrics aggregation performance
 func BenchmarkMetricsCollection(b *testing.B) {
 	collector := NewMetricsCollector(1000)
-	entry := createTestLogEntry(200, "/api/users", 45.3)
+	entry := createBenchLogEntry(200, "/api/users", 45.3)
 
 	b.ReportAllocs()
 	b.ResetTimer()
@@ -45,7 +45,7 @@ func BenchmarkMetricsCollectionVariedData(b *testing.B) {
 	statusCodes := []int{200, 201, 400, 404, 500}
 
 	for i := range entries {
-		entries[i] = createTestLogEntry(
+		entries[i] = createBenchLogEntry(
 			statusCodes[i%len(statusCodes)],
 			paths[i%len(paths)],
 			float64(10+i%100),
@@ -66,7 +66,7 @@ func BenchmarkGetCurrentMetrics(b *testing.B) {
 
 	// Populate with sample data
 	for i := 0; i < 1000; i++ {
-		collector.AddLogEntry(createTestLogEntry(200, "/api/test", 50.0))
+		collector.AddLogEntry(createBenchLogEntry(200, "/api/test", 50.0))
 	}
 
 	b.ReportAllocs()
@@ -112,7 +112,7 @@ func BenchmarkTopIPsCalculation(b *testing.B) {
 // BenchmarkConcurrentMetricsCollection tests thread-safe performance
 func BenchmarkConcurrentMetricsCollection(b *testing.B) {
 	collector := NewMetricsCollector(10000)
-	entry := createTestLogEntry(200, "/api/test", 50.0)
+	entry := createBenchLogEntry(200, "/api/test", 50.0)
 
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
@@ -128,7 +128,7 @@ func BenchmarkHistoricalMetricsRetrieval(b *testing.B) {
 	// Generate historical data
 	for i := 0; i < 100; i++ {
 		for j := 0; j < 1000; j++ {
-			collector.AddLogEntry(createTestLogEntry(200, "/api/test", 50.0))
+			collector.AddLogEntry(createBenchLogEntry(200, "/api/test", 50.0))
 		}
 		collector.GetCurrentMetrics() // Archive window
 	}
@@ -162,7 +162,7 @@ func BenchmarkFullMetricsPipeline(b *testing.B) {
 
 			// Add 1000 log entries
 			for j := 0; j < 1000; j++ {
-				collector.AddLogEntry(createTestLogEntry(200, "/api/test", 50.0))
+				collector.AddLogEntry(createBenchLogEntry(200, "/api/test", 50.0))
 			}
 
 			// Compute metrics
@@ -179,7 +179,7 @@ func BenchmarkFullMetricsPipeline(b *testing.B) {
 
 			// Add 10000 log entries
 			for j := 0; j < 10000; j++ {
-				collector.AddLogEntry(createTestLogEntry(200, "/api/test", 50.0))
+				collector.AddLogEntry(createBenchLogEntry(200, "/api/test", 50.0))
 			}
 
 			// Compute metrics
@@ -195,7 +195,7 @@ func BenchmarkStatusCodeAggregation(b *testing.B) {
 
 	entries := make([]*models.LogEntry, len(statusCodes))
 	for i, code := range statusCodes {
-		entries[i] = createTestLogEntry(code, "/api/test", 50.0)
+		entries[i] = createBenchLogEntry(code, "/api/test", 50.0)
 	}
 
 	b.ReportAllocs()
@@ -214,7 +214,7 @@ func BenchmarkResponseTimeTracking(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		entry := createTestLogEntry(200, "/api/test", float64(i%1000))
+		entry := createBenchLogEntry(200, "/api/test", float64(i%1000))
 		collector.AddLogEntry(entry)
 	}
 }
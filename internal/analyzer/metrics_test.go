@@ -0,0 +1,154 @@
+package analyzer
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// collectWindow adds entries with the given response times and closes the
+// window
+func collectWindow(collector *MetricsCollector, responseTimes []float64) *models.Metrics {
+	for _, responseTime := range responseTimes {
+		collector.AddLogEntry(createTestLogEntry(200, "/api/users", responseTime))
+	}
+	return collector.GetCurrentMetrics()
+}
+
+// slowTail returns 1000 response times of 20-29ms where slowShare of the
+// requests take 2 seconds instead
+func slowTail(slowShare float64) []float64 {
+	responseTimes := make([]float64, 1000)
+	for i := range responseTimes {
+		responseTimes[i] = 20 + float64(i%10)
+		if float64(i) >= (1-slowShare)*1000 {
+			responseTimes[i] = 2000
+		}
+	}
+	return responseTimes
+}
+
+// TestMetricsCollector_ResponseTimePercentiles tests percentiles computed
+// from the window's sketch
+func TestMetricsCollector_ResponseTimePercentiles(t *testing.T) {
+	collector := NewMetricsCollector(100)
+	responseTimes := make([]float64, 1000)
+	for i := range responseTimes {
+		responseTimes[i] = float64(i + 1)
+	}
+	metrics := collectWindow(collector, responseTimes)
+
+	if metrics.AvgResponseTime != 500.5 {
+		t.Errorf("Expected exact average 500.5, got %v", metrics.AvgResponseTime)
+	}
+	for _, tc := range []struct {
+		actual, expected float64
+	}{
+		{metrics.ResponseTimeP50, 500},
+		{metrics.ResponseTimeP90, 900},
+		{metrics.ResponseTimeP99, 990},
+		{metrics.ResponseTimePercentile(99.9), 999},
+	} {
+		if math.Abs(tc.actual-tc.expected) > 0.01*tc.expected {
+			t.Errorf("Expected percentile within 1%% of %v, got %v", tc.expected, tc.actual)
+		}
+	}
+
+	// Decoded metrics have no sketch, only the stored percentiles
+	decoded := *metrics
+	decoded.ResponseTimes = nil
+	if decoded.ResponseTimePercentile(99) != metrics.ResponseTimeP99 || decoded.ResponseTimePercentile(75) != 0 {
+		t.Error("Expected stored p99 and no p75 without the sketch")
+	}
+}
+
+// TestResolveMetrics tests detector.metrics names
+func TestResolveMetrics(t *testing.T) {
+	metrics, err := resolveMetrics([]string{"error_rate", "response_time_p99", "response_time_p99.9"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(metrics) != 3 || metrics[1].name != "response_time_p99" || !metrics[2].increasesOnly {
+		t.Errorf("Unexpected metrics %+v", metrics)
+	}
+
+	window := &models.Metrics{ResponseTimeP99: 250}
+	if metrics[1].value(window) != 250 {
+		t.Errorf("Expected p99 value 250, got %v", metrics[1].value(window))
+	}
+
+	for _, name := range []string{"response_time_p100", "response_time_pxx", "latency"} {
+		if _, err := resolveMetrics([]string{name}); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+	if defaults, _ := resolveMetrics(nil); len(defaults) != len(trackedMetrics) {
+		t.Errorf("Expected default metrics, got %d", len(defaults))
+	}
+}
+
+// TestMADDetector_TargetsPercentile tests that a p99 regression is reported
+// on its own metric when detector.metrics selects p99
+func TestMADDetector_TargetsPercentile(t *testing.T) {
+	algo, err := NewAlgorithm("mad", config.DetectorConfig{
+		SensitivityLevel: 3.5,
+		Metrics:          []string{"avg_response_time", "response_time_p99"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create mad: %v", err)
+	}
+
+	collector := NewMetricsCollector(100)
+	for i := 0; i < 20; i++ {
+		collectWindow(collector, slowTail(0.001))
+	}
+	historical := collector.GetHistoricalMetrics()
+
+	// Two percent of requests become slow, moving p99 from about 30ms to 2s
+	current := collectWindow(collector, slowTail(0.02))
+	anomalies := algo.Detect(current, historical)
+
+	found := false
+	for _, anomaly := range anomalies {
+		if anomaly.Metric == "response_time_p99" {
+			found = true
+			if anomaly.ActualValue < 1900 {
+				t.Errorf("Expected p99 near 2000ms, got %v", anomaly.ActualValue)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a response_time_p99 anomaly, got %+v", anomalies)
+	}
+}
+
+// TestStdDevDetector_TargetsPercentile tests that the default algorithm
+// honors detector.metrics and that algorithms with fixed series reject it
+func TestStdDevDetector_TargetsPercentile(t *testing.T) {
+	cfg := config.DetectorConfig{SensitivityLevel: 3.0, Metrics: []string{"response_time_p99"}}
+	algo, err := NewAlgorithm("stddev", cfg)
+	if err != nil {
+		t.Fatalf("Failed to create stddev: %v", err)
+	}
+
+	collector := NewMetricsCollector(100)
+	for i := 0; i < 20; i++ {
+		collectWindow(collector, slowTail(0.001))
+	}
+	historical := collector.GetHistoricalMetrics()
+	current := collectWindow(collector, slowTail(0.02))
+
+	anomalies := algo.Detect(current, historical)
+	if len(anomalies) != 1 || anomalies[0].Metric != "response_time_p99" || anomalies[0].Type != models.AnomalyTypeResponseTime {
+		t.Errorf("Expected only a response_time_p99 anomaly, got %+v", anomalies)
+	}
+
+	for _, name := range []string{"moving_average", "cusum", "ensemble"} {
+		if _, err := NewAlgorithm(name, cfg); err == nil || !strings.Contains(err.Error(), "detector.metrics") {
+			t.Errorf("Expected %s to reject detector.metrics, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/drain"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// Defaults for detector.patterns
+const (
+	defaultPatternMinCount = 5
+	defaultPatternExamples = 3
+
+	// minPatternBaseline is how many windows are seen before new templates
+	// are reported, so the templates of normal traffic are learned first
+	minPatternBaseline = 10
+
+	// maxPatternHistory is how many windows of template counts are kept
+	maxPatternHistory = 100
+)
+
+// patternTracker mines log templates from entry messages and counts each
+// template per window. It reports templates that have never been seen and
+// templates whose count spikes above their baseline.
+type patternTracker struct {
+	levels          map[string]bool // Levels whose messages are mined; empty for all
+	sensitivity     float64
+	minCount        int
+	maxExamples     int
+	maxMessageBytes int // Limit on example messages and template text
+
+	mu       sync.Mutex
+	miner    *drain.Miner
+	window   map[int]*templateWindow
+	history  []map[int]int // Template counts of past windows, oldest first
+	messages uint64
+}
+
+// templateWindow is a template's activity in the current window
+type templateWindow struct {
+	count    int
+	created  bool // First seen in this window
+	examples []models.LogEntry
+}
+
+// newPatternTracker creates the template tracker, or returns nil when
+// pattern detection is disabled
+func newPatternTracker(cfg config.DetectorConfig) *patternTracker {
+	patterns := cfg.Patterns
+	if !patterns.Enabled {
+		return nil
+	}
+
+	// Default values if not specified
+	minCount := patterns.MinCount
+	if minCount <= 0 {
+		minCount = defaultPatternMinCount
+	}
+	maxExamples := patterns.Examples
+	if maxExamples <= 0 {
+		maxExamples = defaultPatternExamples
+	}
+	sensitivity := cfg.SensitivityLevel
+	if sensitivity <= 0 {
+		sensitivity = 3.0
+	}
+
+	levels := make(map[string]bool, len(patterns.Levels))
+	for _, level := range patterns.Levels {
+		levels[strings.ToLower(level)] = true
+	}
+
+	return &patternTracker{
+		levels:          levels,
+		sensitivity:     sensitivity,
+		minCount:        minCount,
+		maxExamples:     maxExamples,
+		maxMessageBytes: resolveRelatedLogs(cfg.RelatedLogs).MaxMessageBytes,
+		miner: drain.New(drain.Options{
+			Depth:               patterns.Depth,
+			SimilarityThreshold: patterns.SimilarityThreshold,
+			MaxTemplates:        patterns.MaxTemplates,
+		}),
+		window: make(map[int]*templateWindow),
+	}
+}
+
+// add assigns an entry's message to a template and counts it in the
+// current window
+func (t *patternTracker) add(entry *models.LogEntry) {
+	if entry.Message == "" {
+		return
+	}
+	if len(t.levels) > 0 && !t.levels[strings.ToLower(entry.Level)] {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	template, created := t.miner.Add(entry.Message)
+	t.messages++
+
+	current, ok := t.window[template.ID]
+	if !ok {
+		current = &templateWindow{}
+		t.window[template.ID] = current
+	}
+	current.count++
+	current.created = current.created || created
+	if len(current.examples) < t.maxExamples {
+		current.examples = append(current.examples, sampleEntry(entry, t.maxMessageBytes))
+	}
+}
+
+// evaluate reports new and spiking templates in the current window, then
+// starts a new window
+func (t *patternTracker) evaluate() []models.Anomaly {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var anomalies []models.Anomaly
+	if len(t.history) >= minPatternBaseline {
+		for _, id := range t.sortedTemplates() {
+			current := t.window[id]
+			if current.created {
+				anomalies = append(anomalies, t.newTemplateAnomaly(id, current))
+				continue
+			}
+			if anomaly, ok := t.spikeAnomaly(id, current); ok {
+				anomalies = append(anomalies, anomaly)
+			}
+		}
+	}
+
+	counts := make(map[int]int, len(t.window))
+	for id, current := range t.window {
+		counts[id] = current.count
+	}
+	t.history = append(t.history, counts)
+	if len(t.history) > maxPatternHistory {
+		t.history = t.history[1:]
+	}
+	t.window = make(map[int]*templateWindow)
+
+	return anomalies
+}
+
+// newTemplateAnomaly reports a template seen for the first time. It is high
+// severity when its messages are errors.
+func (t *patternTracker) newTemplateAnomaly(id int, current *templateWindow) models.Anomaly {
+	severity := models.SeverityMedium
+	for _, example := range current.examples {
+		if example.Level == "error" || example.StatusCode >= 500 {
+			severity = models.SeverityHigh
+			break
+		}
+	}
+
+	return models.Anomaly{
+		Timestamp:     time.Now(),
+		Type:          models.AnomalyTypePattern,
+		Severity:      severity,
+		Description:   fmt.Sprintf("New log pattern %q (%d in window)", t.templateText(id), current.count),
+		Metric:        "log_template",
+		ActualValue:   float64(current.count),
+		ExpectedValue: 0,
+		Deviation:     float64(current.count),
+		Dimension:     "template",
+		Key:           strconv.Itoa(id),
+		RelatedLogs:   current.examples,
+	}
+}
+
+// spikeAnomaly reports a template whose count is well above its baseline.
+// Counts of rare templates vary by about their square root, which bounds the
+// standard deviation from below.
+func (t *patternTracker) spikeAnomaly(id int, current *templateWindow) (models.Anomaly, bool) {
+	if current.count < t.minCount {
+		return models.Anomaly{}, false
+	}
+
+	counts := make([]float64, len(t.history))
+	for i, window := range t.history {
+		counts[i] = float64(window[id])
+	}
+	mean, stdDev := meanStdDev(counts)
+	stdDev = math.Max(stdDev, math.Sqrt(math.Max(mean, 1)))
+
+	actual := float64(current.count)
+	if actual <= mean+t.sensitivity*stdDev {
+		return models.Anomaly{}, false
+	}
+
+	return models.Anomaly{
+		Timestamp:     time.Now(),
+		Type:          models.AnomalyTypePattern,
+		Severity:      calculateSeverity(actual, mean, stdDev),
+		Description:   fmt.Sprintf("Log pattern %q spiked to %d (expected %.1f)", t.templateText(id), current.count, mean),
+		Metric:        "log_template",
+		ActualValue:   actual,
+		ExpectedValue: mean,
+		Deviation:     actual - mean,
+		Dimension:     "template",
+		Key:           strconv.Itoa(id),
+		RelatedLogs:   current.examples,
+	}, true
+}
+
+// templateText returns a template's text with <*> in place of its variable
+// tokens, or its ID once the template has been evicted
+func (t *patternTracker) templateText(id int) string {
+	template, ok := t.miner.Template(id)
+	if !ok {
+		return fmt.Sprintf("template %d", id)
+	}
+	return truncateMessage(template.Text, t.maxMessageBytes)
+}
+
+// sortedTemplates returns the template IDs in the current window in order,
+// so anomalies are reported in a stable order
+func (t *patternTracker) sortedTemplates() []int {
+	ids := make([]int, 0, len(t.window))
+	for id := range t.window {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// reportStats adds the number of templates and mined messages
+func (t *patternTracker) reportStats(counters map[string]uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	counters["templates"] = uint64(t.miner.Len())
+	counters["messages"] = t.messages
+}
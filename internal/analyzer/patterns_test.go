@@ -0,0 +1,134 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// newTestPatternTracker creates a pattern tracker for error and warn entries
+func newTestPatternTracker() *patternTracker {
+	return newPatternTracker(config.DetectorConfig{
+		SensitivityLevel: 3.0,
+		Patterns:         config.PatternConfig{Enabled: true, Levels: []string{"error", "warn"}},
+	})
+}
+
+// addMessages adds count error entries with a message built from format and
+// the entry number
+func addMessages(tracker *patternTracker, format string, count int) {
+	for i := 0; i < count; i++ {
+		tracker.add(&models.LogEntry{Level: "error", Message: fmt.Sprintf(format, i)})
+	}
+}
+
+// warmUp evaluates the baseline windows, each with a couple of login
+// failures, and fails on any anomaly
+func warmUp(t *testing.T, tracker *patternTracker) {
+	for i := 0; i < minPatternBaseline; i++ {
+		addMessages(tracker, "user %d failed to log in", 2)
+		if anomalies := tracker.evaluate(); len(anomalies) != 0 {
+			t.Fatalf("Expected no anomalies while learning, got %+v", anomalies)
+		}
+	}
+}
+
+// TestPatternTracker_NewTemplate tests that a never-seen template is reported
+// with example messages
+func TestPatternTracker_NewTemplate(t *testing.T) {
+	tracker := newTestPatternTracker()
+	warmUp(t, tracker)
+
+	addMessages(tracker, "user %d failed to log in", 2)
+	addMessages(tracker, "connection to replica db-%d refused", 4)
+
+	anomalies := tracker.evaluate()
+	if len(anomalies) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %+v", anomalies)
+	}
+	anomaly := anomalies[0]
+	if anomaly.Type != models.AnomalyTypePattern || anomaly.Severity != models.SeverityHigh || anomaly.ActualValue != 4 {
+		t.Errorf("Unexpected anomaly %+v", anomaly)
+	}
+	if anomaly.Description != `New log pattern "connection to replica <*> refused" (4 in window)` || anomaly.Key != "2" {
+		t.Errorf("Expected the template text in the description, got %q", anomaly.Description)
+	}
+	if len(anomaly.RelatedLogs) != defaultPatternExamples || anomaly.RelatedLogs[0].Message != "connection to replica db-0 refused" {
+		t.Errorf("Expected %d example messages, got %+v", defaultPatternExamples, anomaly.RelatedLogs)
+	}
+
+	// Seen before: no longer new
+	addMessages(tracker, "connection to replica db-%d refused", 1)
+	if anomalies := tracker.evaluate(); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies for a known template, got %+v", anomalies)
+	}
+}
+
+// TestPatternTracker_FrequencySpike tests that a known template occurring
+// far more often than usual is reported
+func TestPatternTracker_FrequencySpike(t *testing.T) {
+	tracker := newTestPatternTracker()
+	warmUp(t, tracker)
+
+	addMessages(tracker, "user %d failed to log in", 40)
+	anomalies := tracker.evaluate()
+	if len(anomalies) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %+v", anomalies)
+	}
+	anomaly := anomalies[0]
+	if anomaly.ActualValue != 40 || anomaly.ExpectedValue != 2 || anomaly.Severity != models.SeverityCritical {
+		t.Errorf("Unexpected anomaly %+v", anomaly)
+	}
+	if !strings.Contains(anomaly.Description, `"user <*> failed to log in" spiked to 40`) {
+		t.Errorf("Unexpected description %q", anomaly.Description)
+	}
+
+	// Within the usual variation
+	addMessages(tracker, "user %d failed to log in", 4)
+	if anomalies := tracker.evaluate(); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies, got %+v", anomalies)
+	}
+}
+
+// TestPatternTracker_ExampleSize tests that examples and template text are
+// truncated to related_logs.max_message_bytes
+func TestPatternTracker_ExampleSize(t *testing.T) {
+	tracker := newPatternTracker(config.DetectorConfig{
+		Patterns:    config.PatternConfig{Enabled: true},
+		RelatedLogs: config.RelatedLogsConfig{MaxMessageBytes: 16},
+	})
+	warmUp(t, tracker)
+
+	addMessages(tracker, "disk full on volume %d "+strings.Repeat("x", 100), 1)
+	anomalies := tracker.evaluate()
+	if len(anomalies) != 1 || len(anomalies[0].RelatedLogs) != 1 {
+		t.Fatalf("Expected 1 anomaly with 1 example, got %+v", anomalies)
+	}
+	if example := anomalies[0].RelatedLogs[0].Message; example != "disk full on vol" {
+		t.Errorf("Expected the example truncated to 16 bytes, got %q", example)
+	}
+	if anomalies[0].Description != `New log pattern "disk full on vol" (1 in window)` {
+		t.Errorf("Expected the template text truncated to 16 bytes, got %q", anomalies[0].Description)
+	}
+}
+
+// TestPatternTracker_Levels tests that only configured levels are mined and
+// that disabled pattern detection creates no tracker
+func TestPatternTracker_Levels(t *testing.T) {
+	tracker := newTestPatternTracker()
+	tracker.add(&models.LogEntry{Level: "INFO", Message: "request served"})
+	tracker.add(&models.LogEntry{Level: "WARN", Message: "slow query detected"})
+
+	counters := make(map[string]uint64)
+	tracker.reportStats(counters)
+	if counters["templates"] != 1 || counters["messages"] != 1 {
+		t.Errorf("Expected only the warning mined, got %v", counters)
+	}
+
+	if newPatternTracker(config.DetectorConfig{}) != nil {
+		t.Error("Expected no tracker when disabled")
+	}
+}
@@ -0,0 +1,220 @@
+package analyzer
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// Defaults for detector.related_logs
+const (
+	defaultRelatedLogsMaxEntries      = 5
+	defaultRelatedLogsSampleSize      = 50
+	defaultRelatedLogsMaxMessageBytes = 1024
+)
+
+// Sampling weights: errors are this many times more likely to be kept, and
+// slow requests up to this many times, in proportion to how much slower
+// than the window's mean they are
+const (
+	errorSampleWeight   = 8.0
+	maxSlowSampleWeight = 8.0
+)
+
+// resolveRelatedLogs applies the defaults to the related logs settings
+func resolveRelatedLogs(cfg config.RelatedLogsConfig) config.RelatedLogsConfig {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaultRelatedLogsMaxEntries
+	}
+	if cfg.SampleSize <= 0 {
+		cfg.SampleSize = defaultRelatedLogsSampleSize
+	}
+	if cfg.MaxMessageBytes <= 0 {
+		cfg.MaxMessageBytes = defaultRelatedLogsMaxMessageBytes
+	}
+	return cfg
+}
+
+// entrySampler is a weighted reservoir of a window's entries (A-Res,
+// Efraimidis and Spirakis): each entry gets the key u^(1/weight) for a
+// uniform u, and the entries with the highest keys are kept
+type entrySampler struct {
+	size            int
+	maxMessageBytes int
+	entries         sampleHeap
+}
+
+// sampledEntry is an entry in the reservoir with its key
+type sampledEntry struct {
+	key   float64
+	entry models.LogEntry
+}
+
+// sampleHeap is a min-heap on key, so the entry to replace is at the root
+type sampleHeap []sampledEntry
+
+func (h sampleHeap) Len() int            { return len(h) }
+func (h sampleHeap) Less(i, j int) bool  { return h[i].key < h[j].key }
+func (h sampleHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *sampleHeap) Push(x interface{}) { *h = append(*h, x.(sampledEntry)) }
+func (h *sampleHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+func newEntrySampler(size, maxMessageBytes int) *entrySampler {
+	return &entrySampler{
+		size:            size,
+		maxMessageBytes: maxMessageBytes,
+		entries:         make(sampleHeap, 0, size),
+	}
+}
+
+// add offers an entry to the reservoir. meanResponseTime is the window's
+// mean so far, used to weight slow requests.
+func (s *entrySampler) add(entry *models.LogEntry, meanResponseTime float64, rng *rand.Rand) {
+	key := math.Pow(rng.Float64(), 1/sampleWeight(entry, meanResponseTime))
+	if len(s.entries) >= s.size {
+		if key <= s.entries[0].key {
+			return
+		}
+		heap.Pop(&s.entries)
+	}
+
+	heap.Push(&s.entries, sampledEntry{key: key, entry: sampleEntry(entry, s.maxMessageBytes)})
+}
+
+// sampleEntry copies an entry for use as a related log. The message, paths,
+// user agent and string Extra values are truncated to maxMessageBytes, and
+// Extra is copied so later changes to the entry don't reach the sample.
+func sampleEntry(entry *models.LogEntry, maxMessageBytes int) models.LogEntry {
+	sampled := *entry
+	sampled.Message = truncateMessage(sampled.Message, maxMessageBytes)
+	sampled.Path = truncateMessage(sampled.Path, maxMessageBytes)
+	sampled.RawPath = truncateMessage(sampled.RawPath, maxMessageBytes)
+	sampled.UserAgent = truncateMessage(sampled.UserAgent, maxMessageBytes)
+	if entry.Extra != nil {
+		sampled.Extra = make(map[string]interface{}, len(entry.Extra))
+		for key, value := range entry.Extra {
+			if str, ok := value.(string); ok {
+				value = truncateMessage(str, maxMessageBytes)
+			}
+			sampled.Extra[key] = value
+		}
+	}
+	return sampled
+}
+
+// sorted returns the sampled entries in timestamp order
+func (s *entrySampler) sorted() []models.LogEntry {
+	if len(s.entries) == 0 {
+		return nil
+	}
+	entries := make([]models.LogEntry, len(s.entries))
+	for i, sampled := range s.entries {
+		entries[i] = sampled.entry
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries
+}
+
+// sampleWeight favors errors and requests slower than the window's mean
+func sampleWeight(entry *models.LogEntry, meanResponseTime float64) float64 {
+	weight := 1.0
+	if isErrorEntry(entry) {
+		weight *= errorSampleWeight
+	}
+	if entry.ResponseTime > 0 && meanResponseTime > 0 {
+		weight *= math.Min(math.Max(entry.ResponseTime/meanResponseTime, 1), maxSlowSampleWeight)
+	}
+	return weight
+}
+
+// isErrorEntry matches the collector's definition of an error
+func isErrorEntry(entry *models.LogEntry) bool {
+	return entry.Level == "error" || entry.StatusCode >= 400
+}
+
+// truncateMessage shortens a message to at most maxBytes without splitting
+// a UTF-8 sequence
+func truncateMessage(message string, maxBytes int) string {
+	if len(message) <= maxBytes {
+		return message
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut]
+}
+
+// attachRelatedLogs gives each anomaly without related logs up to maxEntries
+// of the window's sampled entries that are relevant to it
+func attachRelatedLogs(anomalies []models.Anomaly, metrics *models.Metrics, maxEntries int) {
+	if len(metrics.Samples) == 0 {
+		return
+	}
+	for i := range anomalies {
+		if len(anomalies[i].RelatedLogs) == 0 {
+			anomalies[i].RelatedLogs = relatedLogs(anomalies[i], metrics.Samples, maxEntries)
+		}
+	}
+}
+
+// relatedLogs picks the samples that explain an anomaly: errors for error
+// rate and status code anomalies, the slowest requests for response time
+// anomalies, and requests from the shifted country, ASN or automated
+// clients for traffic mix anomalies. Without a relevant sample, any samples
+// are used.
+func relatedLogs(anomaly models.Anomaly, samples []models.LogEntry, maxEntries int) []models.LogEntry {
+	var relevant func(entry *models.LogEntry) bool
+	switch anomaly.Type {
+	case models.AnomalyTypeErrorRate, models.AnomalyTypeStatusCode:
+		relevant = isErrorEntry
+	case models.AnomalyTypeResponseTime:
+		relevant = func(entry *models.LogEntry) bool { return entry.ResponseTime > 0 }
+	case models.AnomalyTypeBotSurge:
+		relevant = func(entry *models.LogEntry) bool {
+			class, ok := entry.Extra[models.ExtraUAClass].(string)
+			return ok && class != "human"
+		}
+	case models.AnomalyTypeGeoShift:
+		if key, ok := strings.CutPrefix(anomaly.Metric, "country_share:"); ok {
+			relevant = func(entry *models.LogEntry) bool { return entry.Extra[models.ExtraGeoCountry] == key }
+		} else if key, ok := strings.CutPrefix(anomaly.Metric, "asn_share:"); ok {
+			relevant = func(entry *models.LogEntry) bool { return entry.Extra[models.ExtraGeoASN] == key }
+		}
+	}
+
+	var selected []models.LogEntry
+	if relevant != nil {
+		for i := range samples {
+			if relevant(&samples[i]) {
+				selected = append(selected, samples[i])
+			}
+		}
+	}
+	if len(selected) == 0 {
+		selected = append(selected, samples...)
+	}
+
+	if anomaly.Type == models.AnomalyTypeResponseTime {
+		sort.SliceStable(selected, func(i, j int) bool {
+			return selected[i].ResponseTime > selected[j].ResponseTime
+		})
+	}
+	if len(selected) > maxEntries {
+		selected = selected[:maxEntries]
+	}
+	return selected
+}
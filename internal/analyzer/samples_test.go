@@ -0,0 +1,143 @@
+package analyzer
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// TestMetricsCollector_Samples tests that sampled entries are bounded,
+// truncated and only kept for the current window
+func TestMetricsCollector_Samples(t *testing.T) {
+	collector := NewMetricsCollector(100)
+	collector.SetSampling(10, 8)
+
+	for i := 0; i < 100; i++ {
+		entry := createTestLogEntry(200, "/api/users", 20)
+		entry.Message = "GET /api/users served"
+		collector.AddLogEntry(entry)
+	}
+	metrics := collector.GetCurrentMetrics()
+
+	if len(metrics.Samples) != 10 {
+		t.Fatalf("Expected 10 samples, got %d", len(metrics.Samples))
+	}
+	if metrics.Samples[0].Message != "GET /api" {
+		t.Errorf("Expected message truncated to 8 bytes, got %q", metrics.Samples[0].Message)
+	}
+	if historical := collector.GetHistoricalMetrics(); historical[0].Samples != nil {
+		t.Error("Expected archived windows without samples")
+	}
+	if truncateMessage("héllo", 2) != "h" {
+		t.Errorf("Expected truncation at a rune boundary, got %q", truncateMessage("héllo", 2))
+	}
+
+	collector.SetSampling(0, 0)
+	collector.AddLogEntry(createTestLogEntry(200, "/", 20))
+	if metrics := collector.GetCurrentMetrics(); metrics.Samples != nil {
+		t.Error("Expected no samples when sampling is disabled")
+	}
+}
+
+// TestSampleEntry tests that every variable-length field is truncated and
+// that the sample does not share Extra with the entry
+func TestSampleEntry(t *testing.T) {
+	entry := &models.LogEntry{
+		Message:   "GET /api/users served",
+		Path:      "/api/users/12345",
+		RawPath:   "/api/users/12345?page=2",
+		UserAgent: "Mozilla/5.0 (X11; Linux x86_64)",
+		Extra:     map[string]interface{}{"trace": "0123456789abcdef", "attempt": 3},
+	}
+
+	sampled := sampleEntry(entry, 8)
+	if sampled.Message != "GET /api" || sampled.Path != "/api/use" || sampled.RawPath != "/api/use" || sampled.UserAgent != "Mozilla/" {
+		t.Errorf("Expected fields truncated to 8 bytes, got %+v", sampled)
+	}
+	if sampled.Extra["trace"] != "01234567" || sampled.Extra["attempt"] != 3 {
+		t.Errorf("Expected string extra values truncated, got %v", sampled.Extra)
+	}
+
+	entry.Extra["trace"] = "changed"
+	entry.Extra["added"] = true
+	if sampled.Extra["trace"] != "01234567" || len(sampled.Extra) != 2 {
+		t.Errorf("Expected the sample's extra to be a copy, got %v", sampled.Extra)
+	}
+}
+
+// TestMetricsCollector_SamplesBiased tests that errors and slow requests are
+// kept far more often than their share of traffic
+func TestMetricsCollector_SamplesBiased(t *testing.T) {
+	errors, slow := 0, 0
+	const trials = 100
+	for trial := 0; trial < trials; trial++ {
+		collector := NewMetricsCollector(100)
+		collector.SetSampling(20, 1024)
+		collector.rng = rand.New(rand.NewSource(int64(trial)))
+
+		// 5% errors and 5% slow requests
+		for i := 0; i < 1000; i++ {
+			switch {
+			case i%20 == 0:
+				collector.AddLogEntry(createTestLogEntry(500, "/", 20))
+			case i%20 == 1:
+				collector.AddLogEntry(createTestLogEntry(200, "/", 2000))
+			default:
+				collector.AddLogEntry(createTestLogEntry(200, "/", 20))
+			}
+		}
+
+		for _, entry := range collector.GetCurrentMetrics().Samples {
+			if entry.StatusCode == 500 {
+				errors++
+			} else if entry.ResponseTime == 2000 {
+				slow++
+			}
+		}
+	}
+
+	// Unbiased sampling would keep about 1 of each per window
+	if errors < 4*trials || slow < 4*trials {
+		t.Errorf("Expected errors and slow requests to be favored, got %.1f errors and %.1f slow per window",
+			float64(errors)/trials, float64(slow)/trials)
+	}
+}
+
+// TestRelatedLogs tests that anomalies get the samples relevant to them
+func TestRelatedLogs(t *testing.T) {
+	samples := []models.LogEntry{
+		*createTestLogEntry(200, "/a", 20),
+		*createTestLogEntry(503, "/b", 30),
+		*createTestLogEntry(200, "/c", 900),
+		*createTestLogEntry(404, "/d", 10),
+		*createTestLogEntry(200, "/e", 400),
+	}
+	samples[0].Extra = map[string]interface{}{models.ExtraGeoCountry: "BR"}
+
+	for _, tc := range []struct {
+		anomaly  models.Anomaly
+		expected []string
+	}{
+		{models.Anomaly{Type: models.AnomalyTypeErrorRate}, []string{"/b", "/d"}},
+		{models.Anomaly{Type: models.AnomalyTypeResponseTime}, []string{"/c", "/e"}},
+		{models.Anomaly{Type: models.AnomalyTypeGeoShift, Metric: "country_share:BR"}, []string{"/a"}},
+		{models.Anomaly{Type: models.AnomalyTypeBotSurge}, []string{"/a", "/b"}}, // No bots sampled: any samples
+	} {
+		related := relatedLogs(tc.anomaly, samples, 2)
+		paths := make([]string, len(related))
+		for i, entry := range related {
+			paths[i] = entry.Path
+		}
+		if len(paths) != len(tc.expected) || (len(paths) > 0 && paths[0] != tc.expected[0]) || (len(paths) > 1 && paths[1] != tc.expected[1]) {
+			t.Errorf("%s %s: expected %v, got %v", tc.anomaly.Type, tc.anomaly.Metric, tc.expected, paths)
+		}
+	}
+
+	// Anomalies that already have evidence keep it
+	anomalies := []models.Anomaly{{Type: models.AnomalyTypePattern, RelatedLogs: samples[:1]}, {Type: models.AnomalyTypeErrorRate}}
+	attachRelatedLogs(anomalies, &models.Metrics{Samples: samples}, 5)
+	if len(anomalies[0].RelatedLogs) != 1 || len(anomalies[1].RelatedLogs) != 2 {
+		t.Errorf("Unexpected related logs %+v", anomalies)
+	}
+}
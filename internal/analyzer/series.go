@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// meanAbsToStdDev converts a mean absolute deviation to an equivalent
+// standard deviation for normally distributed residuals
+const meanAbsToStdDev = 1.25
+
+// trackedMetric describes a metric series checked by the per-series detectors
+type trackedMetric struct {
+	name          string
+	anomalyType   models.AnomalyType
+	description   string
+	increasesOnly bool // Only alert when the value rises above the baseline
+	value         func(*models.Metrics) float64
+}
+
+// trackedMetrics are the series modelled by the per-series detectors
+var trackedMetrics = []trackedMetric{
+	{
+		name:        "error_rate",
+		anomalyType: models.AnomalyTypeErrorRate,
+		description: "Abnormal error rate detected",
+		value:       func(m *models.Metrics) float64 { return m.ErrorRate },
+	},
+	{
+		name:        "requests_per_sec",
+		anomalyType: models.AnomalyTypeTrafficSpike,
+		description: "Traffic spike or drop detected",
+		value:       func(m *models.Metrics) float64 { return m.RequestsPerSec },
+	},
+	{
+		name:          "avg_response_time",
+		anomalyType:   models.AnomalyTypeResponseTime,
+		description:   "Response time degradation detected",
+		increasesOnly: true,
+		value:         func(m *models.Metrics) float64 { return m.AvgResponseTime },
+	},
+}
+
+// responseTimePercentilePrefix names percentile metrics, e.g. response_time_p99
+const responseTimePercentilePrefix = "response_time_p"
+
+// metricTracker is implemented by detectors whose series can be chosen with
+// detector.metrics
+type metricTracker interface {
+	track(metrics []trackedMetric)
+}
+
+// resolveMetrics returns the tracked metrics for names from detector.metrics.
+// Besides the default series, response time percentiles are named
+// response_time_p<N>, e.g. response_time_p99 or response_time_p99.9. No names
+// selects the defaults.
+func resolveMetrics(names []string) ([]trackedMetric, error) {
+	if len(names) == 0 {
+		return trackedMetrics, nil
+	}
+
+	metrics := make([]trackedMetric, 0, len(names))
+	for _, name := range names {
+		metric, err := lookupMetric(name)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+// lookupMetric returns a default tracked metric or a response time percentile
+func lookupMetric(name string) (trackedMetric, error) {
+	for _, metric := range trackedMetrics {
+		if metric.name == name {
+			return metric, nil
+		}
+	}
+
+	if suffix, ok := strings.CutPrefix(name, responseTimePercentilePrefix); ok {
+		percentile, err := strconv.ParseFloat(suffix, 64)
+		if err == nil && percentile > 0 && percentile < 100 {
+			return trackedMetric{
+				name:          name,
+				anomalyType:   models.AnomalyTypeResponseTime,
+				description:   fmt.Sprintf("Response time p%s degradation detected", suffix),
+				increasesOnly: true,
+				value: func(m *models.Metrics) float64 {
+					return m.ResponseTimePercentile(percentile)
+				},
+			}, nil
+		}
+	}
+	return trackedMetric{}, fmt.Errorf("unknown metric %q (use error_rate, requests_per_sec, avg_response_time or response_time_p<N>)", name)
+}
+
+// exceedsResidual reports whether a residual is outside threshold standard
+// deviations. Rounding-level residuals never alert, even on a flat series.
+func exceedsResidual(residual, stdDev, threshold float64, increasesOnly bool) bool {
+	if increasesOnly && residual <= 0 {
+		return false
+	}
+	if math.Abs(residual) < 1e-9 {
+		return false
+	}
+	return math.Abs(residual) > threshold*stdDev
+}
+
+// median returns the median of values, reordering them
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
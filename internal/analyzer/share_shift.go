@@ -0,0 +1,151 @@
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// ShareShiftDetector flags dimension values (countries, ASNs) whose share of
+// traffic in the current window rises well above their baseline share, such
+// as traffic suddenly arriving from a country that is normally absent.
+type ShareShiftDetector struct {
+	dimension   string
+	counts      func(m *models.Metrics) map[string]int
+	threshold   float64 // Minimum increase in share (0-1) over the baseline
+	minRequests int     // Minimum counted requests in the current window
+}
+
+// NewCountryShiftDetector detects shifts in the share of requests per country
+func NewCountryShiftDetector(threshold float64, minRequests int) *ShareShiftDetector {
+	return newShareShiftDetector("country", func(m *models.Metrics) map[string]int {
+		if m.Countries != nil {
+			return m.Countries
+		}
+		// Metrics decoded from JSON only carry the top countries
+		counts := make(map[string]int, len(m.TopCountries))
+		for _, c := range m.TopCountries {
+			counts[c.Country] = c.Count
+		}
+		return counts
+	}, threshold, minRequests)
+}
+
+// NewASNShiftDetector detects shifts in the share of requests per ASN
+func NewASNShiftDetector(threshold float64, minRequests int) *ShareShiftDetector {
+	return newShareShiftDetector("asn", func(m *models.Metrics) map[string]int {
+		if m.ASNs != nil {
+			return m.ASNs
+		}
+		// Metrics decoded from JSON only carry the top ASNs
+		counts := make(map[string]int, len(m.TopASNs))
+		for _, a := range m.TopASNs {
+			counts[a.ASN] = a.Count
+		}
+		return counts
+	}, threshold, minRequests)
+}
+
+func newShareShiftDetector(dimension string, counts func(m *models.Metrics) map[string]int, threshold float64, minRequests int) *ShareShiftDetector {
+	// Default values if not specified
+	if threshold <= 0 || threshold >= 1 {
+		threshold = 0.2
+	}
+	if minRequests <= 0 {
+		minRequests = 20
+	}
+
+	return &ShareShiftDetector{
+		dimension:   dimension,
+		counts:      counts,
+		threshold:   threshold,
+		minRequests: minRequests,
+	}
+}
+
+func (d *ShareShiftDetector) Detect(current *models.Metrics, historical []models.Metrics) []models.Anomaly {
+	anomalies := []models.Anomaly{}
+
+	baseline := baselineWindows(current, historical)
+	if len(baseline) < 10 {
+		return anomalies // Not enough data for baseline
+	}
+
+	currentCounts := d.counts(current)
+	currentTotal := sumCounts(currentCounts)
+	if currentTotal < d.minRequests {
+		return anomalies
+	}
+
+	baselineCounts := make(map[string]int)
+	baselineTotal := 0
+	for i := range baseline {
+		for key, count := range d.counts(&baseline[i]) {
+			baselineCounts[key] += count
+			baselineTotal += count
+		}
+	}
+	if baselineTotal == 0 {
+		return anomalies
+	}
+
+	for key, count := range currentCounts {
+		currentShare := float64(count) / float64(currentTotal)
+		baselineShare := float64(baselineCounts[key]) / float64(baselineTotal)
+		increase := currentShare - baselineShare
+
+		if increase < d.threshold {
+			continue
+		}
+
+		description := fmt.Sprintf("Traffic shifted to %s %s", d.dimension, key)
+		if baselineCounts[key] == 0 {
+			description = fmt.Sprintf("Traffic from new %s %s", d.dimension, key)
+		}
+
+		anomalies = append(anomalies, models.Anomaly{
+			Timestamp:     time.Now(),
+			Type:          models.AnomalyTypeGeoShift,
+			Severity:      calculateShareShiftSeverity(increase, d.threshold),
+			Description:   description,
+			Metric:        d.dimension + "_share:" + key,
+			ActualValue:   currentShare,
+			ExpectedValue: baselineShare,
+			Deviation:     increase,
+		})
+	}
+
+	return anomalies
+}
+
+// calculateShareShiftSeverity scales severity with how far the share moved
+func calculateShareShiftSeverity(increase, threshold float64) models.Severity {
+	ratio := increase / threshold
+	if ratio > 3.0 {
+		return models.SeverityCritical
+	} else if ratio > 2.0 {
+		return models.SeverityHigh
+	} else if ratio > 1.5 {
+		return models.SeverityMedium
+	}
+	return models.SeverityLow
+}
+
+// baselineWindows returns historical windows without the current one.
+// MetricsCollector.GetCurrentMetrics archives the window it returns, so the
+// last historical entry is usually the current window itself.
+func baselineWindows(current *models.Metrics, historical []models.Metrics) []models.Metrics {
+	if n := len(historical); n > 0 && historical[n-1].Timestamp.Equal(current.Timestamp) {
+		return historical[:n-1]
+	}
+	return historical
+}
+
+func sumCounts(counts map[string]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
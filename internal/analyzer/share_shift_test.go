@@ -0,0 +1,123 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// createCountryMetrics creates metrics with the given country counts
+func createCountryMetrics(timestamp time.Time, countries map[string]int) models.Metrics {
+	metrics := *createTestMetrics(100.0, 0.05, 50.0)
+	metrics.Timestamp = timestamp
+	metrics.Countries = countries
+	metrics.TopCountries = getTopCountries(countries, 10)
+	return metrics
+}
+
+// tailCountries returns ten countries with 8 requests each and NZ with nz
+// requests
+func tailCountries(nz int) map[string]int {
+	countries := map[string]int{"NZ": nz}
+	for _, country := range []string{"US", "GB", "DE", "FR", "JP", "BR", "IN", "CA", "AU", "ES"} {
+		countries[country] = 8
+	}
+	return countries
+}
+
+// TestShareShiftDetector_NewCountry tests detection of traffic from a new country
+func TestShareShiftDetector_NewCountry(t *testing.T) {
+	detector := NewCountryShiftDetector(0.2, 20)
+
+	start := time.Now()
+	historical := make([]models.Metrics, 0, 21)
+	for i := 0; i < 20; i++ {
+		historical = append(historical, createCountryMetrics(start.Add(time.Duration(i)*time.Second), map[string]int{"US": 80, "GB": 20}))
+	}
+
+	// Stable window: no shift
+	stable := createCountryMetrics(start.Add(20*time.Second), map[string]int{"US": 78, "GB": 22})
+	if anomalies := detector.Detect(&stable, append(historical, stable)); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies for stable distribution, got %d", len(anomalies))
+	}
+
+	// Half the traffic suddenly arrives from a new country
+	shifted := createCountryMetrics(start.Add(21*time.Second), map[string]int{"US": 50, "KP": 50})
+	anomalies := detector.Detect(&shifted, append(historical, shifted))
+
+	if len(anomalies) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %d", len(anomalies))
+	}
+	anomaly := anomalies[0]
+	if anomaly.Type != models.AnomalyTypeGeoShift || anomaly.Metric != "country_share:KP" {
+		t.Errorf("Unexpected anomaly: %+v", anomaly)
+	}
+	if anomaly.ActualValue != 0.5 || anomaly.ExpectedValue != 0 {
+		t.Errorf("Expected share 0.5 vs baseline 0, got %f vs %f", anomaly.ActualValue, anomaly.ExpectedValue)
+	}
+}
+
+// TestShareShiftDetector_Tail tests that countries outside the top 10 are
+// part of the baseline, so one moving into the top 10 is not reported as new
+func TestShareShiftDetector_Tail(t *testing.T) {
+	detector := NewCountryShiftDetector(0.05, 20)
+
+	start := time.Now()
+	historical := make([]models.Metrics, 0, 21)
+	for i := 0; i < 20; i++ {
+		historical = append(historical, createCountryMetrics(start.Add(time.Duration(i)*time.Second), tailCountries(5)))
+	}
+
+	// NZ moves from 11th to 1st with a share of 10% against a baseline of 6%
+	current := createCountryMetrics(start.Add(20*time.Second), tailCountries(9))
+	if anomalies := detector.Detect(&current, append(historical, current)); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies for a small move in the tail, got %+v", anomalies)
+	}
+
+	// A real shift in the tail is still seen
+	current = createCountryMetrics(start.Add(21*time.Second), tailCountries(40))
+	anomalies := detector.Detect(&current, append(historical, current))
+	if len(anomalies) != 1 || anomalies[0].Metric != "country_share:NZ" || anomalies[0].Description != "Traffic shifted to country NZ" {
+		t.Errorf("Expected a shift to NZ, got %+v", anomalies)
+	}
+}
+
+// TestShareShiftDetector_MinRequests tests that small windows are ignored
+func TestShareShiftDetector_MinRequests(t *testing.T) {
+	detector := NewCountryShiftDetector(0.2, 20)
+
+	historical := make([]models.Metrics, 20)
+	for i := range historical {
+		historical[i] = createCountryMetrics(time.Now(), map[string]int{"US": 100})
+	}
+
+	current := createCountryMetrics(time.Now(), map[string]int{"BR": 5})
+	if anomalies := detector.Detect(&current, historical); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies below min requests, got %d", len(anomalies))
+	}
+}
+
+// TestMetricsCollector_GeoAggregation tests top countries and ASNs
+func TestMetricsCollector_GeoAggregation(t *testing.T) {
+	collector := NewMetricsCollector(100)
+
+	for i := 0; i < 3; i++ {
+		entry := createTestLogEntry(200, "/", 10)
+		entry.Extra = map[string]interface{}{models.ExtraGeoCountry: "DE", models.ExtraGeoASN: "AS3320"}
+		collector.AddLogEntry(entry)
+	}
+	entry := createTestLogEntry(200, "/", 10)
+	entry.Extra = map[string]interface{}{models.ExtraGeoCountry: "FR"}
+	collector.AddLogEntry(entry)
+	collector.AddLogEntry(createTestLogEntry(200, "/", 10))
+
+	metrics := collector.GetCurrentMetrics()
+
+	if len(metrics.TopCountries) != 2 || metrics.TopCountries[0] != (models.CountryCount{Country: "DE", Count: 3}) {
+		t.Errorf("Unexpected top countries: %+v", metrics.TopCountries)
+	}
+	if len(metrics.TopASNs) != 1 || metrics.TopASNs[0] != (models.ASNCount{ASN: "AS3320", Count: 3}) {
+		t.Errorf("Unexpected top ASNs: %+v", metrics.TopASNs)
+	}
+}
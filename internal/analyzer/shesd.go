@@ -0,0 +1,302 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// SHESDOptions configures the Seasonal Hybrid ESD detector, decoded from
+// detector.algorithm_options
+type SHESDOptions struct {
+	Period       int     `yaml:"period"`        // Points per season; 0 skips seasonal decomposition
+	MaxAnomalies float64 `yaml:"max_anomalies"` // Largest fraction of points reported as anomalies (0-0.49)
+	Alpha        float64 `yaml:"alpha"`         // Significance level of the ESD test
+	Window       int     `yaml:"window"`        // Recent windows analyzed when streaming; 0 uses all history
+}
+
+// DefaultSHESDOptions returns the options used when none are configured
+func DefaultSHESDOptions() SHESDOptions {
+	return SHESDOptions{
+		MaxAnomalies: 0.02,
+		Alpha:        0.05,
+	}
+}
+
+// SHESDDetector implements Seasonal Hybrid ESD: the series is decomposed
+// into a median, a seasonal component (the median of each position in the
+// period) and residuals, and the generalized ESD test is run on the
+// residuals using the median and MAD instead of the mean and standard
+// deviation. When streaming it analyzes the recent windows and reports the
+// current one if it is among the outliers; Analyze runs it over a whole
+// series for a batch report.
+type SHESDDetector struct {
+	options SHESDOptions
+	metrics []trackedMetric
+}
+
+// seriesAnomaly is an outlier found at an index of the analyzed series
+type seriesAnomaly struct {
+	index   int
+	anomaly models.Anomaly
+}
+
+// minSHESDPoints is the shortest series the ESD test runs on
+const minSHESDPoints = 10
+
+// NewSHESDDetector creates a Seasonal Hybrid ESD detector
+func NewSHESDDetector(options SHESDOptions) (*SHESDDetector, error) {
+	if options.Period < 0 {
+		return nil, fmt.Errorf("period must not be negative, got %d", options.Period)
+	}
+	if options.MaxAnomalies <= 0 || options.MaxAnomalies >= 0.5 {
+		return nil, fmt.Errorf("max_anomalies must be between 0 and 0.5, got %v", options.MaxAnomalies)
+	}
+	if options.Alpha <= 0 || options.Alpha >= 1 {
+		return nil, fmt.Errorf("alpha must be between 0 and 1, got %v", options.Alpha)
+	}
+	return &SHESDDetector{options: options, metrics: trackedMetrics}, nil
+}
+
+// track sets the metrics tested
+func (d *SHESDDetector) track(metrics []trackedMetric) {
+	d.metrics = metrics
+}
+
+func (d *SHESDDetector) Detect(current *models.Metrics, historical []models.Metrics) []models.Anomaly {
+	anomalies := []models.Anomaly{}
+
+	baseline := baselineWindows(current, historical)
+	series := make([]models.Metrics, 0, len(baseline)+1)
+	series = append(append(series, baseline...), *current)
+	if d.options.Window > 0 && len(series) > d.options.Window {
+		series = series[len(series)-d.options.Window:]
+	}
+
+	for _, found := range d.analyze(series) {
+		if found.index == len(series)-1 {
+			found.anomaly.Timestamp = time.Now()
+			anomalies = append(anomalies, found.anomaly)
+		}
+	}
+	return anomalies
+}
+
+// Analyze runs the test over a whole series, such as replayed metrics, and
+// returns every anomaly in time order with the timestamp of its window
+func (d *SHESDDetector) Analyze(series []models.Metrics) []models.Anomaly {
+	found := d.analyze(series)
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].index < found[j].index
+	})
+
+	anomalies := make([]models.Anomaly, len(found))
+	for i, f := range found {
+		anomalies[i] = f.anomaly
+	}
+	return anomalies
+}
+
+// analyze tests each tracked metric of the series
+func (d *SHESDDetector) analyze(series []models.Metrics) []seriesAnomaly {
+	var found []seriesAnomaly
+	if len(series) < minSHESDPoints {
+		return found
+	}
+
+	maxAnomalies := int(d.options.MaxAnomalies * float64(len(series)))
+	if maxAnomalies < 1 {
+		maxAnomalies = 1
+	}
+
+	values := make([]float64, len(series))
+	for _, metric := range d.metrics {
+		for i := range series {
+			values[i] = metric.value(&series[i])
+		}
+		residuals := seasonalResiduals(values, d.options.Period)
+		center, scale := robustStats(append([]float64(nil), residuals...))
+
+		for _, index := range generalizedESD(residuals, maxAnomalies, d.options.Alpha) {
+			residual := residuals[index] - center
+			if metric.increasesOnly && residual <= 0 {
+				continue
+			}
+
+			expected := values[index] - residual
+			found = append(found, seriesAnomaly{
+				index: index,
+				anomaly: models.Anomaly{
+					Timestamp:     series[index].Timestamp,
+					Type:          metric.anomalyType,
+					Severity:      calculateSeverity(values[index], expected, scale),
+					Description:   metric.description + " (seasonal hybrid ESD)",
+					Metric:        metric.name,
+					ActualValue:   values[index],
+					ExpectedValue: expected,
+					Deviation:     math.Abs(residual),
+				},
+			})
+		}
+	}
+	return found
+}
+
+// seasonalResiduals removes the median and, when the series covers at least
+// two periods, the seasonal component estimated as the median of each
+// position in the period
+func seasonalResiduals(values []float64, period int) []float64 {
+	center := median(append([]float64(nil), values...))
+
+	residuals := make([]float64, len(values))
+	for i, v := range values {
+		residuals[i] = v - center
+	}
+	if period <= 1 || len(values) < 2*period {
+		return residuals
+	}
+
+	seasonal := make([]float64, period)
+	for phase := 0; phase < period; phase++ {
+		var samples []float64
+		for i := phase; i < len(residuals); i += period {
+			samples = append(samples, residuals[i])
+		}
+		seasonal[phase] = median(samples)
+	}
+	for i := range residuals {
+		residuals[i] -= seasonal[i%period]
+	}
+	return residuals
+}
+
+// generalizedESD runs Rosner's generalized extreme Studentized deviate test
+// with robust statistics and returns the indexes of the outliers
+func generalizedESD(values []float64, maxAnomalies int, alpha float64) []int {
+	n := len(values)
+	remaining := make([]int, n)
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	var candidates []int
+	outliers := 0
+	for i := 1; i <= maxAnomalies && len(remaining) > 2; i++ {
+		sample := make([]float64, len(remaining))
+		for j, index := range remaining {
+			sample[j] = values[index]
+		}
+		// Stop once the rest of the residuals are equal up to rounding
+		center, scale := robustStats(sample)
+		if scale < 1e-9 {
+			break
+		}
+
+		worst, worstDeviation := 0, -1.0
+		for j, index := range remaining {
+			if deviation := math.Abs(values[index] - center); deviation > worstDeviation {
+				worst, worstDeviation = j, deviation
+			}
+		}
+		candidates = append(candidates, remaining[worst])
+		remaining = append(remaining[:worst], remaining[worst+1:]...)
+
+		// Critical value for the i-th extreme of the m points tested
+		m := float64(n - i + 1)
+		p := 1 - alpha/(2*m)
+		t := studentTQuantile(p, m-2)
+		lambda := (m - 1) * t / math.Sqrt((m-2+t*t)*m)
+		if worstDeviation/scale > lambda {
+			outliers = i
+		}
+	}
+	return candidates[:outliers]
+}
+
+// studentTQuantile returns the p quantile (p > 0.5) of Student's t
+// distribution with df degrees of freedom
+func studentTQuantile(p, df float64) float64 {
+	low, high := 0.0, 1.0
+	for studentTCDF(high, df) < p && high < 1e6 {
+		high *= 2
+	}
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if studentTCDF(mid, df) < p {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// studentTCDF is the cumulative distribution function of Student's t
+func studentTCDF(t, df float64) float64 {
+	tail := 0.5 * regularizedIncompleteBeta(df/2, 0.5, df/(df+t*t))
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// regularizedIncompleteBeta computes I_x(a, b) with a continued fraction
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lgammaAB, _ := math.Lgamma(a + b)
+	lgammaA, _ := math.Lgamma(a)
+	lgammaB, _ := math.Lgamma(b)
+	front := math.Exp(lgammaAB - lgammaA - lgammaB + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly on this side of the mean
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+// betaContinuedFraction evaluates the incomplete beta continued fraction
+// with the modified Lentz method
+func betaContinuedFraction(a, b, x float64) float64 {
+	const epsilon = 1e-14
+	const tiny = 1e-300
+
+	clamp := func(v float64) float64 {
+		if math.Abs(v) < tiny {
+			return tiny
+		}
+		return v
+	}
+
+	c := 1.0
+	d := 1 / clamp(1-(a+b)*x/(a+1))
+	h := d
+	for m := 1.0; m <= 300; m++ {
+		// Even step
+		numerator := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 / clamp(1+numerator*d)
+		c = clamp(1 + numerator/c)
+		h *= d * c
+
+		// Odd step
+		numerator = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 / clamp(1+numerator*d)
+		c = clamp(1 + numerator/c)
+		delta := d * c
+		h *= delta
+
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,114 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// createSeasonalSeries creates 10 cycles of 24 windows between 50 and 150
+// req/s, with the request rate at the given indexes replaced
+func createSeasonalSeries(start time.Time, overrides map[int]float64) []models.Metrics {
+	series := make([]models.Metrics, 240)
+	for i := range series {
+		reqPerSec := 100 + 50*math.Sin(2*math.Pi*float64(i)/24) + float64(i%5-2)
+		if value, ok := overrides[i]; ok {
+			reqPerSec = value
+		}
+		series[i] = *createTestMetrics(reqPerSec, 0.05, 50.0)
+		series[i].Timestamp = start.Add(time.Duration(i) * time.Minute)
+	}
+	return series
+}
+
+// TestStudentTQuantile tests critical values against published tables
+func TestStudentTQuantile(t *testing.T) {
+	tests := []struct {
+		p, df, want float64
+	}{
+		{0.975, 10, 2.228},
+		{0.95, 5, 2.015},
+		{0.995, 30, 2.750},
+	}
+	for _, tt := range tests {
+		if got := studentTQuantile(tt.p, tt.df); math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("studentTQuantile(%v, %v) = %.4f, want %.3f", tt.p, tt.df, got, tt.want)
+		}
+	}
+}
+
+// TestSHESDDetector_AnalyzeSeasonal tests that off-season values are found
+// only when the seasonal component is removed
+func TestSHESDDetector_AnalyzeSeasonal(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	// 150 req/s is the normal peak at phase 6 but anomalous at the trough (phase 18)
+	series := createSeasonalSeries(start, map[int]float64{42: 150, 186: 150})
+
+	options := DefaultSHESDOptions()
+	options.Period = 24
+	detector, err := NewSHESDDetector(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	anomalies := detector.Analyze(series)
+	if len(anomalies) != 2 {
+		t.Fatalf("Expected 2 anomalies, got %d: %+v", len(anomalies), anomalies)
+	}
+	for i, index := range []int{42, 186} {
+		if !anomalies[i].Timestamp.Equal(series[index].Timestamp) || anomalies[i].Metric != "requests_per_sec" {
+			t.Errorf("Expected anomaly %d at window %d, got %+v", i, index, anomalies[i])
+		}
+		if math.Abs(anomalies[i].ExpectedValue-50) > 5 {
+			t.Errorf("Expected a seasonal expectation near 50, got %.2f", anomalies[i].ExpectedValue)
+		}
+	}
+
+	options.Period = 0
+	detector, _ = NewSHESDDetector(options)
+	if anomalies := detector.Analyze(series); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies without seasonal decomposition, got %d", len(anomalies))
+	}
+}
+
+// TestSHESDDetector_Streaming tests reporting only the current window
+func TestSHESDDetector_Streaming(t *testing.T) {
+	start := time.Now()
+	historical := createSeasonalSeries(start, map[int]float64{10: 400})[:60]
+
+	algo, err := NewAlgorithm("shesd", config.DetectorConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create shesd: %v", err)
+	}
+
+	current := createTestMetrics(500, 0.05, 50.0)
+	current.Timestamp = start.Add(time.Hour)
+	anomalies := algo.Detect(current, append(historical, *current))
+	if len(anomalies) != 1 || anomalies[0].ActualValue != 500 {
+		t.Fatalf("Expected 1 anomaly for the current window only, got %+v", anomalies)
+	}
+
+	normal := createTestMetrics(100, 0.05, 50.0)
+	normal.Timestamp = start.Add(time.Hour)
+	if anomalies := algo.Detect(normal, historical); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies for a normal window, got %d", len(anomalies))
+	}
+}
+
+// TestNewSHESDDetector_Validation tests option validation
+func TestNewSHESDDetector_Validation(t *testing.T) {
+	options := DefaultSHESDOptions()
+	options.MaxAnomalies = 0.6
+	if _, err := NewSHESDDetector(options); err == nil {
+		t.Error("Expected error for max_anomalies above 0.5")
+	}
+
+	options = DefaultSHESDOptions()
+	options.Alpha = 0
+	if _, err := NewSHESDDetector(options); err == nil {
+		t.Error("Expected error for zero alpha")
+	}
+}
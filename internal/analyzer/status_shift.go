@@ -0,0 +1,195 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// maxStatusDrivers is how many codes are named in a status shift description
+const maxStatusDrivers = 3
+
+// StatusCodeShiftDetector compares the current window's status code
+// distribution with the baseline distribution using the Jensen-Shannon
+// divergence (base 2, between 0 and 1). A shift is reported when the
+// divergence is above the threshold and unusual compared with the baseline
+// windows' own divergence, naming the new or surging codes that drove it.
+type StatusCodeShiftDetector struct {
+	threshold   float64 // Minimum divergence (0-1)
+	sensitivity float64 // Standard deviations above the baseline windows' divergence
+	minRequests int     // Minimum requests with a status code in the current window
+}
+
+// statusDriver is a status code whose share rose over the baseline
+type statusDriver struct {
+	code          int
+	share         float64
+	baselineShare float64
+	contribution  float64 // Part of the divergence due to this code
+}
+
+// NewStatusCodeShiftDetector creates a status code distribution detector
+func NewStatusCodeShiftDetector(threshold, sensitivity float64, minRequests int) *StatusCodeShiftDetector {
+	// Default values if not specified
+	if threshold <= 0 || threshold >= 1 {
+		threshold = 0.02
+	}
+	if sensitivity <= 0 {
+		sensitivity = 3.0
+	}
+	if minRequests <= 0 {
+		minRequests = 20
+	}
+
+	return &StatusCodeShiftDetector{
+		threshold:   threshold,
+		sensitivity: sensitivity,
+		minRequests: minRequests,
+	}
+}
+
+func (d *StatusCodeShiftDetector) Detect(current *models.Metrics, historical []models.Metrics) []models.Anomaly {
+	anomalies := []models.Anomaly{}
+
+	baseline := baselineWindows(current, historical)
+	if len(baseline) < 10 {
+		return anomalies // Not enough data for baseline
+	}
+	if sumStatusCodes(current.StatusCodes) < d.minRequests {
+		return anomalies
+	}
+
+	baselineCounts := make(map[int]int)
+	for i := range baseline {
+		for code, count := range baseline[i].StatusCodes {
+			baselineCounts[code] += count
+		}
+	}
+	if sumStatusCodes(baselineCounts) == 0 {
+		return anomalies
+	}
+
+	divergence, drivers := statusDivergence(baselineCounts, current.StatusCodes)
+	if divergence < d.threshold {
+		return anomalies
+	}
+
+	// Small windows diverge from the baseline by chance; compare with how
+	// far the baseline windows themselves usually are
+	var usual []float64
+	for i := range baseline {
+		if sumStatusCodes(baseline[i].StatusCodes) >= d.minRequests {
+			windowDivergence, _ := statusDivergence(baselineCounts, baseline[i].StatusCodes)
+			usual = append(usual, windowDivergence)
+		}
+	}
+	expected, stdDev := 0.0, 0.0
+	if len(usual) > 0 {
+		expected, stdDev = meanStdDev(usual)
+	}
+	if divergence <= expected+d.sensitivity*stdDev {
+		return anomalies
+	}
+
+	anomalies = append(anomalies, models.Anomaly{
+		Timestamp:     time.Now(),
+		Type:          models.AnomalyTypeStatusCode,
+		Severity:      calculateShareShiftSeverity(divergence, d.threshold),
+		Description:   describeStatusShift(divergence, drivers),
+		Metric:        "status_codes",
+		ActualValue:   divergence,
+		ExpectedValue: expected,
+		Deviation:     divergence - expected,
+	})
+	return anomalies
+}
+
+// statusDivergence returns the Jensen-Shannon divergence between two status
+// code distributions and the codes whose share rose, largest contribution
+// first
+func statusDivergence(baselineCounts, currentCounts map[int]int) (float64, []statusDriver) {
+	baselineTotal := float64(sumStatusCodes(baselineCounts))
+	currentTotal := float64(sumStatusCodes(currentCounts))
+
+	codes := make(map[int]bool, len(baselineCounts)+len(currentCounts))
+	for code := range baselineCounts {
+		codes[code] = true
+	}
+	for code := range currentCounts {
+		codes[code] = true
+	}
+
+	divergence := 0.0
+	var drivers []statusDriver
+	for code := range codes {
+		p := float64(baselineCounts[code]) / baselineTotal
+		q := float64(currentCounts[code]) / currentTotal
+		m := (p + q) / 2
+
+		contribution := 0.0
+		if p > 0 {
+			contribution += p * math.Log2(p/m) / 2
+		}
+		if q > 0 {
+			contribution += q * math.Log2(q/m) / 2
+		}
+		divergence += contribution
+
+		if q > p {
+			drivers = append(drivers, statusDriver{code: code, share: q, baselineShare: p, contribution: contribution})
+		}
+	}
+
+	sort.Slice(drivers, func(i, j int) bool {
+		if drivers[i].contribution != drivers[j].contribution {
+			return drivers[i].contribution > drivers[j].contribution
+		}
+		return drivers[i].code < drivers[j].code
+	})
+	return divergence, drivers
+}
+
+// describeStatusShift names the codes that drove a shift, e.g.
+// "502 new at 18%, 429 up from 1% to 12%"
+func describeStatusShift(divergence float64, drivers []statusDriver) string {
+	if len(drivers) > maxStatusDrivers {
+		drivers = drivers[:maxStatusDrivers]
+	}
+
+	parts := make([]string, len(drivers))
+	for i, driver := range drivers {
+		if driver.baselineShare == 0 {
+			parts[i] = fmt.Sprintf("%d new at %.0f%%", driver.code, driver.share*100)
+		} else {
+			parts[i] = fmt.Sprintf("%d up from %.0f%% to %.0f%%", driver.code, driver.baselineShare*100, driver.share*100)
+		}
+	}
+	return fmt.Sprintf("Status code distribution shifted (divergence %.2f): %s", divergence, strings.Join(parts, ", "))
+}
+
+func sumStatusCodes(counts map[int]int) int {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+// meanStdDev returns the mean and population standard deviation of values
+func meanStdDev(values []float64) (float64, float64) {
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
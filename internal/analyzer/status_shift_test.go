@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// statusWindows returns baseline windows of about 200 requests that are
+// mostly 200s with a few 404s and, when withThrottling is set, about 1% 429s
+func statusWindows(count int, withThrottling bool) []models.Metrics {
+	windows := make([]models.Metrics, count)
+	start := time.Now().Add(-time.Hour)
+	for i := range windows {
+		codes := map[int]int{200: 188 + i%5, 404: 8 + i%3}
+		if withThrottling {
+			codes[429] = 2
+		}
+		windows[i] = models.Metrics{Timestamp: start.Add(time.Duration(i) * time.Second), StatusCodes: codes}
+	}
+	return windows
+}
+
+// TestStatusCodeShiftDetector_NewCode tests that 502s appearing are reported
+// as the cause of the shift
+func TestStatusCodeShiftDetector_NewCode(t *testing.T) {
+	detector := NewStatusCodeShiftDetector(0.02, 3.0, 20)
+	current := &models.Metrics{Timestamp: time.Now(), StatusCodes: map[int]int{200: 155, 404: 9, 502: 36}}
+
+	anomalies := detector.Detect(current, statusWindows(20, false))
+	if len(anomalies) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %d", len(anomalies))
+	}
+	anomaly := anomalies[0]
+	if anomaly.Type != models.AnomalyTypeStatusCode || anomaly.Metric != "status_codes" {
+		t.Errorf("Unexpected anomaly %+v", anomaly)
+	}
+	if !strings.Contains(anomaly.Description, ": 502 new at 18%") {
+		t.Errorf("Expected 502 named as the driver, got %q", anomaly.Description)
+	}
+	if anomaly.ActualValue <= anomaly.ExpectedValue || anomaly.ActualValue > 1 {
+		t.Errorf("Expected divergence above the baseline's, got %v and %v", anomaly.ActualValue, anomaly.ExpectedValue)
+	}
+}
+
+// TestStatusCodeShiftDetector_SurgingCode tests a code that is normally rare
+// rising sharply
+func TestStatusCodeShiftDetector_SurgingCode(t *testing.T) {
+	detector := NewStatusCodeShiftDetector(0.02, 3.0, 20)
+	current := &models.Metrics{Timestamp: time.Now(), StatusCodes: map[int]int{200: 168, 404: 8, 429: 24}}
+
+	anomalies := detector.Detect(current, statusWindows(20, true))
+	if len(anomalies) != 1 {
+		t.Fatalf("Expected 1 anomaly, got %d", len(anomalies))
+	}
+	if !strings.Contains(anomalies[0].Description, ": 429 up from 1% to 12%") {
+		t.Errorf("Expected 429 named as the driver, got %q", anomalies[0].Description)
+	}
+}
+
+// TestStatusCodeShiftDetector_NoShift tests usual variation, small windows
+// and a short baseline
+func TestStatusCodeShiftDetector_NoShift(t *testing.T) {
+	detector := NewStatusCodeShiftDetector(0.02, 3.0, 20)
+	baseline := statusWindows(20, false)
+
+	usual := &models.Metrics{Timestamp: time.Now(), StatusCodes: map[int]int{200: 190, 404: 10}}
+	if anomalies := detector.Detect(usual, baseline); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies for usual traffic, got %+v", anomalies)
+	}
+
+	small := &models.Metrics{Timestamp: time.Now(), StatusCodes: map[int]int{502: 5}}
+	if anomalies := detector.Detect(small, baseline); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies below min requests, got %+v", anomalies)
+	}
+
+	shifted := &models.Metrics{Timestamp: time.Now(), StatusCodes: map[int]int{502: 100}}
+	if anomalies := detector.Detect(shifted, baseline[:5]); len(anomalies) != 0 {
+		t.Errorf("Expected no anomalies without enough baseline, got %+v", anomalies)
+	}
+}
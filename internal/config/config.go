@@ -10,8 +10,23 @@ import (
 type Config struct {
 	LogPath         string           `yaml:"log_path"`
 	LogFormat       string           `yaml:"log_format"`
+	LogFormatOptions yaml.Node       `yaml:"log_format_options"` // Options for the selected format, decoded by its factory
+	WatchConfig     WatchConfig      `yaml:"watch"`
+	ForwardConfig   ForwardConfig    `yaml:"forward"`
+	FilterConfig    FilterConfig     `yaml:"filter"`
+	NormalizeConfig NormalizeConfig  `yaml:"normalize"`
+	RedactConfig    RedactConfig     `yaml:"redact"`
+	GeoIPConfig     GeoIPConfig      `yaml:"geoip"`
+	UserAgentConfig UserAgentConfig  `yaml:"user_agent"`
 	DetectorConfig  DetectorConfig   `yaml:"detector"`
 	DashboardConfig DashboardConfig  `yaml:"dashboard"`
+	Sinks           []SinkConfig     `yaml:"sinks"`
+}
+
+// SinkConfig selects a registered output sink by type
+type SinkConfig struct {
+	Type    string    `yaml:"type"`
+	Options yaml.Node `yaml:"options"` // Decoded by the sink's factory
 }
 
 // DetectorConfig contains anomaly detection settings
@@ -20,10 +35,172 @@ type DetectorConfig struct {
 	SensitivityLevel   float64 `yaml:"sensitivity_level"`
 	BaselineMinutes    int     `yaml:"baseline_minutes"`
 	ErrorRateThreshold float64 `yaml:"error_rate_threshold"`
-	Algorithm          string  `yaml:"algorithm"` // "moving_average", "cusum", or "stddev"
+	Algorithm          string  `yaml:"algorithm"` // "stddev", "moving_average", "cusum", "mad", "holt_winters", "shesd", "bocpd" or "ensemble"
 	SmoothingFactor    float64 `yaml:"smoothing_factor"` // Alpha parameter for moving average (0-1)
-	CUSUMSlack         float64 `yaml:"cusum_slack"` // k parameter: slack/allowable deviation for CUSUM
-	CUSUMThreshold     float64 `yaml:"cusum_threshold"` // h parameter: decision threshold for CUSUM
+	CUSUMSlack         float64 `yaml:"cusum_slack"` // k parameter: slack/allowable deviation for CUSUM, in standard deviations
+	CUSUMThreshold     float64 `yaml:"cusum_threshold"` // h parameter: decision threshold for CUSUM, in standard deviations
+	CUSUMMetrics       map[string]CUSUMMetricConfig `yaml:"cusum_metrics"` // Per-metric slack/threshold overrides, keyed by metric name
+	CUSUMReestimateInterval int `yaml:"cusum_reestimate_interval"` // Evaluations between re-estimating CUSUM references (0 = never)
+	StatusCodes        StatusCodeConfig `yaml:"status_codes"` // Status code distribution shift detection
+	Patterns           PatternConfig `yaml:"patterns"` // Log template mining and new/spiking pattern detection
+	Dimensions         []DimensionConfig `yaml:"dimensions"` // Per-key detection, e.g. per path
+	RelatedLogs        RelatedLogsConfig `yaml:"related_logs"` // Sampled entries attached to anomalies as evidence
+	Contributors       ContributorsConfig `yaml:"contributors"` // Root-cause hints: dimension values behind each anomaly
+	Incidents          IncidentConfig `yaml:"incidents"` // Group consecutive anomalies into incidents
+	Metrics            []string `yaml:"metrics"` // Series checked by mad, holt_winters, shesd and bocpd, e.g. response_time_p99 (empty = defaults)
+	AlgorithmOptions   yaml.Node `yaml:"algorithm_options"` // Options for the selected algorithm, decoded by its factory
+}
+
+// StatusCodeConfig contains status code distribution shift settings
+type StatusCodeConfig struct {
+	Enabled             bool    `yaml:"enabled"`
+	DivergenceThreshold float64 `yaml:"divergence_threshold"` // Jensen-Shannon divergence from the baseline (0-1) that counts as a shift
+	MinRequests         int     `yaml:"min_requests"`         // Minimum requests with a status code in a window before shifts are evaluated
+}
+
+// PatternConfig contains log template mining settings. Messages are grouped
+// into templates such as "user <*> failed to log in", and each template is
+// counted per window.
+type PatternConfig struct {
+	Enabled             bool     `yaml:"enabled"`
+	Levels              []string `yaml:"levels"`               // Levels whose messages are mined (empty = all)
+	SimilarityThreshold float64  `yaml:"similarity_threshold"` // Share of matching tokens for a message to join a template (default 0.4)
+	Depth               int      `yaml:"depth"`                // Parse tree depth (default 4)
+	MaxTemplates        int      `yaml:"max_templates"`        // Templates kept; the least recently seen is dropped for a new one (default 1000)
+	MinCount            int      `yaml:"min_count"`            // Minimum occurrences in a window for a frequency spike (default 5)
+	Examples            int      `yaml:"examples"`             // Example messages attached to pattern anomalies (default 3)
+}
+
+// RelatedLogsConfig contains settings for the entries attached to anomalies.
+// Each window keeps a sample of its entries, biased toward errors and slow
+// requests, and each anomaly gets the samples most relevant to it.
+type RelatedLogsConfig struct {
+	Enabled         bool `yaml:"enabled"`
+	MaxEntries      int  `yaml:"max_entries"`       // Entries attached to each anomaly (default 5)
+	SampleSize      int  `yaml:"sample_size"`       // Entries sampled per window (default 50)
+	MaxMessageBytes int  `yaml:"max_message_bytes"` // Longer messages, paths, user agents and extra values are truncated (default 1024)
+}
+
+// ContributorsConfig contains contributor analysis settings. Each anomaly
+// lists the paths, statuses, IPs, user agents and sources that account for
+// most of its excess over the baseline.
+type ContributorsConfig struct {
+	Enabled         bool    `yaml:"enabled"`
+	MaxContributors int     `yaml:"max_contributors"` // Contributors listed per anomaly (default 5)
+	MinShare        float64 `yaml:"min_share"`        // Minimum share of a dimension's excess to be listed (default 0.1)
+}
+
+// IncidentConfig contains incident lifecycle settings. When enabled, the
+// detector emits incident transitions (open, ongoing, resolved) instead of
+// an anomaly for every detection.
+type IncidentConfig struct {
+	Enabled            bool `yaml:"enabled"`
+	QuietPeriodSeconds int  `yaml:"quiet_period_seconds"` // Time without anomalies before an incident is resolved (default 60)
+}
+
+// DimensionConfig enables detection on each key of a dimension, such as every
+// normalized path, with its own series and algorithm instance
+type DimensionConfig struct {
+	Name        string `yaml:"name"`         // "path", "source", "method", "host" or "status_class"
+	MaxKeys     int    `yaml:"max_keys"`     // Keys tracked at once; entries with further keys are not tracked (default 100)
+	MinRequests int    `yaml:"min_requests"` // Requests a key's window collects before it is evaluated (default 10)
+}
+
+// CUSUMMetricConfig overrides the CUSUM slack and threshold for one metric
+// ("error_rate", "requests_per_sec" or "avg_response_time"). Zero keeps the
+// detector-wide value.
+type CUSUMMetricConfig struct {
+	Slack     float64 `yaml:"slack"`
+	Threshold float64 `yaml:"threshold"`
+}
+
+// WatchConfig contains directory watch settings. When Dir is set, every
+// matching file in it is tailed instead of the single LogPath.
+type WatchConfig struct {
+	Dir                string   `yaml:"dir"`
+	Include            []string `yaml:"include"` // Glob patterns matched against file names (empty = all)
+	Exclude            []string `yaml:"exclude"`
+	IdleTimeoutSeconds int      `yaml:"idle_timeout_seconds"` // Release files that stop receiving writes
+	MaxOpenFiles       int      `yaml:"max_open_files"`
+}
+
+// ForwardConfig contains Fluent Forward protocol listener settings
+type ForwardConfig struct {
+	Enabled         bool   `yaml:"enabled"`
+	Host            string `yaml:"host"`
+	Port            int    `yaml:"port"`
+	MaxMessageBytes int64  `yaml:"max_message_bytes"` // Limit on one message, including a decompressed PackedForward payload
+}
+
+// FilterConfig contains the filter and sampling stage settings applied to
+// parsed entries before they reach the detector
+type FilterConfig struct {
+	Include    []FilterRule `yaml:"include"`     // If set, entries must match at least one rule
+	Exclude    []FilterRule `yaml:"exclude"`     // Entries matching any rule are dropped
+	SampleRate float64      `yaml:"sample_rate"` // Fraction of entries kept (0 or 1 = keep all)
+	SampleBy   string       `yaml:"sample_by"`   // Field hashed for sampling; empty hashes the whole entry
+}
+
+// FilterRule matches a LogEntry field, e.g. path, user_agent, status_code,
+// level or extra.<key>. With no condition set the rule matches when the
+// field is present.
+type FilterRule struct {
+	Name   string   `yaml:"name"`
+	Field  string   `yaml:"field"`
+	Prefix string   `yaml:"prefix"`
+	Regex  string   `yaml:"regex"`
+	Values []string `yaml:"values"` // Exact matches; status_code also accepts classes like "5xx"
+}
+
+// NormalizeConfig contains path normalization settings. Normalized paths
+// replace LogEntry.Path so metrics aggregate per route instead of per URL.
+type NormalizeConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	StripQuery  bool     `yaml:"strip_query"`  // Drop query strings and fragments
+	CollapseIDs bool     `yaml:"collapse_ids"` // Replace numeric IDs, UUIDs and hashes with placeholders
+	Routes      []string `yaml:"routes"`       // Route templates such as "/users/:id/orders/:order_id"
+}
+
+// RedactConfig contains PII redaction settings, applied to entries before
+// they reach the detector, the dashboard or any other output
+type RedactConfig struct {
+	Enabled   bool            `yaml:"enabled"`
+	Detectors []string        `yaml:"detectors"` // Built-ins: email, credit_card, bearer_token, jwt, url_secret, ipv4, ipv6
+	Custom    []RedactPattern `yaml:"custom"`
+	Mode      string          `yaml:"mode"`     // "mask", "hash" or "drop"
+	Fields    []string        `yaml:"fields"`   // Fields to scan; "extra" covers every Extra value
+	HashKey   string          `yaml:"hash_key"` // HMAC key, required for hash mode
+}
+
+// RedactPattern is a user-defined redaction regex
+type RedactPattern struct {
+	Name  string `yaml:"name"`
+	Regex string `yaml:"regex"`
+	Mode  string `yaml:"mode"` // Overrides RedactConfig.Mode when set
+}
+
+// GeoIPConfig contains offline GeoIP/ASN enrichment settings
+type GeoIPConfig struct {
+	Enabled        bool    `yaml:"enabled"`
+	CityDB         string  `yaml:"city_db"`         // Path to a GeoLite2/GeoIP2 City or Country MMDB file
+	ASNDB          string  `yaml:"asn_db"`          // Path to a GeoLite2/GeoIP2 ASN MMDB file
+	ShiftThreshold float64 `yaml:"shift_threshold"` // Share increase over baseline that counts as a traffic shift
+	MinRequests    int     `yaml:"min_requests"`    // Minimum located requests in a window before shifts are evaluated
+}
+
+// UserAgentConfig contains user-agent parsing and bot classification settings
+type UserAgentConfig struct {
+	Enabled        bool                 `yaml:"enabled"`
+	Custom         []UserAgentSignature `yaml:"custom"`
+	SurgeThreshold float64              `yaml:"surge_threshold"` // Increase in bot share over baseline that counts as a surge
+	MinRequests    int                  `yaml:"min_requests"`    // Minimum classified requests in a window before surges are evaluated
+}
+
+// UserAgentSignature classifies user agents containing Match (case-insensitive)
+type UserAgentSignature struct {
+	Name  string `yaml:"name"`
+	Match string `yaml:"match"`
+	Class string `yaml:"class"` // "crawler", "tool", "headless" or "scanner"
 }
 
 // DashboardConfig contains web dashboard settings
@@ -33,6 +210,14 @@ type DashboardConfig struct {
 	EnableTUI      bool   `yaml:"enable_tui"`
 	RefreshRate    int    `yaml:"refresh_rate_ms"`
 	MaxLogLines    int    `yaml:"max_log_lines"`
+	Ingest         IngestConfig `yaml:"ingest"`
+}
+
+// IngestConfig contains HTTP log ingestion settings
+type IngestConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	Token        string `yaml:"token"`          // Shared secret expected as a Bearer token
+	MaxBodyBytes int64  `yaml:"max_body_bytes"` // Limit on the (decompressed) request body size
 }
 
 // LoadConfig loads configuration from a YAML file
@@ -43,12 +228,13 @@ func LoadConfig(path string) (*Config, error) {
 		return DefaultConfig(), nil
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	// Start from the defaults so omitted sections keep their default values
+	cfg := DefaultConfig()
+	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, err
 	}
 
-	return &cfg, nil
+	return cfg, nil
 }
 
 // DefaultConfig returns a default configuration
@@ -56,6 +242,40 @@ func DefaultConfig() *Config {
 	return &Config{
 		LogPath:   "/var/log/app.log",
 		LogFormat: "json",
+		WatchConfig: WatchConfig{
+			IdleTimeoutSeconds: 300,
+			MaxOpenFiles:       64,
+		},
+		ForwardConfig: ForwardConfig{
+			Enabled:         false,
+			Host:            "localhost",
+			Port:            24224,
+			MaxMessageBytes: 16 << 20, // 16 MiB
+		},
+		FilterConfig: FilterConfig{
+			SampleRate: 1.0,
+		},
+		NormalizeConfig: NormalizeConfig{
+			Enabled:     true,
+			StripQuery:  true,
+			CollapseIDs: true,
+		},
+		RedactConfig: RedactConfig{
+			Enabled:   false,
+			Detectors: []string{"email", "credit_card", "bearer_token", "jwt", "url_secret", "ipv4", "ipv6"},
+			Mode:      "mask",
+			Fields:    []string{"message", "path", "raw_path", "user_agent", "ip_address", "extra"},
+		},
+		GeoIPConfig: GeoIPConfig{
+			Enabled:        false,
+			ShiftThreshold: 0.2,
+			MinRequests:    20,
+		},
+		UserAgentConfig: UserAgentConfig{
+			Enabled:        true,
+			SurgeThreshold: 0.15,
+			MinRequests:    20,
+		},
 		DetectorConfig: DetectorConfig{
 			WindowSize:         100,
 			SensitivityLevel:   2.0,
@@ -65,6 +285,33 @@ func DefaultConfig() *Config {
 			SmoothingFactor:    0.3,
 			CUSUMSlack:         0.5,  // Default slack parameter
 			CUSUMThreshold:     5.0,  // Default decision threshold
+			CUSUMReestimateInterval: 60,
+			StatusCodes: StatusCodeConfig{
+				Enabled:             true,
+				DivergenceThreshold: 0.02,
+				MinRequests:         20,
+			},
+			Patterns: PatternConfig{
+				Enabled:  true,
+				Levels:   []string{"error", "warn"},
+				MinCount: 5,
+				Examples: 3,
+			},
+			RelatedLogs: RelatedLogsConfig{
+				Enabled:         true,
+				MaxEntries:      5,
+				SampleSize:      50,
+				MaxMessageBytes: 1024,
+			},
+			Contributors: ContributorsConfig{
+				Enabled:         true,
+				MaxContributors: 5,
+				MinShare:        0.1,
+			},
+			Incidents: IncidentConfig{
+				Enabled:            true,
+				QuietPeriodSeconds: 60,
+			},
 		},
 		DashboardConfig: DashboardConfig{
 			Port:           8080,
@@ -72,6 +319,10 @@ func DefaultConfig() *Config {
 			EnableTUI:      false,
 			RefreshRate:    1000,
 			MaxLogLines:    500,
+			Ingest: IngestConfig{
+				Enabled:      false,
+				MaxBodyBytes: 10 << 20, // 10 MiB
+			},
 		},
 	}
 }
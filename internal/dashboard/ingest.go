@@ -0,0 +1,267 @@
+package dashboard
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/parser"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+const (
+	// defaultIngestMaxBodyBytes applies when the config leaves max_body_bytes unset
+	defaultIngestMaxBodyBytes = 10 << 20
+
+	// maxIngestLineBytes bounds a single raw log line
+	maxIngestLineBytes = 1 << 20
+
+	// ingestSource is recorded on entries that arrive without a source
+	ingestSource = "http"
+)
+
+// errPipelineFull signals that the detector input channel has no free capacity
+var errPipelineFull = errors.New("pipeline is full")
+
+// ingestResponse summarizes the outcome of an ingest request.
+// Processed counts the records consumed from the body, in order, so a client
+// that receives a 429 can resend everything after that position.
+type ingestResponse struct {
+	Accepted  int    `json:"accepted"`
+	Invalid   int    `json:"invalid"`
+	Processed int    `json:"processed"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ingestCounters tracks ingest throughput for pipeline stats. Fields are
+// accessed atomically.
+type ingestCounters struct {
+	accepted  uint64
+	invalid   uint64
+	bytes     uint64
+	throttled uint64
+}
+
+// countingReader counts bytes read from a request body
+type countingReader struct {
+	reader io.ReadCloser
+	count  *uint64
+}
+
+func (cr countingReader) Read(p []byte) (int, error) {
+	n, err := cr.reader.Read(p)
+	atomic.AddUint64(cr.count, uint64(n))
+	return n, err
+}
+
+func (cr countingReader) Close() error {
+	return cr.reader.Close()
+}
+
+// EnableIngest connects the /api/ingest endpoint to the detector pipeline.
+// Raw lines are parsed with logParser and every entry is sent on output.
+// The endpoint is only served when ingestion is enabled in the config.
+func (s *Server) EnableIngest(logParser parser.LogParser, output chan<- models.Message) {
+	s.ingestParser = logParser
+	s.ingestOutput = output
+}
+
+// handleIngest accepts log records over HTTP. The body is either
+// newline-delimited raw lines (parsed with the configured parser) or, with a
+// JSON content type, an array of LogEntry objects. Bodies may be gzipped.
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeIngestResponse(w, http.StatusMethodNotAllowed, ingestResponse{Error: "method not allowed"})
+		return
+	}
+
+	if !s.authorizeIngest(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="logflow"`)
+		writeIngestResponse(w, http.StatusUnauthorized, ingestResponse{Error: "invalid or missing token"})
+		return
+	}
+
+	maxBodyBytes := s.config.Ingest.MaxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = defaultIngestMaxBodyBytes
+	}
+
+	var body io.ReadCloser = r.Body
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			writeIngestResponse(w, http.StatusBadRequest, ingestResponse{Error: "invalid gzip body"})
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+	// Limit the decompressed size so a small gzip body cannot expand unbounded
+	body = http.MaxBytesReader(w, body, maxBodyBytes)
+	body = countingReader{reader: body, count: &s.ingestStats.bytes}
+
+	var resp ingestResponse
+	var err error
+	if isJSONContentType(r.Header.Get("Content-Type")) {
+		err = s.ingestJSON(body, &resp)
+	} else {
+		err = s.ingestLines(body, &resp)
+	}
+	atomic.AddUint64(&s.ingestStats.accepted, uint64(resp.Accepted))
+	atomic.AddUint64(&s.ingestStats.invalid, uint64(resp.Invalid))
+
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case err == nil:
+		writeIngestResponse(w, http.StatusOK, resp)
+	case errors.Is(err, errPipelineFull):
+		atomic.AddUint64(&s.ingestStats.throttled, 1)
+		resp.Error = err.Error()
+		w.Header().Set("Retry-After", "1")
+		writeIngestResponse(w, http.StatusTooManyRequests, resp)
+	case errors.As(err, &maxBytesErr):
+		resp.Error = "request body too large"
+		writeIngestResponse(w, http.StatusRequestEntityTooLarge, resp)
+	default:
+		resp.Error = err.Error()
+		writeIngestResponse(w, http.StatusBadRequest, resp)
+	}
+}
+
+// ReportStats adds ingest throughput to a pipeline stats snapshot under the
+// "http" source. Requests rejected because the pipeline was full are counted
+// as dropped.
+func (s *Server) ReportStats(stats *models.PipelineStats) {
+	if !s.config.Ingest.Enabled || s.ingestOutput == nil {
+		return
+	}
+
+	accepted := atomic.LoadUint64(&s.ingestStats.accepted)
+	invalid := atomic.LoadUint64(&s.ingestStats.invalid)
+	stats.AddSource(ingestSource, models.SourceStats{
+		Lines:       accepted + invalid,
+		Bytes:       atomic.LoadUint64(&s.ingestStats.bytes),
+		ParseErrors: invalid,
+	})
+	if throttled := atomic.LoadUint64(&s.ingestStats.throttled); throttled > 0 {
+		stats.Dropped["ingest_throttled"] += throttled
+	}
+}
+
+// authorizeIngest checks the request token against the configured one
+func (s *Server) authorizeIngest(r *http.Request) bool {
+	expected := s.config.Ingest.Token
+	if expected == "" {
+		return true
+	}
+
+	provided := r.Header.Get("X-LogFlow-Token")
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		const prefix = "Bearer "
+		if len(auth) > len(prefix) && strings.EqualFold(auth[:len(prefix)], prefix) {
+			provided = auth[len(prefix):]
+		}
+	}
+
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) == 1
+}
+
+// ingestLines parses newline-delimited raw log lines
+func (s *Server) ingestLines(body io.Reader, resp *ingestResponse) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxIngestLineBytes)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		entry, err := s.ingestParser.Parse(line)
+		if err != nil {
+			resp.Invalid++
+			resp.Processed++
+			continue
+		}
+
+		if err := s.forwardEntry(entry); err != nil {
+			return err
+		}
+		resp.Accepted++
+		resp.Processed++
+	}
+
+	return scanner.Err()
+}
+
+// ingestJSON decodes a JSON array of log entries, streaming element by element
+func (s *Server) ingestJSON(body io.Reader, resp *ingestResponse) error {
+	decoder := json.NewDecoder(body)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return errors.New("expected a JSON array of log entries")
+	}
+
+	for decoder.More() {
+		var entry models.LogEntry
+		if err := decoder.Decode(&entry); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				// The element was consumed; skip it and keep going
+				resp.Invalid++
+				resp.Processed++
+				continue
+			}
+			return err
+		}
+
+		if err := s.forwardEntry(&entry); err != nil {
+			return err
+		}
+		resp.Accepted++
+		resp.Processed++
+	}
+
+	_, err = decoder.Token()
+	return err
+}
+
+// forwardEntry hands an entry to the pipeline without blocking the request
+func (s *Server) forwardEntry(entry *models.LogEntry) error {
+	if entry.Source == "" {
+		entry.Source = ingestSource
+	}
+
+	select {
+	case s.ingestOutput <- models.NewLogMessage(entry):
+		return nil
+	default:
+		return errPipelineFull
+	}
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json"
+}
+
+func writeIngestResponse(w http.ResponseWriter, status int, resp ingestResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
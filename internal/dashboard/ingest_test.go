@@ -0,0 +1,178 @@
+package dashboard
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/internal/parser"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// newIngestTestServer creates a server with ingestion enabled
+func newIngestTestServer(token string, bufferSize int) (*Server, chan models.Message) {
+	cfg := config.DefaultConfig().DashboardConfig
+	cfg.Ingest.Enabled = true
+	cfg.Ingest.Token = token
+
+	output := make(chan models.Message, bufferSize)
+	server := NewServer(cfg)
+	server.EnableIngest(&parser.JSONParser{}, output)
+	return server, output
+}
+
+func doIngest(server *Server, body []byte, headers map[string]string) (*httptest.ResponseRecorder, ingestResponse) {
+	req := httptest.NewRequest(http.MethodPost, "/api/ingest", bytes.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	server.handleIngest(rec, req)
+
+	var resp ingestResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	return rec, resp
+}
+
+// TestIngest_RawLines tests newline-delimited raw lines through the parser
+func TestIngest_RawLines(t *testing.T) {
+	server, output := newIngestTestServer("", 10)
+
+	body := `{"level":"info","message":"ok","status_code":200}
+not json
+{"level":"error","message":"boom","status_code":500,"source":"lambda"}
+`
+	rec, resp := doIngest(server, []byte(body), nil)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if resp.Accepted != 2 || resp.Invalid != 1 || resp.Processed != 3 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if len(output) != 2 {
+		t.Fatalf("Expected 2 entries forwarded, got %d", len(output))
+	}
+
+	first, _ := (<-output).LogEntry()
+	if first.Source != ingestSource {
+		t.Errorf("Expected default source %q, got %q", ingestSource, first.Source)
+	}
+	second, _ := (<-output).LogEntry()
+	if second.Source != "lambda" {
+		t.Errorf("Expected source to be preserved, got %q", second.Source)
+	}
+}
+
+// TestIngest_JSONArrayGzip tests a gzipped JSON array body
+func TestIngest_JSONArrayGzip(t *testing.T) {
+	server, output := newIngestTestServer("", 10)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`[{"message":"a","status_code":200},{"message":"b","status_code":"bad"},{"message":"c","status_code":503}]`))
+	gz.Close()
+
+	rec, resp := doIngest(server, buf.Bytes(), map[string]string{
+		"Content-Type":     "application/json; charset=utf-8",
+		"Content-Encoding": "gzip",
+	})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d (%s)", rec.Code, resp.Error)
+	}
+	if resp.Accepted != 2 || resp.Invalid != 1 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if len(output) != 2 {
+		t.Errorf("Expected 2 entries forwarded, got %d", len(output))
+	}
+}
+
+// TestIngest_Authentication tests token checks
+func TestIngest_Authentication(t *testing.T) {
+	server, _ := newIngestTestServer("s3cret", 10)
+	body := []byte(`{"message":"ok"}`)
+
+	rec, _ := doIngest(server, body, nil)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", rec.Code)
+	}
+
+	rec, _ = doIngest(server, body, map[string]string{"Authorization": "Bearer wrong"})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with wrong token, got %d", rec.Code)
+	}
+
+	rec, _ = doIngest(server, body, map[string]string{"Authorization": "Bearer s3cret"})
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 with bearer token, got %d", rec.Code)
+	}
+
+	rec, _ = doIngest(server, body, map[string]string{"X-LogFlow-Token": "s3cret"})
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 with token header, got %d", rec.Code)
+	}
+}
+
+// TestIngest_Backpressure tests that a full pipeline returns 429
+func TestIngest_Backpressure(t *testing.T) {
+	server, output := newIngestTestServer("", 2)
+
+	body := strings.Repeat(`{"message":"x"}`+"\n", 5)
+	rec, resp := doIngest(server, []byte(body), nil)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+	if resp.Accepted != 2 || resp.Processed != 2 {
+		t.Errorf("Expected 2 accepted before backpressure, got %+v", resp)
+	}
+	if len(output) != 2 {
+		t.Errorf("Expected 2 entries forwarded, got %d", len(output))
+	}
+}
+
+// TestIngest_ReportStats tests ingest counters in pipeline stats
+func TestIngest_ReportStats(t *testing.T) {
+	server, _ := newIngestTestServer("", 2)
+
+	body := "{\"message\":\"ok\"}\nnot json\n"
+	doIngest(server, []byte(body), nil)
+	doIngest(server, []byte(strings.Repeat(`{"message":"x"}`+"\n", 3)), nil)
+
+	stats := models.NewPipelineStats()
+	server.ReportStats(stats)
+
+	source := stats.Sources[ingestSource]
+	if source.Lines != 3 || source.ParseErrors != 1 {
+		t.Errorf("Expected 3 lines with 1 parse error, got %+v", source)
+	}
+	if source.Bytes == 0 {
+		t.Error("Expected ingested bytes to be counted")
+	}
+	if stats.Dropped["ingest_throttled"] != 1 {
+		t.Errorf("Expected 1 throttled request, got %v", stats.Dropped)
+	}
+}
+
+// TestIngest_BodyTooLarge tests the body size limit
+func TestIngest_BodyTooLarge(t *testing.T) {
+	server, _ := newIngestTestServer("", 1000)
+	server.config.Ingest.MaxBodyBytes = 64
+
+	body := strings.Repeat(`{"message":"x"}`+"\n", 20)
+	rec, _ := doIngest(server, []byte(body), nil)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rec.Code)
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/gorilla/websocket"
 	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/internal/parser"
 	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
 )
 
@@ -24,7 +25,14 @@ type Server struct {
 	upgrader  websocket.Upgrader
 	clients   map[*websocket.Conn]bool
 	clientsMu sync.RWMutex
-	broadcast chan interface{}
+	broadcast chan models.Message
+
+	// HTTP ingestion (see ingest.go)
+	ingestParser parser.LogParser
+	ingestOutput chan<- models.Message
+	ingestStats  ingestCounters
+
+	statsProvider func() models.PipelineStats
 }
 
 // NewServer creates a new dashboard server
@@ -37,12 +45,12 @@ func NewServer(cfg config.DashboardConfig) *Server {
 			},
 		},
 		clients:   make(map[*websocket.Conn]bool),
-		broadcast: make(chan interface{}, 100),
+		broadcast: make(chan models.Message, 100),
 	}
 }
 
 // Start starts the dashboard server
-func (s *Server) Start(ctx context.Context, input <-chan interface{}) {
+func (s *Server) Start(ctx context.Context, input <-chan models.Message) {
 	// Start WebSocket broadcaster
 	go s.broadcastLoop(ctx)
 
@@ -53,6 +61,15 @@ func (s *Server) Start(ctx context.Context, input <-chan interface{}) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/ws", s.handleWebSocket)
 	mux.HandleFunc("/api/metrics", s.handleMetrics)
+	if s.statsProvider != nil {
+		mux.HandleFunc("/api/stats", s.handleStats)
+	}
+	if s.config.Ingest.Enabled && s.ingestOutput != nil {
+		mux.HandleFunc("/api/ingest", s.handleIngest)
+		if s.config.Ingest.Token == "" {
+			log.Printf("Warning: /api/ingest is enabled without a token")
+		}
+	}
 	mux.HandleFunc("/", s.handleIndex)
 
 	// Add pprof endpoints for profiling
@@ -85,7 +102,7 @@ func (s *Server) Start(ctx context.Context, input <-chan interface{}) {
 	server.Shutdown(context.Background())
 }
 
-func (s *Server) handleInput(ctx context.Context, input <-chan interface{}) {
+func (s *Server) handleInput(ctx context.Context, input <-chan models.Message) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -155,166 +172,24 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
-	html := `<!DOCTYPE html>
-<html>
-<head>
-    <title>LogFlow Anomaly Detector</title>
-    <style>
-        body {
-            font-family: Arial, sans-serif;
-            margin: 0;
-            padding: 20px;
-            background: #1a1a1a;
-            color: #fff;
-        }
-        .container {
-            max-width: 1400px;
-            margin: 0 auto;
-        }
-        h1 {
-            color: #4CAF50;
-        }
-        .metrics-grid {
-            display: grid;
-            grid-template-columns: repeat(auto-fit, minmax(250px, 1fr));
-            gap: 20px;
-            margin: 20px 0;
-        }
-        .metric-card {
-            background: #2a2a2a;
-            padding: 20px;
-            border-radius: 8px;
-            border-left: 4px solid #4CAF50;
-        }
-        .metric-value {
-            font-size: 2em;
-            font-weight: bold;
-            color: #4CAF50;
-        }
-        .metric-label {
-            color: #999;
-            font-size: 0.9em;
-        }
-        .log-stream {
-            background: #2a2a2a;
-            padding: 20px;
-            border-radius: 8px;
-            max-height: 400px;
-            overflow-y: auto;
-            font-family: monospace;
-            font-size: 0.9em;
-        }
-        .anomaly {
-            background: #ff5722;
-            padding: 15px;
-            margin: 10px 0;
-            border-radius: 8px;
-            border-left: 4px solid #d32f2f;
-        }
-        .anomaly-high { background: #ff5722; }
-        .anomaly-critical { background: #d32f2f; }
-        .anomaly-medium { background: #ff9800; }
-        .anomaly-low { background: #ffc107; }
-        .status {
-            color: #4CAF50;
-            font-size: 0.9em;
-        }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <h1>🔍 LogFlow Anomaly Detector</h1>
-        <div class="status" id="status">Connecting to server...</div>
-
-        <div class="metrics-grid" id="metrics">
-            <div class="metric-card">
-                <div class="metric-label">Requests/sec</div>
-                <div class="metric-value" id="requests-per-sec">0</div>
-            </div>
-            <div class="metric-card">
-                <div class="metric-label">Error Rate</div>
-                <div class="metric-value" id="error-rate">0%</div>
-            </div>
-            <div class="metric-card">
-                <div class="metric-label">Avg Response Time</div>
-                <div class="metric-value" id="response-time">0ms</div>
-            </div>
-            <div class="metric-card">
-                <div class="metric-label">Total Requests</div>
-                <div class="metric-value" id="total-requests">0</div>
-            </div>
-        </div>
-
-        <h2>🚨 Recent Anomalies</h2>
-        <div id="anomalies"></div>
-
-        <h2>📋 Log Stream</h2>
-        <div class="log-stream" id="log-stream"></div>
-    </div>
-
-    <script>
-        const ws = new WebSocket('ws://' + window.location.host + '/ws');
-        const statusEl = document.getElementById('status');
-        const anomaliesEl = document.getElementById('anomalies');
-        const logStreamEl = document.getElementById('log-stream');
-        let totalRequests = 0;
-
-        ws.onopen = () => {
-            statusEl.textContent = '✓ Connected';
-        };
-
-        ws.onclose = () => {
-            statusEl.textContent = '✗ Disconnected';
-        };
-
-        ws.onmessage = (event) => {
-            const data = JSON.parse(event.data);
-
-            if (data.requests_per_sec !== undefined) {
-                // Metrics update
-                document.getElementById('requests-per-sec').textContent =
-                    data.requests_per_sec.toFixed(2);
-                document.getElementById('error-rate').textContent =
-                    (data.error_rate * 100).toFixed(2) + '%';
-                document.getElementById('response-time').textContent =
-                    data.avg_response_time.toFixed(2) + 'ms';
-                totalRequests += Math.round(data.requests_per_sec);
-                document.getElementById('total-requests').textContent = totalRequests;
-            } else if (data.type) {
-                // Anomaly detected
-                const anomalyDiv = document.createElement('div');
-                anomalyDiv.className = 'anomaly anomaly-' + data.severity;
-                anomalyDiv.innerHTML = \`
-                    <strong>\${data.type.toUpperCase()}</strong> -
-                    Severity: \${data.severity} |
-                    \${data.description}<br>
-                    Metric: \${data.metric} |
-                    Expected: \${data.expected_value.toFixed(2)} |
-                    Actual: \${data.actual_value.toFixed(2)}
-                \`;
-                anomaliesEl.insertBefore(anomalyDiv, anomaliesEl.firstChild);
+// SetStatsProvider serves the pipeline stats returned by provider on
+// /api/stats. It must be called before Start.
+func (s *Server) SetStatsProvider(provider func() models.PipelineStats) {
+	s.statsProvider = provider
+}
 
-                // Keep only last 10 anomalies
-                while (anomaliesEl.children.length > 10) {
-                    anomaliesEl.removeChild(anomaliesEl.lastChild);
-                }
-            } else if (data.message) {
-                // Log entry
-                const logDiv = document.createElement('div');
-                logDiv.textContent = \`[\${data.timestamp}] \${data.level}: \${data.message}\`;
-                logStreamEl.insertBefore(logDiv, logStreamEl.firstChild);
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.statsProvider())
+}
 
-                // Keep only last 100 lines
-                while (logStreamEl.children.length > 100) {
-                    logStreamEl.removeChild(logStreamEl.lastChild);
-                }
-            }
-        };
-    </script>
-</body>
-</html>`
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	html, err := staticFiles.ReadFile("static/index.html")
+	if err != nil {
+		http.Error(w, "dashboard unavailable", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "text/html")
-	w.Write([]byte(html))
+	w.Write(html)
 }
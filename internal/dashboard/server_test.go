@@ -0,0 +1,27 @@
+package dashboard
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestIndex_NoHTMLSinks tests that the dashboard never parses message data
+// as markup. Anomaly descriptions and keys can hold request paths, hosts and
+// log text, so a key such as "/<img src=x onerror=alert(1)>" must be set as
+// text.
+func TestIndex_NoHTMLSinks(t *testing.T) {
+	html, err := staticFiles.ReadFile("static/index.html")
+	if err != nil {
+		t.Fatalf("Failed to read index.html: %v", err)
+	}
+
+	page := string(html)
+	for _, sink := range []string{"innerHTML", "outerHTML", "insertAdjacentHTML", "document.write"} {
+		if strings.Contains(page, sink) {
+			t.Errorf("Expected no %s in index.html", sink)
+		}
+	}
+	if !strings.Contains(page, "function renderAnomaly") {
+		t.Error("Expected anomalies to be rendered by renderAnomaly")
+	}
+}
@@ -0,0 +1,137 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// cityRecord is the subset of a GeoIP2/GeoLite2 City or Country record we use
+type cityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// asnRecord is a GeoIP2/GeoLite2 ASN record
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// GeoIPEnricher looks up LogEntry.IPAddress in local MaxMind DB files and
+// records the country, city and ASN in the entry's Extra map. It must run
+// before any stage that redacts the IP address.
+type GeoIPEnricher struct {
+	cityDB *maxminddb.Reader
+	asnDB  *maxminddb.Reader
+}
+
+// NewGeoIPEnricher opens the configured MMDB files. Either may be omitted.
+func NewGeoIPEnricher(cfg config.GeoIPConfig) (*GeoIPEnricher, error) {
+	if cfg.CityDB == "" && cfg.ASNDB == "" {
+		return nil, fmt.Errorf("geoip enrichment needs city_db or asn_db")
+	}
+
+	g := &GeoIPEnricher{}
+	if cfg.CityDB != "" {
+		reader, err := maxminddb.Open(cfg.CityDB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open city database: %w", err)
+		}
+		g.cityDB = reader
+	}
+	if cfg.ASNDB != "" {
+		reader, err := maxminddb.Open(cfg.ASNDB)
+		if err != nil {
+			g.Close()
+			return nil, fmt.Errorf("failed to open ASN database: %w", err)
+		}
+		g.asnDB = reader
+	}
+
+	return g, nil
+}
+
+// Enrich adds geo fields to the entry's Extra map when the IP is found
+func (g *GeoIPEnricher) Enrich(entry *models.LogEntry) {
+	ip := net.ParseIP(entry.IPAddress)
+	if ip == nil {
+		return
+	}
+
+	if g.cityDB != nil {
+		var record cityRecord
+		// Lookup errors (e.g. IPv6 in an IPv4-only database) just mean no data
+		if err := g.cityDB.Lookup(ip, &record); err == nil {
+			if record.Country.ISOCode != "" {
+				setExtra(entry, models.ExtraGeoCountry, record.Country.ISOCode)
+			}
+			if city := record.City.Names["en"]; city != "" {
+				setExtra(entry, models.ExtraGeoCity, city)
+			}
+		}
+	}
+
+	if g.asnDB != nil {
+		var record asnRecord
+		if err := g.asnDB.Lookup(ip, &record); err == nil && record.Number != 0 {
+			setExtra(entry, models.ExtraGeoASN, fmt.Sprintf("AS%d", record.Number))
+			if record.Organization != "" {
+				setExtra(entry, models.ExtraGeoASOrg, record.Organization)
+			}
+		}
+	}
+}
+
+// Start enriches log entries from input into output. Other messages pass
+// through unchanged.
+func (g *GeoIPEnricher) Start(ctx context.Context, input <-chan models.Message, output chan<- models.Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-input:
+			if !ok {
+				return
+			}
+			if entry, ok := message.LogEntry(); ok {
+				g.Enrich(entry)
+			}
+
+			select {
+			case output <- message:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// Close releases the database files
+func (g *GeoIPEnricher) Close() error {
+	var firstErr error
+	for _, reader := range []*maxminddb.Reader{g.cityDB, g.asnDB} {
+		if reader == nil {
+			continue
+		}
+		if err := reader.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func setExtra(entry *models.LogEntry, key string, value interface{}) {
+	if entry.Extra == nil {
+		entry.Extra = make(map[string]interface{})
+	}
+	entry.Extra[key] = value
+}
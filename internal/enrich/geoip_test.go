@@ -0,0 +1,226 @@
+package enrich
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// testNetwork maps an IPv4 CIDR to the record stored for it
+type testNetwork struct {
+	cidr   string
+	record map[string]interface{}
+}
+
+// writeTestMMDB generates a minimal IPv4-only MaxMind DB (24-bit records)
+// containing the given non-overlapping networks
+func writeTestMMDB(t *testing.T, dbType string, networks []testNetwork) string {
+	t.Helper()
+
+	// Records: -1 is empty, >= 0 is a node index, <= -2 is data offset -(r+2)
+	nodes := [][2]int64{{-1, -1}}
+	var data bytes.Buffer
+
+	for _, network := range networks {
+		_, ipNet, err := net.ParseCIDR(network.cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prefixLen, _ := ipNet.Mask.Size()
+		ip := ipNet.IP.To4()
+
+		offset := int64(data.Len())
+		encodeMMDBValue(&data, network.record)
+
+		current := 0
+		for depth := 0; depth < prefixLen; depth++ {
+			bit := (ip[depth/8] >> (7 - depth%8)) & 1
+			if depth == prefixLen-1 {
+				nodes[current][bit] = -offset - 2
+				break
+			}
+			next := nodes[current][bit]
+			if next < 0 {
+				nodes = append(nodes, [2]int64{-1, -1})
+				next = int64(len(nodes) - 1)
+				nodes[current][bit] = next
+			}
+			current = int(next)
+		}
+	}
+
+	nodeCount := int64(len(nodes))
+	var db bytes.Buffer
+	for _, node := range nodes {
+		for _, record := range node {
+			value := record
+			switch {
+			case record == -1:
+				value = nodeCount
+			case record <= -2:
+				value = nodeCount + 16 + (-record - 2)
+			}
+			db.Write([]byte{byte(value >> 16), byte(value >> 8), byte(value)})
+		}
+	}
+	db.Write(make([]byte, 16))
+	db.Write(data.Bytes())
+	db.WriteString("\xab\xcd\xefMaxMind.com")
+	encodeMMDBValue(&db, map[string]interface{}{
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+		"ip_version":                  uint16(4),
+		"database_type":               dbType,
+		"languages":                   []interface{}{"en"},
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1760600000),
+		"description":                 map[string]interface{}{"en": "logflow test fixture"},
+	})
+
+	path := filepath.Join(t.TempDir(), dbType+".mmdb")
+	if err := os.WriteFile(path, db.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// encodeMMDBValue writes a value in the MaxMind DB data section format
+func encodeMMDBValue(buf *bytes.Buffer, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		writeMMDBControl(buf, 2, len(v))
+		buf.WriteString(v)
+	case uint16:
+		writeMMDBControl(buf, 5, 2)
+		binary.Write(buf, binary.BigEndian, v)
+	case uint32:
+		writeMMDBControl(buf, 6, 4)
+		binary.Write(buf, binary.BigEndian, v)
+	case uint64:
+		writeMMDBControl(buf, 9, 8)
+		binary.Write(buf, binary.BigEndian, v)
+	case map[string]interface{}:
+		writeMMDBControl(buf, 7, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			encodeMMDBValue(buf, key)
+			encodeMMDBValue(buf, v[key])
+		}
+	case []interface{}:
+		writeMMDBControl(buf, 11, len(v))
+		for _, item := range v {
+			encodeMMDBValue(buf, item)
+		}
+	default:
+		panic("unsupported MMDB test value")
+	}
+}
+
+// writeMMDBControl writes a control byte (plus extended type and size bytes)
+func writeMMDBControl(buf *bytes.Buffer, dataType, size int) {
+	sizeBits := size
+	if size >= 29 {
+		sizeBits = 29
+	}
+
+	if dataType <= 7 {
+		buf.WriteByte(byte(dataType<<5 | sizeBits))
+	} else {
+		buf.WriteByte(byte(sizeBits))
+		buf.WriteByte(byte(dataType - 7))
+	}
+	if size >= 29 {
+		buf.WriteByte(byte(size - 29))
+	}
+}
+
+func newTestGeoIPEnricher(t *testing.T) *GeoIPEnricher {
+	t.Helper()
+
+	cityDB := writeTestMMDB(t, "GeoLite2-City", []testNetwork{
+		{"81.2.69.0/24", map[string]interface{}{
+			"country": map[string]interface{}{"iso_code": "GB"},
+			"city":    map[string]interface{}{"names": map[string]interface{}{"en": "London"}},
+		}},
+		{"175.16.199.0/24", map[string]interface{}{
+			"country": map[string]interface{}{"iso_code": "CN"},
+		}},
+	})
+	asnDB := writeTestMMDB(t, "GeoLite2-ASN", []testNetwork{
+		{"81.2.69.0/24", map[string]interface{}{
+			"autonomous_system_number":       uint32(20712),
+			"autonomous_system_organization": "Andrews & Arnold Ltd",
+		}},
+	})
+
+	g, err := NewGeoIPEnricher(config.GeoIPConfig{Enabled: true, CityDB: cityDB, ASNDB: asnDB})
+	if err != nil {
+		t.Fatalf("Failed to open test databases: %v", err)
+	}
+	t.Cleanup(func() { g.Close() })
+	return g
+}
+
+// TestGeoIPEnricher_Enrich tests country, city and ASN lookups
+func TestGeoIPEnricher_Enrich(t *testing.T) {
+	g := newTestGeoIPEnricher(t)
+
+	entry := &models.LogEntry{IPAddress: "81.2.69.142"}
+	g.Enrich(entry)
+
+	want := map[string]interface{}{
+		models.ExtraGeoCountry: "GB",
+		models.ExtraGeoCity:    "London",
+		models.ExtraGeoASN:     "AS20712",
+		models.ExtraGeoASOrg:   "Andrews & Arnold Ltd",
+	}
+	for key, value := range want {
+		if entry.Extra[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, entry.Extra[key])
+		}
+	}
+
+	countryOnly := &models.LogEntry{IPAddress: "175.16.199.5"}
+	g.Enrich(countryOnly)
+	if countryOnly.Extra[models.ExtraGeoCountry] != "CN" {
+		t.Errorf("Expected country CN, got %v", countryOnly.Extra)
+	}
+	if _, ok := countryOnly.Extra[models.ExtraGeoASN]; ok {
+		t.Error("Expected no ASN for network missing from the ASN database")
+	}
+}
+
+// TestGeoIPEnricher_Unknown tests addresses that cannot be located
+func TestGeoIPEnricher_Unknown(t *testing.T) {
+	g := newTestGeoIPEnricher(t)
+
+	for _, ip := range []string{"", "not-an-ip", "10.0.0.1", "2001:db8::1"} {
+		entry := &models.LogEntry{IPAddress: ip}
+		g.Enrich(entry)
+		if entry.Extra != nil {
+			t.Errorf("Expected no enrichment for %q, got %v", ip, entry.Extra)
+		}
+	}
+}
+
+// TestNewGeoIPEnricher_MissingDatabase tests configuration errors
+func TestNewGeoIPEnricher_MissingDatabase(t *testing.T) {
+	if _, err := NewGeoIPEnricher(config.GeoIPConfig{}); err == nil {
+		t.Error("Expected error when no database is configured")
+	}
+	if _, err := NewGeoIPEnricher(config.GeoIPConfig{CityDB: "/nonexistent.mmdb"}); err == nil {
+		t.Error("Expected error for missing database file")
+	}
+}
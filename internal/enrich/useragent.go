@@ -0,0 +1,305 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// Client classes assigned by the user-agent parser
+const (
+	ClassHuman    = "human"
+	ClassCrawler  = "crawler"  // Search engines, SEO and AI crawlers, link previewers
+	ClassTool     = "tool"     // HTTP libraries and command-line clients
+	ClassHeadless = "headless" // Automated browsers
+	ClassScanner  = "scanner"  // Vulnerability and port scanners
+	ClassMissing  = "missing"  // Empty or "-" user agent
+)
+
+// maxUACacheSize bounds the parse cache; it is cleared when full
+const maxUACacheSize = 10000
+
+// UserAgentInfo is the result of parsing a user-agent string
+type UserAgentInfo struct {
+	Browser string
+	OS      string
+	Device  string // "desktop", "mobile", "tablet" or "bot"
+	Class   string
+	Bot     string // Bot or client name when Class is not human
+}
+
+// IsBot reports whether the client is anything other than a human browser
+func (i UserAgentInfo) IsBot() bool {
+	return i.Class != ClassHuman
+}
+
+// uaSignature maps a lowercase substring to a named client class
+type uaSignature struct {
+	match string
+	name  string
+	class string
+}
+
+// builtinSignatures are checked in order; scanners come first because many
+// of them also claim to be a browser or a generic bot
+var builtinSignatures = []uaSignature{
+	{"sqlmap", "sqlmap", ClassScanner},
+	{"nikto", "Nikto", ClassScanner},
+	{"nmap", "Nmap", ClassScanner},
+	{"masscan", "masscan", ClassScanner},
+	{"zgrab", "ZGrab", ClassScanner},
+	{"nuclei", "Nuclei", ClassScanner},
+	{"wpscan", "WPScan", ClassScanner},
+	{"gobuster", "Gobuster", ClassScanner},
+	{"dirbuster", "DirBuster", ClassScanner},
+	{"acunetix", "Acunetix", ClassScanner},
+	{"nessus", "Nessus", ClassScanner},
+	{"openvas", "OpenVAS", ClassScanner},
+	{"censysinspect", "Censys", ClassScanner},
+
+	{"googlebot", "Googlebot", ClassCrawler},
+	{"bingbot", "Bingbot", ClassCrawler},
+	{"yahoo! slurp", "Yahoo Slurp", ClassCrawler},
+	{"duckduckbot", "DuckDuckBot", ClassCrawler},
+	{"baiduspider", "Baiduspider", ClassCrawler},
+	{"yandexbot", "YandexBot", ClassCrawler},
+	{"applebot", "Applebot", ClassCrawler},
+	{"facebookexternalhit", "Facebook", ClassCrawler},
+	{"twitterbot", "Twitterbot", ClassCrawler},
+	{"linkedinbot", "LinkedInBot", ClassCrawler},
+	{"slackbot", "Slackbot", ClassCrawler},
+	{"ahrefsbot", "AhrefsBot", ClassCrawler},
+	{"semrushbot", "SemrushBot", ClassCrawler},
+	{"mj12bot", "MJ12bot", ClassCrawler},
+	{"dotbot", "DotBot", ClassCrawler},
+	{"petalbot", "PetalBot", ClassCrawler},
+	{"bytespider", "Bytespider", ClassCrawler},
+	{"gptbot", "GPTBot", ClassCrawler},
+	{"ccbot", "CCBot", ClassCrawler},
+	{"amazonbot", "Amazonbot", ClassCrawler},
+	{"scrapy", "Scrapy", ClassCrawler},
+
+	{"headlesschrome", "HeadlessChrome", ClassHeadless},
+	{"phantomjs", "PhantomJS", ClassHeadless},
+	{"slimerjs", "SlimerJS", ClassHeadless},
+	{"puppeteer", "Puppeteer", ClassHeadless},
+	{"playwright", "Playwright", ClassHeadless},
+	{"selenium", "Selenium", ClassHeadless},
+
+	{"curl/", "curl", ClassTool},
+	{"wget/", "Wget", ClassTool},
+	{"python-requests", "python-requests", ClassTool},
+	{"python-urllib", "Python-urllib", ClassTool},
+	{"python-httpx", "python-httpx", ClassTool},
+	{"aiohttp", "aiohttp", ClassTool},
+	{"go-http-client", "Go-http-client", ClassTool},
+	{"okhttp", "okhttp", ClassTool},
+	{"apache-httpclient", "Apache-HttpClient", ClassTool},
+	{"java/", "Java", ClassTool},
+	{"libwww-perl", "libwww-perl", ClassTool},
+	{"httpie", "HTTPie", ClassTool},
+	{"axios/", "axios", ClassTool},
+	{"node-fetch", "node-fetch", ClassTool},
+	{"postmanruntime", "Postman", ClassTool},
+	{"powershell", "PowerShell", ClassTool},
+}
+
+// genericBotTokens catch crawlers missing from the list above
+var genericBotTokens = []string{"bot", "crawler", "spider"}
+
+// UserAgentEnricher parses LogEntry.UserAgent into browser, OS and device
+// families and classifies automated clients, recording the result in the
+// entry's Extra map. It must run before any stage that redacts user agents.
+type UserAgentEnricher struct {
+	signatures []uaSignature
+
+	mu    sync.Mutex
+	cache map[string]UserAgentInfo
+}
+
+// NewUserAgentEnricher creates an enricher with the built-in signatures plus
+// any custom ones, which take precedence
+func NewUserAgentEnricher(cfg config.UserAgentConfig) (*UserAgentEnricher, error) {
+	signatures := make([]uaSignature, 0, len(cfg.Custom)+len(builtinSignatures))
+	for _, custom := range cfg.Custom {
+		if custom.Match == "" {
+			return nil, fmt.Errorf("user agent signature %q has no match", custom.Name)
+		}
+		switch custom.Class {
+		case ClassCrawler, ClassTool, ClassHeadless, ClassScanner:
+		default:
+			return nil, fmt.Errorf("user agent signature %q has unknown class %q", custom.Name, custom.Class)
+		}
+		name := custom.Name
+		if name == "" {
+			name = custom.Match
+		}
+		signatures = append(signatures, uaSignature{
+			match: strings.ToLower(custom.Match),
+			name:  name,
+			class: custom.Class,
+		})
+	}
+	signatures = append(signatures, builtinSignatures...)
+
+	return &UserAgentEnricher{
+		signatures: signatures,
+		cache:      make(map[string]UserAgentInfo),
+	}, nil
+}
+
+// Parse parses and classifies a user-agent string
+func (u *UserAgentEnricher) Parse(userAgent string) UserAgentInfo {
+	u.mu.Lock()
+	info, ok := u.cache[userAgent]
+	u.mu.Unlock()
+	if ok {
+		return info
+	}
+
+	info = u.parse(userAgent)
+
+	u.mu.Lock()
+	if len(u.cache) >= maxUACacheSize {
+		u.cache = make(map[string]UserAgentInfo)
+	}
+	u.cache[userAgent] = info
+	u.mu.Unlock()
+
+	return info
+}
+
+func (u *UserAgentEnricher) parse(userAgent string) UserAgentInfo {
+	trimmed := strings.TrimSpace(userAgent)
+	if trimmed == "" || trimmed == "-" {
+		return UserAgentInfo{Browser: "Other", OS: "Other", Device: "bot", Class: ClassMissing}
+	}
+
+	lower := strings.ToLower(trimmed)
+	info := UserAgentInfo{
+		Browser: parseBrowser(trimmed),
+		OS:      parseOS(trimmed),
+		Class:   ClassHuman,
+	}
+
+	for _, sig := range u.signatures {
+		if strings.Contains(lower, sig.match) {
+			info.Class = sig.class
+			info.Bot = sig.name
+			break
+		}
+	}
+	if info.Class == ClassHuman {
+		for _, token := range genericBotTokens {
+			if strings.Contains(lower, token) {
+				info.Class = ClassCrawler
+				info.Bot = "Other bot"
+				break
+			}
+		}
+	}
+
+	if info.IsBot() {
+		info.Device = "bot"
+		if info.Browser == "Other" {
+			info.Browser = info.Bot
+		}
+	} else {
+		info.Device = parseDevice(trimmed)
+	}
+
+	return info
+}
+
+// parseBrowser returns the browser family. Order matters: most browsers
+// also advertise the engines they are compatible with.
+func parseBrowser(ua string) string {
+	switch {
+	case strings.Contains(ua, "Edg/"), strings.Contains(ua, "EdgA/"), strings.Contains(ua, "EdgiOS/"):
+		return "Edge"
+	case strings.Contains(ua, "OPR/"), strings.Contains(ua, "Opera"):
+		return "Opera"
+	case strings.Contains(ua, "SamsungBrowser/"):
+		return "Samsung Internet"
+	case strings.Contains(ua, "Firefox/"), strings.Contains(ua, "FxiOS/"):
+		return "Firefox"
+	case strings.Contains(ua, "Chrome/"), strings.Contains(ua, "CriOS/"):
+		return "Chrome"
+	case strings.Contains(ua, "Safari/") && strings.Contains(ua, "Version/"):
+		return "Safari"
+	case strings.Contains(ua, "MSIE "), strings.Contains(ua, "Trident/"):
+		return "Internet Explorer"
+	}
+	return "Other"
+}
+
+func parseOS(ua string) string {
+	switch {
+	case strings.Contains(ua, "Windows"):
+		return "Windows"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"), strings.Contains(ua, "iPod"):
+		return "iOS"
+	case strings.Contains(ua, "Android"):
+		return "Android"
+	case strings.Contains(ua, "CrOS"):
+		return "Chrome OS"
+	case strings.Contains(ua, "Mac OS X"), strings.Contains(ua, "Macintosh"):
+		return "macOS"
+	case strings.Contains(ua, "Linux"):
+		return "Linux"
+	}
+	return "Other"
+}
+
+func parseDevice(ua string) string {
+	switch {
+	case strings.Contains(ua, "iPad"), strings.Contains(ua, "Tablet"):
+		return "tablet"
+	case strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile"):
+		return "tablet"
+	case strings.Contains(ua, "Mobi"), strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPod"):
+		return "mobile"
+	}
+	return "desktop"
+}
+
+// Enrich adds user-agent fields to the entry's Extra map
+func (u *UserAgentEnricher) Enrich(entry *models.LogEntry) {
+	info := u.Parse(entry.UserAgent)
+
+	setExtra(entry, models.ExtraUABrowser, info.Browser)
+	setExtra(entry, models.ExtraUAOS, info.OS)
+	setExtra(entry, models.ExtraUADevice, info.Device)
+	setExtra(entry, models.ExtraUAClass, info.Class)
+	if info.Bot != "" {
+		setExtra(entry, models.ExtraUABot, info.Bot)
+	}
+}
+
+// Start enriches log entries from input into output. Other messages pass
+// through unchanged.
+func (u *UserAgentEnricher) Start(ctx context.Context, input <-chan models.Message, output chan<- models.Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-input:
+			if !ok {
+				return
+			}
+			if entry, ok := message.LogEntry(); ok {
+				u.Enrich(entry)
+			}
+
+			select {
+			case output <- message:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
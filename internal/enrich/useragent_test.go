@@ -0,0 +1,99 @@
+package enrich
+
+import (
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// TestUserAgentEnricher_Parse tests browser, OS, device and class parsing
+func TestUserAgentEnricher_Parse(t *testing.T) {
+	u, err := NewUserAgentEnricher(config.UserAgentConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		userAgent string
+		want      UserAgentInfo
+	}{
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+			UserAgentInfo{Browser: "Chrome", OS: "Windows", Device: "desktop", Class: ClassHuman},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.0.0",
+			UserAgentInfo{Browser: "Edge", OS: "Windows", Device: "desktop", Class: ClassHuman},
+		},
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+			UserAgentInfo{Browser: "Safari", OS: "iOS", Device: "mobile", Class: ClassHuman},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 14; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+			UserAgentInfo{Browser: "Chrome", OS: "Android", Device: "tablet", Class: ClassHuman},
+		},
+		{
+			"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0",
+			UserAgentInfo{Browser: "Firefox", OS: "Linux", Device: "desktop", Class: ClassHuman},
+		},
+		{
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			UserAgentInfo{Browser: "Googlebot", OS: "Other", Device: "bot", Class: ClassCrawler, Bot: "Googlebot"},
+		},
+		{
+			"curl/8.5.0",
+			UserAgentInfo{Browser: "curl", OS: "Other", Device: "bot", Class: ClassTool, Bot: "curl"},
+		},
+		{
+			"python-requests/2.32.3",
+			UserAgentInfo{Browser: "python-requests", OS: "Other", Device: "bot", Class: ClassTool, Bot: "python-requests"},
+		},
+		{
+			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/129.0.0.0 Safari/537.36",
+			UserAgentInfo{Browser: "Chrome", OS: "Linux", Device: "bot", Class: ClassHeadless, Bot: "HeadlessChrome"},
+		},
+		{
+			"sqlmap/1.8#stable (https://sqlmap.org)",
+			UserAgentInfo{Browser: "sqlmap", OS: "Other", Device: "bot", Class: ClassScanner, Bot: "sqlmap"},
+		},
+		{
+			"Mozilla/5.0 (compatible; ExampleCrawler/1.0)",
+			UserAgentInfo{Browser: "Other bot", OS: "Other", Device: "bot", Class: ClassCrawler, Bot: "Other bot"},
+		},
+		{
+			"-",
+			UserAgentInfo{Browser: "Other", OS: "Other", Device: "bot", Class: ClassMissing},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := u.Parse(tt.userAgent); got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+// TestUserAgentEnricher_Custom tests custom signatures and enrichment fields
+func TestUserAgentEnricher_Custom(t *testing.T) {
+	u, err := NewUserAgentEnricher(config.UserAgentConfig{
+		Custom: []config.UserAgentSignature{{Name: "Uptime", Match: "UptimeChecker", Class: ClassTool}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := &models.LogEntry{UserAgent: "Mozilla/5.0 (compatible; UptimeChecker/3.0)"}
+	u.Enrich(entry)
+
+	if entry.Extra[models.ExtraUAClass] != ClassTool || entry.Extra[models.ExtraUABot] != "Uptime" {
+		t.Errorf("Expected custom tool classification, got %v", entry.Extra)
+	}
+
+	if _, err := NewUserAgentEnricher(config.UserAgentConfig{
+		Custom: []config.UserAgentSignature{{Name: "x", Match: "x", Class: "robot"}},
+	}); err == nil {
+		t.Error("Expected error for unknown class")
+	}
+}
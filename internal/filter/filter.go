@@ -0,0 +1,275 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// Filter drops uninteresting entries (health checks, static assets, ...)
+// between parsing and the detector, and optionally samples the rest
+// deterministically so the same entry is always kept or always dropped.
+type Filter struct {
+	include    []*rule
+	exclude    []*rule
+	sampleRate float64
+	sampleBy   string
+	stats      Stats
+	mu         sync.Mutex
+}
+
+// Stats counts what the filter did with the entries it received
+type Stats struct {
+	Received    uint64            `json:"received"`
+	Passed      uint64            `json:"passed"`
+	NotIncluded uint64            `json:"not_included"`
+	Excluded    map[string]uint64 `json:"excluded"` // Keyed by rule name
+	SampledOut  uint64            `json:"sampled_out"`
+}
+
+// rule is a compiled config.FilterRule
+type rule struct {
+	name   string
+	field  string
+	prefix string
+	regex  *regexp.Regexp
+	values []string
+}
+
+// NewFilter creates a filter from configuration
+func NewFilter(cfg config.FilterConfig) (*Filter, error) {
+	include, err := compileRules(cfg.Include)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include rule: %w", err)
+	}
+	exclude, err := compileRules(cfg.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude rule: %w", err)
+	}
+
+	if cfg.SampleBy != "" && !models.IsField(cfg.SampleBy) {
+		return nil, fmt.Errorf("invalid sample_by: %w", unknownFieldError(cfg.SampleBy))
+	}
+
+	sampleRate := cfg.SampleRate
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+
+	return &Filter{
+		include:    include,
+		exclude:    exclude,
+		sampleRate: sampleRate,
+		sampleBy:   cfg.SampleBy,
+		stats:      Stats{Excluded: make(map[string]uint64)},
+	}, nil
+}
+
+func compileRules(rules []config.FilterRule) ([]*rule, error) {
+	compiled := make([]*rule, 0, len(rules))
+	for _, r := range rules {
+		if r.Field == "" {
+			return nil, fmt.Errorf("rule %q has no field", r.Name)
+		}
+		if !models.IsField(r.Field) {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, unknownFieldError(r.Field))
+		}
+
+		c := &rule{
+			name:   r.Name,
+			field:  r.Field,
+			prefix: r.Prefix,
+			values: r.Values,
+		}
+		if r.Regex != "" {
+			re, err := regexp.Compile(r.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("rule on %s: %w", r.Field, err)
+			}
+			c.regex = re
+		}
+		if c.name == "" {
+			c.name = c.describe()
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// unknownFieldError lists the valid field names, since a rule on an unknown
+// field never matches
+func unknownFieldError(field string) error {
+	return fmt.Errorf("unknown field %q (available: %s, extra.<key>)", field, strings.Join(models.FieldNames, ", "))
+}
+
+// describe names an unnamed rule after its condition, for the stats
+func (r *rule) describe() string {
+	switch {
+	case r.prefix != "":
+		return r.field + " prefix " + r.prefix
+	case r.regex != nil:
+		return r.field + " ~ " + r.regex.String()
+	case len(r.values) > 0:
+		return r.field + " in " + strings.Join(r.values, ",")
+	default:
+		return r.field + " present"
+	}
+}
+
+// matches reports whether the entry satisfies every condition set on the rule
+func (r *rule) matches(entry *models.LogEntry) bool {
+	value, ok := entry.Field(r.field)
+	if !ok {
+		return false
+	}
+
+	if r.prefix != "" && !strings.HasPrefix(value, r.prefix) {
+		return false
+	}
+	if r.regex != nil && !r.regex.MatchString(value) {
+		return false
+	}
+	if len(r.values) > 0 && !r.matchesValue(value) {
+		return false
+	}
+	return true
+}
+
+func (r *rule) matchesValue(value string) bool {
+	for _, want := range r.values {
+		if want == value {
+			return true
+		}
+		// Status classes such as "4xx"
+		if r.field == "status_code" && len(want) == 3 && strings.HasSuffix(strings.ToLower(want), "xx") {
+			if code, err := strconv.Atoi(value); err == nil && strconv.Itoa(code/100) == want[:1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Allow reports whether the entry should continue to the detector, and
+// records the decision in the stats
+func (f *Filter) Allow(entry *models.LogEntry) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stats.Received++
+
+	if len(f.include) > 0 && !matchesAny(f.include, entry) {
+		f.stats.NotIncluded++
+		return false
+	}
+
+	for _, r := range f.exclude {
+		if r.matches(entry) {
+			f.stats.Excluded[r.name]++
+			return false
+		}
+	}
+
+	if f.sampleRate < 1 && !f.sampled(entry) {
+		f.stats.SampledOut++
+		return false
+	}
+
+	f.stats.Passed++
+	return true
+}
+
+func matchesAny(rules []*rule, entry *models.LogEntry) bool {
+	for _, r := range rules {
+		if r.matches(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// sampled hashes the sample key and keeps the entry when the hash falls in
+// the lowest sampleRate fraction of the hash space
+func (f *Filter) sampled(entry *models.LogEntry) bool {
+	h := fnv.New64a()
+	if f.sampleBy != "" {
+		value, _ := entry.Field(f.sampleBy)
+		h.Write([]byte(value))
+	} else {
+		fmt.Fprintf(h, "%d|%s|%s|%s|%s", entry.Timestamp.UnixNano(), entry.Source, entry.IPAddress, entry.Path, entry.Message)
+	}
+
+	return float64(mix64(h.Sum64())) < f.sampleRate*math.MaxUint64
+}
+
+// mix64 is the splitmix64 finalizer. FNV alone spreads similar short keys
+// (sequential IDs, neighbouring IPs) poorly across the high bits.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Stats returns a snapshot of the filter counters
+func (f *Filter) Stats() Stats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	snapshot := f.stats
+	snapshot.Excluded = make(map[string]uint64, len(f.stats.Excluded))
+	for name, count := range f.stats.Excluded {
+		snapshot.Excluded[name] = count
+	}
+	return snapshot
+}
+
+// ReportStats adds the filter counters to a pipeline stats snapshot.
+// Exclusions are reported per rule as "excluded:<rule>".
+func (f *Filter) ReportStats(stats *models.PipelineStats) {
+	snapshot := f.Stats()
+	counters := map[string]uint64{
+		"received":     snapshot.Received,
+		"passed":       snapshot.Passed,
+		"not_included": snapshot.NotIncluded,
+		"sampled_out":  snapshot.SampledOut,
+	}
+	for name, count := range snapshot.Excluded {
+		counters["excluded:"+name] = count
+	}
+	stats.Stages["filter"] = counters
+}
+
+// Start filters log entries from input into output. Other messages pass
+// through unchanged.
+func (f *Filter) Start(ctx context.Context, input <-chan models.Message, output chan<- models.Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-input:
+			if !ok {
+				return
+			}
+			if entry, ok := message.LogEntry(); ok && !f.Allow(entry) {
+				continue
+			}
+
+			select {
+			case output <- message:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
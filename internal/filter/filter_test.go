@@ -0,0 +1,146 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+func createTestEntry(path, userAgent string, statusCode int) *models.LogEntry {
+	return &models.LogEntry{
+		Level:      "info",
+		Message:    "GET " + path,
+		Path:       path,
+		UserAgent:  userAgent,
+		StatusCode: statusCode,
+		IPAddress:  "10.0.0.1",
+	}
+}
+
+// TestFilter_ExcludeRules tests prefix, regex, status class and extra rules
+func TestFilter_ExcludeRules(t *testing.T) {
+	f, err := NewFilter(config.FilterConfig{
+		Exclude: []config.FilterRule{
+			{Name: "health", Field: "path", Prefix: "/health"},
+			{Field: "user_agent", Regex: `(?i)kube-probe`},
+			{Field: "status_code", Values: []string{"3xx"}},
+			{Field: "extra.static"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	static := createTestEntry("/app.js", "Mozilla/5.0", 200)
+	static.Extra = map[string]interface{}{"static": true}
+
+	tests := []struct {
+		name  string
+		entry *models.LogEntry
+		want  bool
+	}{
+		{"health check", createTestEntry("/healthz", "Mozilla/5.0", 200), false},
+		{"probe user agent", createTestEntry("/api", "Kube-Probe/1.29", 200), false},
+		{"redirect", createTestEntry("/login", "Mozilla/5.0", 302), false},
+		{"static asset", static, false},
+		{"real traffic", createTestEntry("/api/users", "Mozilla/5.0", 500), true},
+	}
+
+	for _, tt := range tests {
+		if got := f.Allow(tt.entry); got != tt.want {
+			t.Errorf("%s: expected Allow=%v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	stats := f.Stats()
+	if stats.Received != 5 || stats.Passed != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.Excluded["health"] != 1 {
+		t.Errorf("Expected named rule to be counted, got %v", stats.Excluded)
+	}
+	if stats.Excluded["status_code in 3xx"] != 1 {
+		t.Errorf("Expected unnamed rule to be described, got %v", stats.Excluded)
+	}
+}
+
+// TestFilter_IncludeRules tests that include rules restrict what passes
+func TestFilter_IncludeRules(t *testing.T) {
+	f, err := NewFilter(config.FilterConfig{
+		Include: []config.FilterRule{{Field: "path", Prefix: "/api/"}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !f.Allow(createTestEntry("/api/orders", "", 200)) {
+		t.Error("Expected /api/orders to be included")
+	}
+	if f.Allow(createTestEntry("/index.html", "", 200)) {
+		t.Error("Expected /index.html to be dropped")
+	}
+	if f.Stats().NotIncluded != 1 {
+		t.Errorf("Expected 1 not-included entry, got %+v", f.Stats())
+	}
+}
+
+// TestFilter_DeterministicSampling tests hash-based sampling
+func TestFilter_DeterministicSampling(t *testing.T) {
+	cfg := config.FilterConfig{SampleRate: 0.25, SampleBy: "ip_address"}
+	first, _ := NewFilter(cfg)
+	second, _ := NewFilter(cfg)
+
+	kept := 0
+	for i := 0; i < 4000; i++ {
+		entry := createTestEntry("/api", "", 200)
+		entry.IPAddress = fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+
+		decision := first.Allow(entry)
+		if decision != second.Allow(entry) {
+			t.Fatalf("Sampling decision for %s is not deterministic", entry.IPAddress)
+		}
+		if decision {
+			kept++
+		}
+	}
+
+	if kept < 800 || kept > 1200 {
+		t.Errorf("Expected roughly 25%% of 4000 entries kept, got %d", kept)
+	}
+	if first.Stats().SampledOut != uint64(4000-kept) {
+		t.Errorf("Expected sampled-out count %d, got %d", 4000-kept, first.Stats().SampledOut)
+	}
+}
+
+// TestNewFilter_InvalidRegex tests configuration validation
+func TestNewFilter_InvalidRegex(t *testing.T) {
+	_, err := NewFilter(config.FilterConfig{
+		Exclude: []config.FilterRule{{Field: "user_agent", Regex: "("}},
+	})
+	if err == nil {
+		t.Error("Expected error for invalid regex")
+	}
+}
+
+// TestNewFilter_UnknownField tests that rules and sampling on fields that
+// never match are configuration errors listing the valid names
+func TestNewFilter_UnknownField(t *testing.T) {
+	for _, cfg := range []config.FilterConfig{
+		{Include: []config.FilterRule{{Field: "staus_code", Values: []string{"5xx"}}}},
+		{Exclude: []config.FilterRule{{Field: "response_time"}}},
+		{Exclude: []config.FilterRule{{Field: "extra."}}},
+		{SampleRate: 0.5, SampleBy: "timestamp"},
+	} {
+		_, err := NewFilter(cfg)
+		if err == nil || !strings.Contains(err.Error(), "status_code") || !strings.Contains(err.Error(), "extra.<key>") {
+			t.Errorf("Expected error listing the valid fields for %+v, got %v", cfg, err)
+		}
+	}
+
+	if _, err := NewFilter(config.FilterConfig{Exclude: []config.FilterRule{{Field: "extra.ua_bot"}}, SampleBy: "ip_address"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package normalize
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// Placeholders substituted for variable path segments
+const (
+	placeholderID   = ":id"
+	placeholderUUID = ":uuid"
+	placeholderHash = ":hash"
+)
+
+// Pre-compiled segment patterns
+var (
+	numericSegment = regexp.MustCompile(`^\d+$`)
+	uuidSegment    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hashSegment    = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+)
+
+// PathNormalizer rewrites request paths into route templates so that
+// /users/123 and /users/456 aggregate as /users/:id. The original path is
+// kept in LogEntry.RawPath.
+type PathNormalizer struct {
+	stripQuery  bool
+	collapseIDs bool
+	routes      []route
+}
+
+// route is a parsed route template
+type route struct {
+	template string
+	segments []string
+}
+
+// NewPathNormalizer creates a normalizer from configuration
+func NewPathNormalizer(cfg config.NormalizeConfig) (*PathNormalizer, error) {
+	routes := make([]route, 0, len(cfg.Routes))
+	for _, template := range cfg.Routes {
+		if !strings.HasPrefix(template, "/") {
+			return nil, fmt.Errorf("route %q must start with /", template)
+		}
+		routes = append(routes, route{
+			template: template,
+			segments: splitPath(template),
+		})
+	}
+
+	return &PathNormalizer{
+		stripQuery:  cfg.StripQuery,
+		collapseIDs: cfg.CollapseIDs,
+		routes:      routes,
+	}, nil
+}
+
+// Normalize returns the route template for a raw request path
+func (n *PathNormalizer) Normalize(path string) string {
+	if n.stripQuery {
+		if i := strings.IndexAny(path, "?#"); i >= 0 {
+			path = path[:i]
+		}
+	}
+
+	segments := splitPath(path)
+
+	// Configured routes take precedence over the generic rules
+	for _, r := range n.routes {
+		if r.matches(segments) {
+			return r.template
+		}
+	}
+
+	if !n.collapseIDs {
+		return path
+	}
+
+	changed := false
+	for i, segment := range segments {
+		if placeholder := classifySegment(segment); placeholder != "" {
+			segments[i] = placeholder
+			changed = true
+		}
+	}
+	if !changed {
+		return path
+	}
+
+	normalized := "/" + strings.Join(segments, "/")
+	if strings.HasSuffix(path, "/") && len(segments) > 0 {
+		normalized += "/"
+	}
+	return normalized
+}
+
+// NormalizeEntry rewrites entry.Path in place, preserving the original
+func (n *PathNormalizer) NormalizeEntry(entry *models.LogEntry) {
+	if entry.Path == "" {
+		return
+	}
+
+	normalized := n.Normalize(entry.Path)
+	if normalized == entry.Path {
+		return
+	}
+	if entry.RawPath == "" {
+		entry.RawPath = entry.Path
+	}
+	entry.Path = normalized
+}
+
+// Start normalizes log entries from input into output. Other messages pass
+// through unchanged.
+func (n *PathNormalizer) Start(ctx context.Context, input <-chan models.Message, output chan<- models.Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-input:
+			if !ok {
+				return
+			}
+			if entry, ok := message.LogEntry(); ok {
+				n.NormalizeEntry(entry)
+			}
+
+			select {
+			case output <- message:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// matches reports whether path segments fit the template; ":name" matches any
+// single segment and a trailing "*" matches the remainder
+func (r route) matches(segments []string) bool {
+	for i, want := range r.segments {
+		if want == "*" && i == len(r.segments)-1 {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if strings.HasPrefix(want, ":") {
+			continue
+		}
+		if want != segments[i] {
+			return false
+		}
+	}
+	return len(segments) == len(r.segments)
+}
+
+// classifySegment returns the placeholder for a variable segment, or ""
+func classifySegment(segment string) string {
+	switch {
+	case numericSegment.MatchString(segment):
+		return placeholderID
+	case uuidSegment.MatchString(segment):
+		return placeholderUUID
+	case hashSegment.MatchString(segment) && strings.ContainsAny(segment, "0123456789"):
+		// Require a digit so long words made only of a-f letters are left alone
+		return placeholderHash
+	default:
+		return ""
+	}
+}
+
+// splitPath splits a path into its non-empty segments
+func splitPath(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+}
@@ -0,0 +1,71 @@
+package normalize
+
+import (
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// TestPathNormalizer_Normalize tests the built-in and configured rules
+func TestPathNormalizer_Normalize(t *testing.T) {
+	n, err := NewPathNormalizer(config.NormalizeConfig{
+		Enabled:     true,
+		StripQuery:  true,
+		CollapseIDs: true,
+		Routes:      []string{"/users/:user/avatar", "/static/*"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users/123", "/users/:id"},
+		{"/users/456/orders/789?token=abc", "/users/:id/orders/:id"},
+		{"/orders/3f2b8c1e-9a4d-4e6b-8f0a-1c2d3e4f5a6b", "/orders/:uuid"},
+		{"/blobs/9f86d081884c7d659a2feaa0c55ad015", "/blobs/:hash"},
+		{"/users/alice/avatar", "/users/:user/avatar"},
+		{"/static/css/app.css", "/static/*"},
+		{"/api/v2/health", "/api/v2/health"},
+		{"/api/facade", "/api/facade"},
+		{"/search#results", "/search"},
+		{"/", "/"},
+	}
+
+	for _, tt := range tests {
+		if got := n.Normalize(tt.path); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestPathNormalizer_NormalizeEntry tests that the raw path is preserved
+func TestPathNormalizer_NormalizeEntry(t *testing.T) {
+	n, _ := NewPathNormalizer(config.NormalizeConfig{Enabled: true, StripQuery: true, CollapseIDs: true})
+
+	entry := &models.LogEntry{Path: "/users/42?tab=posts"}
+	n.NormalizeEntry(entry)
+
+	if entry.Path != "/users/:id" {
+		t.Errorf("Expected normalized path, got %q", entry.Path)
+	}
+	if entry.RawPath != "/users/42?tab=posts" {
+		t.Errorf("Expected raw path to be preserved, got %q", entry.RawPath)
+	}
+
+	unchanged := &models.LogEntry{Path: "/about"}
+	n.NormalizeEntry(unchanged)
+	if unchanged.RawPath != "" {
+		t.Errorf("Expected no raw path for unchanged entry, got %q", unchanged.RawPath)
+	}
+}
+
+// TestNewPathNormalizer_InvalidRoute tests route validation
+func TestNewPathNormalizer_InvalidRoute(t *testing.T) {
+	if _, err := NewPathNormalizer(config.NormalizeConfig{Routes: []string{"users/:id"}}); err == nil {
+		t.Error("Expected error for route without leading slash")
+	}
+}
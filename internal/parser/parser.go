@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/justin4957/logflow-anomaly-detector/internal/registry"
 	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
 )
 
@@ -16,18 +16,40 @@ type LogParser interface {
 	Parse(line string) (*models.LogEntry, error)
 }
 
-// NewParser creates a parser based on the specified format
-func NewParser(format string) LogParser {
-	switch format {
-	case "json":
-		return &JSONParser{}
-	case "apache", "combined":
-		return &ApacheParser{}
-	case "common":
-		return &CommonLogParser{}
-	default:
-		return &JSONParser{}
+// Factory creates a parser, decoding any format-specific options
+type Factory func(decode registry.DecodeFunc) (LogParser, error)
+
+var formats = registry.New[Factory]("log format")
+
+func init() {
+	Register("json", func(registry.DecodeFunc) (LogParser, error) { return &JSONParser{}, nil })
+	Register("apache", func(registry.DecodeFunc) (LogParser, error) { return &ApacheParser{}, nil })
+	Register("combined", func(registry.DecodeFunc) (LogParser, error) { return &ApacheParser{}, nil })
+	Register("common", func(registry.DecodeFunc) (LogParser, error) { return &CommonLogParser{}, nil })
+}
+
+// Register makes a log format available by name. It panics if the name is
+// already registered.
+func Register(format string, factory Factory) {
+	formats.Register(format, factory)
+}
+
+// Formats returns the registered log format names
+func Formats() []string {
+	return formats.Names()
+}
+
+// New creates a parser for a registered format, returning an error that
+// lists the available formats when it is unknown
+func New(format string, decode registry.DecodeFunc) (LogParser, error) {
+	factory, err := formats.Lookup(format)
+	if err != nil {
+		return nil, err
 	}
+	if decode == nil {
+		decode = registry.NoOptions
+	}
+	return factory(decode)
 }
 
 // JSONParser parses JSON-formatted logs
@@ -41,6 +63,17 @@ func (p *JSONParser) Parse(line string) (*models.LogEntry, error) {
 	return &entry, nil
 }
 
+// ParseFields maps an already-decoded structured record onto a LogEntry using
+// the same field rules as JSONParser. It is used by inputs that receive
+// structured records rather than raw lines (e.g. Fluent Forward).
+func ParseFields(fields map[string]interface{}) (*models.LogEntry, error) {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode log record: %w", err)
+	}
+	return (&JSONParser{}).Parse(string(data))
+}
+
 // ApacheParser parses Apache Combined log format
 type ApacheParser struct {
 	regex *regexp.Regexp
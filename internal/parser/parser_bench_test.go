@@ -98,19 +98,19 @@ func BenchmarkCommonLogParserAllocs(b *testing.B) {
 func BenchmarkParserFactoryOverhead(b *testing.B) {
 	b.Run("JSON", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			_ = NewParser("json")
+			_, _ = New("json", nil)
 		}
 	})
 
 	b.Run("Apache", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			_ = NewParser("apache")
+			_, _ = New("apache", nil)
 		}
 	})
 
 	b.Run("Common", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			_ = NewParser("common")
+			_, _ = New("common", nil)
 		}
 	})
 }
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/registry"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// prefixParser is a test format that strips a configurable prefix
+type prefixParser struct {
+	prefix string
+}
+
+func (p *prefixParser) Parse(line string) (*models.LogEntry, error) {
+	return &models.LogEntry{Message: strings.TrimPrefix(line, p.prefix)}, nil
+}
+
+// TestNew_Registry tests registered formats, options and unknown formats
+func TestNew_Registry(t *testing.T) {
+	Register("test_prefix", func(decode registry.DecodeFunc) (LogParser, error) {
+		options := struct {
+			Prefix string `yaml:"prefix"`
+		}{Prefix: "> "}
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		return &prefixParser{prefix: options.Prefix}, nil
+	})
+
+	p, err := New("test_prefix", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, _ := p.Parse("> hello")
+	if entry.Message != "hello" {
+		t.Errorf("Expected default prefix to be stripped, got %q", entry.Message)
+	}
+
+	if _, err := New("apache", nil); err != nil {
+		t.Errorf("Expected built-in apache format, got %v", err)
+	}
+
+	_, err = New("syslog", nil)
+	if err == nil || !strings.Contains(err.Error(), "available: apache, combined, common, json, test_prefix") {
+		t.Errorf("Expected unknown format error listing formats, got %v", err)
+	}
+}
@@ -0,0 +1,404 @@
+package redact
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// Redaction modes
+const (
+	ModeMask = "mask" // Replace matches with [REDACTED:<detector>]
+	ModeHash = "hash" // Replace matches with a keyed hash, stable for grouping
+	ModeDrop = "drop" // Clear the whole field when it contains a match
+)
+
+// defaultFields are scanned when RedactConfig.Fields is empty
+var defaultFields = []string{"message", "path", "raw_path", "user_agent", "ip_address", "extra"}
+
+// stringFields are the entry fields stringField can redact
+var stringFields = []string{"message", "path", "raw_path", "user_agent", "ip_address", "source"}
+
+// detector finds one kind of sensitive value
+type detector struct {
+	name     string
+	regex    *regexp.Regexp
+	group    int               // Submatch to replace; 0 replaces the whole match
+	validate func(string) bool // Optional check to reduce false positives
+	mode     string
+}
+
+// builtinDetectors are available by name in RedactConfig.Detectors. Their
+// order matters: tokens are handled before the patterns they could contain.
+var builtinDetectors = []detector{
+	{
+		name:  "jwt",
+		regex: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	},
+	{
+		name:  "bearer_token",
+		regex: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+		group: 1,
+	},
+	{
+		name:  "url_secret",
+		regex: regexp.MustCompile(`(?i)[?&;](?:access_token|token|api_?key|key|password|passwd|secret|sig|signature|auth)=([^&;\s"]+)`),
+		group: 1,
+	},
+	{
+		name:  "email",
+		regex: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+	{
+		name:     "credit_card",
+		regex:    regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		validate: luhnValid,
+	},
+	{
+		name:     "ipv6",
+		regex:    regexp.MustCompile(`[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}`),
+		validate: isIPv6,
+	},
+	{
+		name:  "ipv4",
+		regex: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`),
+	},
+}
+
+// Redactor removes personal data and secrets from log entries
+type Redactor struct {
+	detectors []detector
+	fields    []string
+	hashKey   []byte
+	counts    map[string]uint64 // Redactions per detector
+	mu        sync.Mutex
+}
+
+// NewRedactor creates a redactor from configuration
+func NewRedactor(cfg config.RedactConfig) (*Redactor, error) {
+	mode := cfg.Mode
+	if mode == "" {
+		mode = ModeMask
+	}
+	if err := validateMode(mode); err != nil {
+		return nil, err
+	}
+
+	var detectors []detector
+	for _, builtin := range builtinDetectors {
+		if contains(cfg.Detectors, builtin.name) {
+			d := builtin
+			d.mode = mode
+			detectors = append(detectors, d)
+		}
+	}
+	for _, name := range cfg.Detectors {
+		if !isBuiltin(name) {
+			return nil, fmt.Errorf("unknown redaction detector %q (available: %s)", name, strings.Join(BuiltinDetectors(), ", "))
+		}
+	}
+
+	for _, pattern := range cfg.Custom {
+		re, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("custom pattern %q: %w", pattern.Name, err)
+		}
+		patternMode := pattern.Mode
+		if patternMode == "" {
+			patternMode = mode
+		}
+		if err := validateMode(patternMode); err != nil {
+			return nil, fmt.Errorf("custom pattern %q: %w", pattern.Name, err)
+		}
+		name := pattern.Name
+		if name == "" {
+			name = "custom"
+		}
+		detectors = append(detectors, detector{name: name, regex: re, mode: patternMode})
+	}
+
+	for _, d := range detectors {
+		if d.mode == ModeHash && cfg.HashKey == "" {
+			return nil, fmt.Errorf("hash mode requires hash_key: unkeyed hashes of IPs and other short values can be reversed by brute force")
+		}
+	}
+
+	fields := cfg.Fields
+	if len(fields) == 0 {
+		fields = defaultFields
+	}
+	for _, field := range fields {
+		if err := validateField(field); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Redactor{
+		detectors: detectors,
+		fields:    fields,
+		hashKey:   []byte(cfg.HashKey),
+		counts:    make(map[string]uint64),
+	}, nil
+}
+
+// BuiltinDetectors returns the names of the built-in detectors
+func BuiltinDetectors() []string {
+	names := make([]string, len(builtinDetectors))
+	for i, d := range builtinDetectors {
+		names[i] = d.name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Redact scrubs the configured fields of an entry in place
+func (r *Redactor) Redact(entry *models.LogEntry) {
+	for _, field := range r.fields {
+		switch {
+		case field == "extra":
+			for key := range entry.Extra {
+				r.redactExtra(entry, key)
+			}
+		case strings.HasPrefix(field, "extra."):
+			r.redactExtra(entry, strings.TrimPrefix(field, "extra."))
+		default:
+			if value := stringField(entry, field); value != nil && *value != "" {
+				*value = r.redactString(*value, true)
+			}
+		}
+	}
+}
+
+func (r *Redactor) redactExtra(entry *models.LogEntry, key string) {
+	value, ok := entry.Extra[key].(string)
+	if !ok || value == "" {
+		return
+	}
+
+	redacted := r.redactString(value, true)
+	if redacted == "" {
+		delete(entry.Extra, key)
+		return
+	}
+	entry.Extra[key] = redacted
+}
+
+// RedactString applies every detector to a value without counting the
+// redactions, for text shown more than once such as parse error samples in
+// pipeline stats. Drop-mode matches return an empty string.
+func (r *Redactor) RedactString(value string) string {
+	return r.redactString(value, false)
+}
+
+// redactString applies every detector to a value. An empty result means a
+// drop-mode detector matched and the field should be cleared.
+func (r *Redactor) redactString(value string, counted bool) string {
+	for i := range r.detectors {
+		d := &r.detectors[i]
+
+		matched := false
+		value = replaceMatches(d, value, func(secret string) string {
+			matched = true
+			return r.replacement(d, secret, counted)
+		})
+		if !matched {
+			continue
+		}
+
+		if d.mode == ModeDrop {
+			if counted {
+				r.count(d.name)
+			}
+			return ""
+		}
+	}
+	return value
+}
+
+// replaceMatches replaces each (validated) match, or its configured submatch
+func replaceMatches(d *detector, value string, replace func(string) string) string {
+	matches := d.regex.FindAllStringSubmatchIndex(value, -1)
+	if len(matches) == 0 {
+		return value
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[2*d.group], m[2*d.group+1]
+		if start < 0 {
+			continue
+		}
+		secret := value[start:end]
+		if d.validate != nil && !d.validate(secret) {
+			continue
+		}
+		b.WriteString(value[last:start])
+		b.WriteString(replace(secret))
+		last = end
+	}
+	b.WriteString(value[last:])
+	return b.String()
+}
+
+// replacement renders the substitute for a matched secret
+func (r *Redactor) replacement(d *detector, secret string, counted bool) string {
+	if counted && d.mode != ModeDrop {
+		r.count(d.name)
+	}
+
+	if d.mode == ModeHash {
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(secret))
+		return "[" + d.name + ":" + hex.EncodeToString(mac.Sum(nil))[:12] + "]"
+	}
+	return "[REDACTED:" + d.name + "]"
+}
+
+func (r *Redactor) count(name string) {
+	r.mu.Lock()
+	r.counts[name]++
+	r.mu.Unlock()
+}
+
+// Stats returns the number of redactions per detector
+func (r *Redactor) Stats() map[string]uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := make(map[string]uint64, len(r.counts))
+	for name, count := range r.counts {
+		snapshot[name] = count
+	}
+	return snapshot
+}
+
+// ReportStats adds redactions per detector to a pipeline stats snapshot
+func (r *Redactor) ReportStats(stats *models.PipelineStats) {
+	stats.Stages["redact"] = r.Stats()
+}
+
+// Start redacts log entries from input into output. Other messages pass
+// through unchanged.
+func (r *Redactor) Start(ctx context.Context, input <-chan models.Message, output chan<- models.Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-input:
+			if !ok {
+				return
+			}
+			if entry, ok := message.LogEntry(); ok {
+				r.Redact(entry)
+			} else if parseErr, ok := message.Payload.(models.ParseError); ok {
+				// Unparsed lines are raw log text and may hold anything
+				parseErr.Line = r.redactString(parseErr.Line, true)
+				message.Payload = parseErr
+			}
+
+			select {
+			case output <- message:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// stringField returns a pointer to a redactable string field of the entry
+func stringField(entry *models.LogEntry, name string) *string {
+	switch name {
+	case "message":
+		return &entry.Message
+	case "path":
+		return &entry.Path
+	case "raw_path":
+		return &entry.RawPath
+	case "user_agent":
+		return &entry.UserAgent
+	case "ip_address":
+		return &entry.IPAddress
+	case "source":
+		return &entry.Source
+	default:
+		return nil
+	}
+}
+
+// luhnValid checks a card number candidate with the Luhn checksum
+func luhnValid(candidate string) bool {
+	sum := 0
+	double := false
+	digits := 0
+	for i := len(candidate) - 1; i >= 0; i-- {
+		c := candidate[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		n := int(c - '0')
+		if double {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+		double = !double
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
+
+func isIPv6(candidate string) bool {
+	addr, err := netip.ParseAddr(candidate)
+	return err == nil && addr.Is6()
+}
+
+// validateField checks that a field name in RedactConfig.Fields is known
+func validateField(field string) error {
+	if field == "extra" || (strings.HasPrefix(field, "extra.") && field != "extra.") {
+		return nil
+	}
+	if stringField(&models.LogEntry{}, field) != nil {
+		return nil
+	}
+	return fmt.Errorf("unknown redaction field %q (available: %s, extra, extra.<key>)", field, strings.Join(stringFields, ", "))
+}
+
+func validateMode(mode string) error {
+	switch mode {
+	case ModeMask, ModeHash, ModeDrop:
+		return nil
+	default:
+		return fmt.Errorf("unknown redaction mode %q (available: mask, hash, drop)", mode)
+	}
+}
+
+func isBuiltin(name string) bool {
+	for _, d := range builtinDetectors {
+		if d.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+func newTestRedactor(t *testing.T, cfg config.RedactConfig) *Redactor {
+	t.Helper()
+
+	r, err := NewRedactor(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return r
+}
+
+// TestRedactor_BuiltinDetectors tests masking with each built-in detector
+func TestRedactor_BuiltinDetectors(t *testing.T) {
+	r := newTestRedactor(t, config.RedactConfig{Detectors: BuiltinDetectors()})
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"email", "user alice@example.com logged in", "user [REDACTED:email] logged in"},
+		{"valid card", "paid with 4111 1111 1111 1111", "paid with [REDACTED:credit_card]"},
+		{"invalid card", "order 1234567890123", "order 1234567890123"},
+		{"bearer", "Authorization: Bearer abc.DEF-123", "Authorization: Bearer [REDACTED:bearer_token]"},
+		{"jwt", "token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig_123", "token [REDACTED:jwt]"},
+		{"url secret", `"GET /cb?code=1&access_token=s3cr3t HTTP/1.1"`, `"GET /cb?code=1&access_token=[REDACTED:url_secret] HTTP/1.1"`},
+		{"ipv4", "client 192.168.1.10 connected", "client [REDACTED:ipv4] connected"},
+		{"ipv6", "client 2001:db8::1 connected", "client [REDACTED:ipv6] connected"},
+		{"timestamp is not ipv6", "[15/Jan/2025:10:30:00 -0700]", "[15/Jan/2025:10:30:00 -0700]"},
+	}
+
+	for _, tt := range tests {
+		entry := &models.LogEntry{Message: tt.input}
+		r.Redact(entry)
+		if entry.Message != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, entry.Message, tt.want)
+		}
+	}
+}
+
+// TestRedactor_HashMode tests that hashing is stable and keyed
+func TestRedactor_HashMode(t *testing.T) {
+	cfg := config.RedactConfig{Detectors: []string{"email"}, Mode: ModeHash, HashKey: "k1"}
+	r := newTestRedactor(t, cfg)
+
+	first := &models.LogEntry{Message: "from bob@example.com"}
+	second := &models.LogEntry{Message: "again bob@example.com"}
+	r.Redact(first)
+	r.Redact(second)
+
+	hash := strings.TrimPrefix(first.Message, "from ")
+	if !strings.HasPrefix(hash, "[email:") || strings.Contains(hash, "bob") {
+		t.Fatalf("Expected hashed email, got %q", first.Message)
+	}
+	if second.Message != "again "+hash {
+		t.Errorf("Expected stable hash %q, got %q", hash, second.Message)
+	}
+
+	cfg.HashKey = "k2"
+	other := &models.LogEntry{Message: "from bob@example.com"}
+	newTestRedactor(t, cfg).Redact(other)
+	if other.Message == first.Message {
+		t.Error("Expected different hash for a different key")
+	}
+}
+
+// TestRedactor_DropModeAndFields tests dropping fields, Extra and custom patterns
+func TestRedactor_DropModeAndFields(t *testing.T) {
+	r := newTestRedactor(t, config.RedactConfig{
+		Detectors: []string{"ipv4"},
+		Custom:    []config.RedactPattern{{Name: "ssn", Regex: `\b\d{3}-\d{2}-\d{4}\b`, Mode: ModeDrop}},
+		Fields:    []string{"message", "ip_address", "extra"},
+	})
+
+	entry := &models.LogEntry{
+		Message:   "ssn 123-45-6789 submitted",
+		IPAddress: "10.1.2.3",
+		Path:      "/users/10.1.2.3",
+		Extra:     map[string]interface{}{"note": "ssn 987-65-4321", "count": 3},
+	}
+	r.Redact(entry)
+
+	if entry.Message != "" {
+		t.Errorf("Expected message to be dropped, got %q", entry.Message)
+	}
+	if entry.IPAddress != "[REDACTED:ipv4]" {
+		t.Errorf("Expected IP field to be masked, got %q", entry.IPAddress)
+	}
+	if entry.Path != "/users/10.1.2.3" {
+		t.Errorf("Expected unlisted field to be untouched, got %q", entry.Path)
+	}
+	if _, ok := entry.Extra["note"]; ok {
+		t.Error("Expected Extra key to be dropped")
+	}
+	if entry.Extra["count"] != 3 {
+		t.Error("Expected non-string Extra values to be kept")
+	}
+
+	stats := r.Stats()
+	if stats["ssn"] != 2 || stats["ipv4"] != 1 {
+		t.Errorf("Unexpected stats: %v", stats)
+	}
+}
+
+// TestNewRedactor_InvalidConfig tests configuration errors
+func TestNewRedactor_InvalidConfig(t *testing.T) {
+	if _, err := NewRedactor(config.RedactConfig{Detectors: []string{"ssn"}}); err == nil {
+		t.Error("Expected error for unknown detector")
+	}
+	if _, err := NewRedactor(config.RedactConfig{Mode: "scramble"}); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+	if _, err := NewRedactor(config.RedactConfig{Fields: []string{"mesage"}}); err == nil || !strings.Contains(err.Error(), "ip_address") {
+		t.Errorf("Expected error listing the valid fields, got %v", err)
+	}
+	if _, err := NewRedactor(config.RedactConfig{Detectors: []string{"ipv4"}, Mode: ModeHash}); err == nil {
+		t.Error("Expected error for hash mode without a key")
+	}
+	custom := []config.RedactPattern{{Name: "ssn", Regex: `\d{3}-\d{2}-\d{4}`, Mode: ModeHash}}
+	if _, err := NewRedactor(config.RedactConfig{Custom: custom}); err == nil {
+		t.Error("Expected error for a hashed custom pattern without a key")
+	}
+}
+
+// TestRedactor_DefaultFields tests that the client IP is scanned by default
+func TestRedactor_DefaultFields(t *testing.T) {
+	r := newTestRedactor(t, config.RedactConfig{Detectors: []string{"ipv4", "ipv6"}})
+
+	entry := &models.LogEntry{IPAddress: "203.0.113.7", Message: "client 2001:db8::1 connected"}
+	r.Redact(entry)
+	if entry.IPAddress != "[REDACTED:ipv4]" || entry.Message != "client [REDACTED:ipv6] connected" {
+		t.Errorf("Expected IPs redacted, got %q and %q", entry.IPAddress, entry.Message)
+	}
+}
+
+// TestRedactor_DefaultConfig tests that the default configuration masks the
+// client IP and IPs in an Apache access line
+func TestRedactor_DefaultConfig(t *testing.T) {
+	r := newTestRedactor(t, config.DefaultConfig().RedactConfig)
+
+	entry := &models.LogEntry{
+		IPAddress: "203.0.113.7",
+		Message:   `203.0.113.7 - - [15/Jan/2025:10:30:00 -0700] "GET /status HTTP/1.1" 200 512 from 2001:db8::1`,
+	}
+	r.Redact(entry)
+	if entry.IPAddress != "[REDACTED:ipv4]" {
+		t.Errorf("Expected client IP redacted, got %q", entry.IPAddress)
+	}
+	want := `[REDACTED:ipv4] - - [15/Jan/2025:10:30:00 -0700] "GET /status HTTP/1.1" 200 512 from [REDACTED:ipv6]`
+	if entry.Message != want {
+		t.Errorf("Expected IPs in the message redacted, got %q", entry.Message)
+	}
+}
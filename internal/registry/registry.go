@@ -0,0 +1,91 @@
+// Package registry provides named factory registries so parsers, detection
+// algorithms and sinks can be added by other packages and selected by name
+// from configuration.
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DecodeFunc decodes a component's configuration options into v, which is
+// usually a pointer to a struct with yaml tags
+type DecodeFunc func(v interface{}) error
+
+// NodeDecoder returns a DecodeFunc for an options block from the YAML config.
+// An absent block decodes to nothing, leaving v unchanged.
+func NodeDecoder(node yaml.Node) DecodeFunc {
+	return func(v interface{}) error {
+		if node.Kind == 0 {
+			return nil
+		}
+		return node.Decode(v)
+	}
+}
+
+// NoOptions is a DecodeFunc for components created without options
+func NoOptions(v interface{}) error {
+	return nil
+}
+
+// Registry maps names to factories of type F
+type Registry[F any] struct {
+	kind      string
+	mu        sync.RWMutex
+	factories map[string]F
+}
+
+// New creates a registry; kind names the component in error messages,
+// e.g. "log format"
+func New[F any](kind string) *Registry[F] {
+	return &Registry[F]{
+		kind:      kind,
+		factories: make(map[string]F),
+	}
+}
+
+// Register adds a factory. Like database/sql.Register it panics if the name
+// is empty or already registered, since that is a programming error.
+func (r *Registry[F]) Register(name string, factory F) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if name == "" {
+		panic(fmt.Sprintf("registry: empty %s name", r.kind))
+	}
+	if _, exists := r.factories[name]; exists {
+		panic(fmt.Sprintf("registry: %s %q registered twice", r.kind, name))
+	}
+	r.factories[name] = factory
+}
+
+// Lookup returns the factory for name, or an error listing the registered
+// names when there is none
+func (r *Registry[F]) Lookup(name string) (F, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		var zero F
+		return zero, fmt.Errorf("unknown %s %q (available: %s)", r.kind, name, strings.Join(r.Names(), ", "))
+	}
+	return factory, nil
+}
+
+// Names returns the registered names in sorted order
+func (r *Registry[F]) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
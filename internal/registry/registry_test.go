@@ -0,0 +1,57 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestRegistry_Lookup tests registration and unknown-name errors
+func TestRegistry_Lookup(t *testing.T) {
+	r := New[func() string]("widget")
+	r.Register("b", func() string { return "b" })
+	r.Register("a", func() string { return "a" })
+
+	factory, err := r.Lookup("a")
+	if err != nil || factory() != "a" {
+		t.Fatalf("Expected factory a, got err %v", err)
+	}
+
+	_, err = r.Lookup("c")
+	if err == nil || !strings.Contains(err.Error(), `unknown widget "c" (available: a, b)`) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic on duplicate registration")
+		}
+	}()
+	r.Register("a", func() string { return "again" })
+}
+
+// TestNodeDecoder tests decoding options blocks
+func TestNodeDecoder(t *testing.T) {
+	var cfg struct {
+		Options yaml.Node `yaml:"options"`
+	}
+	if err := yaml.Unmarshal([]byte("options:\n  size: 3\n"), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	options := struct {
+		Size int    `yaml:"size"`
+		Name string `yaml:"name"`
+	}{Name: "default"}
+	if err := NodeDecoder(cfg.Options)(&options); err != nil {
+		t.Fatal(err)
+	}
+	if options.Size != 3 || options.Name != "default" {
+		t.Errorf("Unexpected options: %+v", options)
+	}
+
+	if err := NodeDecoder(yaml.Node{})(&options); err != nil {
+		t.Errorf("Expected absent options to decode to nothing, got %v", err)
+	}
+}
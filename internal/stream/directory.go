@@ -0,0 +1,358 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+)
+
+// SourceLine is a line read in directory watch mode, tagged with its file
+type SourceLine struct {
+	Path string
+	Line string
+}
+
+// DirectoryWatcher tails every matching file in a directory. New files are
+// attached when they are created, files that stop receiving writes are
+// released after an idle timeout, and at most maxOpenFiles are tailed at once
+// (the least recently active file is released to make room).
+type DirectoryWatcher struct {
+	config       config.WatchConfig
+	idleTimeout  time.Duration
+	maxOpenFiles int
+	watcher      *fsnotify.Watcher
+	lineChan     chan SourceLine
+	files        map[string]*watchedFile // Currently tailed files
+	offsets      map[string]int64        // Read positions of released files
+	dropped      uint64                  // Lines dropped by released tailers
+	wg           sync.WaitGroup
+	mu           sync.Mutex
+}
+
+// watchedFile tracks a tailed file within the directory
+type watchedFile struct {
+	tailer     *Tailer
+	lastActive time.Time
+}
+
+// NewDirectoryWatcher creates a new directory watcher
+func NewDirectoryWatcher(cfg config.WatchConfig) *DirectoryWatcher {
+	idleTimeout := time.Duration(cfg.IdleTimeoutSeconds) * time.Second
+	if idleTimeout <= 0 {
+		idleTimeout = 5 * time.Minute
+	}
+	maxOpenFiles := cfg.MaxOpenFiles
+	if maxOpenFiles <= 0 {
+		maxOpenFiles = 64
+	}
+
+	return &DirectoryWatcher{
+		config:       cfg,
+		idleTimeout:  idleTimeout,
+		maxOpenFiles: maxOpenFiles,
+		lineChan:     make(chan SourceLine, 100),
+		files:        make(map[string]*watchedFile),
+		offsets:      make(map[string]int64),
+	}
+}
+
+// Start begins watching the directory. Files that already exist are tailed
+// from their end, like a single-file LogStream; files created afterwards are
+// read from the beginning.
+func (dw *DirectoryWatcher) Start(ctx context.Context) (<-chan SourceLine, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+	if err := watcher.Add(dw.config.Dir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch directory: %w", err)
+	}
+	dw.watcher = watcher
+
+	if err := dw.attachExisting(ctx); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	log.Printf("Started watching directory: %s", dw.config.Dir)
+
+	go dw.watchLoop(ctx)
+
+	return dw.lineChan, nil
+}
+
+// attachExisting tails the most recently modified matching files at startup
+func (dw *DirectoryWatcher) attachExisting(ctx context.Context) error {
+	dirEntries, err := os.ReadDir(dw.config.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	type candidate struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var candidates []candidate
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.Type().IsRegular() || !dw.matches(dirEntry.Name()) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			path:    filepath.Join(dw.config.Dir, dirEntry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].modTime.After(candidates[j].modTime)
+	})
+
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	for i, c := range candidates {
+		if i < dw.maxOpenFiles {
+			dw.attachLocked(ctx, c.path, -1)
+		} else {
+			// Over the cap: remember the current end so a later write resumes there
+			dw.offsets[c.path] = c.size
+		}
+	}
+	return nil
+}
+
+// watchLoop reacts to directory events and releases idle files
+func (dw *DirectoryWatcher) watchLoop(ctx context.Context) {
+	defer dw.shutdown()
+
+	sweepInterval := dw.idleTimeout / 2
+	if sweepInterval > 10*time.Second {
+		sweepInterval = 10 * time.Second
+	}
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-dw.watcher.Events:
+			if !ok {
+				return
+			}
+			dw.handleEvent(ctx, event)
+
+		case err, ok := <-dw.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Directory watcher error: %v", err)
+
+		case <-ticker.C:
+			dw.releaseIdle()
+		}
+	}
+}
+
+func (dw *DirectoryWatcher) handleEvent(ctx context.Context, event fsnotify.Event) {
+	if !dw.matches(filepath.Base(event.Name)) {
+		return
+	}
+
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	switch {
+	case event.Op&fsnotify.Create == fsnotify.Create:
+		if info, err := os.Stat(event.Name); err != nil || !info.Mode().IsRegular() {
+			return
+		}
+		log.Printf("File created: %s", event.Name)
+		delete(dw.offsets, event.Name)
+		dw.attachLocked(ctx, event.Name, 0)
+
+	case event.Op&fsnotify.Write == fsnotify.Write:
+		// Writes to a released (idle or evicted) file re-attach it where it left off
+		if wf, attached := dw.files[event.Name]; attached {
+			wf.tailer.notify(event)
+		} else {
+			dw.attachLocked(ctx, event.Name, dw.offsets[event.Name])
+		}
+
+	case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		if _, attached := dw.files[event.Name]; attached {
+			log.Printf("File removed: %s", event.Name)
+			dw.releaseLocked(event.Name)
+		}
+		delete(dw.offsets, event.Name)
+	}
+}
+
+// attachLocked starts tailing path at offset, evicting the least recently
+// active file if the open-file cap is reached. dw.mu must be held.
+func (dw *DirectoryWatcher) attachLocked(ctx context.Context, path string, offset int64) {
+	if _, attached := dw.files[path]; attached {
+		return
+	}
+
+	if len(dw.files) >= dw.maxOpenFiles {
+		var oldestPath string
+		var oldest time.Time
+		for p, wf := range dw.files {
+			if oldestPath == "" || wf.lastActive.Before(oldest) {
+				oldestPath, oldest = p, wf.lastActive
+			}
+		}
+		log.Printf("Open file limit reached, releasing: %s", oldestPath)
+		dw.releaseLocked(oldestPath)
+	}
+
+	tailer := newSharedTailerAt(offset)
+	lines, err := tailer.Start(ctx, path)
+	if err != nil {
+		log.Printf("Failed to attach %s: %v", path, err)
+		return
+	}
+
+	wf := &watchedFile{tailer: tailer, lastActive: time.Now()}
+	dw.files[path] = wf
+	delete(dw.offsets, path)
+
+	dw.wg.Add(1)
+	go dw.forwardLines(ctx, path, wf, lines)
+}
+
+// forwardLines tags lines from one file and fans them into lineChan
+func (dw *DirectoryWatcher) forwardLines(ctx context.Context, path string, wf *watchedFile, lines <-chan string) {
+	defer dw.wg.Done()
+
+	for line := range lines {
+		dw.mu.Lock()
+		wf.lastActive = time.Now()
+		dw.mu.Unlock()
+
+		select {
+		case dw.lineChan <- SourceLine{Path: path, Line: line}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// releaseIdle releases files that have not been written to within idleTimeout
+func (dw *DirectoryWatcher) releaseIdle() {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	cutoff := time.Now().Add(-dw.idleTimeout)
+	for path, wf := range dw.files {
+		if wf.lastActive.Before(cutoff) {
+			log.Printf("Releasing idle file: %s", path)
+			dw.releaseLocked(path)
+		}
+	}
+}
+
+// releaseLocked stops tailing path and remembers its offset. dw.mu must be held.
+func (dw *DirectoryWatcher) releaseLocked(path string) {
+	wf, ok := dw.files[path]
+	if !ok {
+		return
+	}
+	dw.offsets[path] = wf.tailer.Offset()
+	dw.dropped += wf.tailer.Dropped()
+	wf.tailer.Stop()
+	delete(dw.files, path)
+}
+
+// OpenFiles returns the paths currently being tailed
+func (dw *DirectoryWatcher) OpenFiles() []string {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	paths := make([]string, 0, len(dw.files))
+	for path := range dw.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Lags returns the unread bytes of every known file, including released
+// files that have been written to since
+func (dw *DirectoryWatcher) Lags() map[string]int64 {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	lags := make(map[string]int64, len(dw.files)+len(dw.offsets))
+	for path, wf := range dw.files {
+		lags[path] = wf.tailer.Lag()
+	}
+	for path, offset := range dw.offsets {
+		if info, err := os.Stat(path); err == nil && info.Size() > offset {
+			lags[path] = info.Size() - offset
+		} else {
+			lags[path] = 0
+		}
+	}
+	return lags
+}
+
+// Dropped returns how many lines tailers dropped because the consumer fell behind
+func (dw *DirectoryWatcher) Dropped() uint64 {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	dropped := dw.dropped
+	for _, wf := range dw.files {
+		dropped += wf.tailer.Dropped()
+	}
+	return dropped
+}
+
+// shutdown releases every file and closes the line channel
+func (dw *DirectoryWatcher) shutdown() {
+	dw.mu.Lock()
+	for path := range dw.files {
+		dw.releaseLocked(path)
+	}
+	dw.mu.Unlock()
+
+	dw.watcher.Close()
+	dw.wg.Wait()
+	close(dw.lineChan)
+	log.Printf("Directory watcher stopped")
+}
+
+// matches applies the include and exclude patterns to a file name
+func (dw *DirectoryWatcher) matches(name string) bool {
+	if len(dw.config.Include) > 0 && !matchAny(dw.config.Include, name) {
+		return false
+	}
+	return !matchAny(dw.config.Exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
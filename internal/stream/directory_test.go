@@ -0,0 +1,243 @@
+package stream
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/internal/parser"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// startTestDirectoryWatcher starts a watcher over a fresh temporary directory
+func startTestDirectoryWatcher(t *testing.T, cfg config.WatchConfig, idleTimeout time.Duration) (*DirectoryWatcher, <-chan SourceLine) {
+	t.Helper()
+
+	cfg.Dir = t.TempDir()
+	watcher := NewDirectoryWatcher(cfg)
+	if idleTimeout > 0 {
+		watcher.idleTimeout = idleTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lines, err := watcher.Start(ctx)
+	if err != nil {
+		t.Fatalf("Failed to start watcher: %v", err)
+	}
+
+	t.Cleanup(func() {
+		cancel()
+		for range lines {
+		}
+	})
+
+	return watcher, lines
+}
+
+func appendLine(t *testing.T, path, line string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receiveLine(t *testing.T, lines <-chan SourceLine) SourceLine {
+	t.Helper()
+
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for line")
+		return SourceLine{}
+	}
+}
+
+func waitForOpenFiles(t *testing.T, watcher *DirectoryWatcher, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(watcher.OpenFiles()) == want {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Expected %d open files, got %v", want, watcher.OpenFiles())
+}
+
+// TestDirectoryWatcher_AttachesNewFiles tests include/exclude matching on create
+func TestDirectoryWatcher_AttachesNewFiles(t *testing.T) {
+	watcher, lines := startTestDirectoryWatcher(t, config.WatchConfig{
+		Include: []string{"*.log"},
+		Exclude: []string{"debug-*"},
+	}, 0)
+
+	ignored := filepath.Join(watcher.config.Dir, "notes.txt")
+	excluded := filepath.Join(watcher.config.Dir, "debug-1.log")
+	included := filepath.Join(watcher.config.Dir, "app-2026-10-16.log")
+
+	appendLine(t, ignored, "ignored")
+	appendLine(t, excluded, "excluded")
+	appendLine(t, included, "first")
+
+	line := receiveLine(t, lines)
+	if line.Path != included || line.Line != "first" {
+		t.Errorf("Unexpected line: %+v", line)
+	}
+
+	waitForOpenFiles(t, watcher, 1)
+}
+
+// TestDirectoryWatcher_ReleasesIdleFiles tests idle release and resume at offset
+func TestDirectoryWatcher_ReleasesIdleFiles(t *testing.T) {
+	watcher, lines := startTestDirectoryWatcher(t, config.WatchConfig{}, 200*time.Millisecond)
+
+	path := filepath.Join(watcher.config.Dir, "worker-1234.log")
+	appendLine(t, path, "one")
+	receiveLine(t, lines)
+
+	waitForOpenFiles(t, watcher, 0)
+
+	appendLine(t, path, "two")
+	line := receiveLine(t, lines)
+	if line.Line != "two" {
+		t.Errorf("Expected to resume after released offset, got %q", line.Line)
+	}
+}
+
+// TestDirectoryWatcher_ReleasesPartialLine tests that a line left unfinished
+// when a file is released is delivered whole after it is re-attached
+func TestDirectoryWatcher_ReleasesPartialLine(t *testing.T) {
+	watcher, lines := startTestDirectoryWatcher(t, config.WatchConfig{}, 200*time.Millisecond)
+
+	path := filepath.Join(watcher.config.Dir, "worker-1234.log")
+	if err := os.WriteFile(path, []byte("one\ntw"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	receiveLine(t, lines)
+
+	waitForOpenFiles(t, watcher, 0)
+
+	appendLine(t, path, "o")
+	line := receiveLine(t, lines)
+	if line.Line != "two" {
+		t.Errorf("Expected the partial line read again in full, got %q", line.Line)
+	}
+}
+
+// TestDirectoryWatcher_MaxOpenFiles tests the concurrent open file cap
+func TestDirectoryWatcher_MaxOpenFiles(t *testing.T) {
+	watcher, lines := startTestDirectoryWatcher(t, config.WatchConfig{MaxOpenFiles: 1}, 0)
+
+	first := filepath.Join(watcher.config.Dir, "a.log")
+	second := filepath.Join(watcher.config.Dir, "b.log")
+
+	appendLine(t, first, "a")
+	receiveLine(t, lines)
+	appendLine(t, second, "b")
+	line := receiveLine(t, lines)
+	if line.Path != second {
+		t.Errorf("Expected line from %s, got %+v", second, line)
+	}
+
+	open := watcher.OpenFiles()
+	if len(open) != 1 || open[0] != second {
+		t.Errorf("Expected only the newest file open, got %v", open)
+	}
+}
+
+// TestDirectoryWatcher_SharesWatcher tests that attached files are tailed
+// without an fsnotify watcher per file
+func TestDirectoryWatcher_SharesWatcher(t *testing.T) {
+	watcher, lines := startTestDirectoryWatcher(t, config.WatchConfig{}, 0)
+
+	for _, name := range []string{"a.log", "b.log", "c.log"} {
+		appendLine(t, filepath.Join(watcher.config.Dir, name), name)
+		receiveLine(t, lines)
+	}
+	waitForOpenFiles(t, watcher, 3)
+
+	watcher.mu.Lock()
+	for path, wf := range watcher.files {
+		wf.tailer.mu.RLock()
+		if wf.tailer.watcher != nil {
+			t.Errorf("Expected %s to use the directory watcher, got its own", path)
+		}
+		wf.tailer.mu.RUnlock()
+	}
+	watcher.mu.Unlock()
+
+	path := filepath.Join(watcher.config.Dir, "b.log")
+	appendLine(t, path, "second")
+	line := receiveLine(t, lines)
+	if line.Path != path || line.Line != "second" {
+		t.Errorf("Unexpected line: %+v", line)
+	}
+}
+
+// TestLogStream_ParseLine tests log and parse error messages
+func TestLogStream_ParseLine(t *testing.T) {
+	ls := NewDirectoryLogStream(config.WatchConfig{Dir: t.TempDir()}, "json", &parser.JSONParser{})
+
+	message := ls.parseLine(`{"level":"info","message":"ok"}`, "/var/log/app/api.log")
+	entry, ok := message.LogEntry()
+	if !ok || entry.Source != "api.log" || message.Source != "api.log" {
+		t.Errorf("Expected log message from api.log, got %+v", message)
+	}
+
+	message = ls.parseLine("not json", "/var/log/app/api.log")
+	parseErr, ok := message.Payload.(models.ParseError)
+	if message.Kind != models.MessageKindParseError || !ok {
+		t.Fatalf("Expected parse error message, got %+v", message)
+	}
+	if parseErr.Line != "not json" || parseErr.Format != "json" || parseErr.Source != "api.log" {
+		t.Errorf("Unexpected parse error: %+v", parseErr)
+	}
+}
+
+// TestLogStream_ReportStats tests per-file counters and parse error samples
+func TestLogStream_ReportStats(t *testing.T) {
+	ls := NewDirectoryLogStream(config.WatchConfig{Dir: t.TempDir()}, "json", &parser.JSONParser{})
+
+	ls.parseLine(`{"message":"ok"}`, "/var/log/app/api.log")
+	ls.parseLine(`{"message":"ok"}`, "/var/log/app/web.log")
+	for i := 0; i < models.MaxParseErrorSamples; i++ {
+		ls.parseLine("bad", "/var/log/app/api.log")
+	}
+	long := strings.Repeat("x", 2*maxSampleLength)
+	ls.parseLine(long, "/var/log/app/api.log")
+
+	stats := models.NewPipelineStats()
+	ls.ReportStats(stats)
+
+	api := stats.Sources["api.log"]
+	if api.Lines != 7 || api.ParseErrors != 6 {
+		t.Errorf("Expected 7 lines and 6 parse errors for api.log, got %+v", api)
+	}
+	wantBytes := uint64(len(`{"message":"ok"}`)+1) + uint64(models.MaxParseErrorSamples*len("bad\n")) + uint64(len(long)+1)
+	if api.Bytes != wantBytes {
+		t.Errorf("Expected %d bytes for api.log, got %d", wantBytes, api.Bytes)
+	}
+	if web := stats.Sources["web.log"]; web.Lines != 1 || web.ParseErrors != 0 {
+		t.Errorf("Unexpected web.log stats: %+v", web)
+	}
+
+	parseErrors := stats.ParseErrors["json"]
+	if parseErrors.Count != 6 || len(parseErrors.Samples) != models.MaxParseErrorSamples {
+		t.Fatalf("Expected 6 json parse errors with %d samples, got %+v", models.MaxParseErrorSamples, parseErrors)
+	}
+	if last := parseErrors.Samples[len(parseErrors.Samples)-1]; len(last) != maxSampleLength {
+		t.Errorf("Expected the newest sample truncated to %d bytes, got %d", maxSampleLength, len(last))
+	}
+}
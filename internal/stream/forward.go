@@ -0,0 +1,506 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/internal/parser"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// eventTimeExtType is the msgpack extension type Fluent uses for EventTime
+const eventTimeExtType = 0
+
+const (
+	// defaultForwardMaxMessageBytes applies when the config leaves
+	// max_message_bytes unset
+	defaultForwardMaxMessageBytes = 16 << 20
+
+	// minForwardEntryBytes is the smallest encoded [time, record] pair, used
+	// to reject entry counts a message of the maximum size can't hold
+	minForwardEntryBytes = 3
+
+	// forwardEntriesPrealloc caps the entries allocated up front from a
+	// declared count
+	forwardEntriesPrealloc = 1024
+)
+
+// errForwardMessageTooLarge is returned for a message, or decompressed
+// PackedForward payload, above max_message_bytes
+var errForwardMessageTooLarge = errors.New("message exceeds max_message_bytes")
+
+// ForwardListener receives logs over the Fluent Forward protocol (msgpack over
+// TCP), so Fluent Bit or Fluentd can use logflow as a "forward" output.
+// Message, Forward and PackedForward (optionally gzip-compressed) modes are
+// supported, and chunks are acknowledged when the sender requests it.
+type ForwardListener struct {
+	config   config.ForwardConfig
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+
+	records   uint64 // Accessed atomically
+	bytes     uint64
+	mapErrors uint64
+}
+
+// countingReader counts bytes read from a connection
+type countingReader struct {
+	reader io.Reader
+	count  *uint64
+}
+
+func (cr countingReader) Read(p []byte) (int, error) {
+	n, err := cr.reader.Read(p)
+	atomic.AddUint64(cr.count, uint64(n))
+	return n, err
+}
+
+// limitReader fails once more than limit bytes have been read since the last
+// reset, so a message can't make the decoder read or allocate without bound
+type limitReader struct {
+	reader    io.Reader
+	limit     int64
+	remaining int64
+}
+
+func newLimitReader(reader io.Reader, limit int64) *limitReader {
+	return &limitReader{reader: reader, limit: limit, remaining: limit}
+}
+
+func (lr *limitReader) Read(p []byte) (int, error) {
+	if lr.remaining <= 0 {
+		return 0, errForwardMessageTooLarge
+	}
+	if int64(len(p)) > lr.remaining {
+		p = p[:lr.remaining]
+	}
+	n, err := lr.reader.Read(p)
+	lr.remaining -= int64(n)
+	return n, err
+}
+
+// reset starts counting a new message
+func (lr *limitReader) reset() {
+	lr.remaining = lr.limit
+}
+
+// forwardEntry is one [time, record] pair from a Forward or PackedForward message
+type forwardEntry struct {
+	time   time.Time
+	record map[string]interface{}
+}
+
+// NewForwardListener creates a new Fluent Forward listener
+func NewForwardListener(cfg config.ForwardConfig) *ForwardListener {
+	// Default values if not specified
+	if cfg.MaxMessageBytes <= 0 {
+		cfg.MaxMessageBytes = defaultForwardMaxMessageBytes
+	}
+
+	return &ForwardListener{
+		config: cfg,
+		conns:  make(map[net.Conn]struct{}),
+	}
+}
+
+// Listen binds the TCP listener. Start calls it if it has not been called yet.
+func (fl *ForwardListener) Listen() error {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	if fl.listener != nil {
+		return nil
+	}
+
+	addr := net.JoinHostPort(fl.config.Host, fmt.Sprintf("%d", fl.config.Port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	fl.listener = listener
+	return nil
+}
+
+// Addr returns the bound listener address, or nil before Listen
+func (fl *ForwardListener) Addr() net.Addr {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	if fl.listener == nil {
+		return nil
+	}
+	return fl.listener.Addr()
+}
+
+// Start accepts Fluent Forward connections and sends parsed entries to output
+// until the context is cancelled
+func (fl *ForwardListener) Start(ctx context.Context, output chan<- models.Message) {
+	if err := fl.Listen(); err != nil {
+		log.Printf("Failed to start forward listener: %v", err)
+		return
+	}
+
+	log.Printf("Fluent Forward listener on %s", fl.Addr())
+
+	go func() {
+		<-ctx.Done()
+		fl.mu.Lock()
+		fl.listener.Close()
+		for conn := range fl.conns {
+			conn.Close()
+		}
+		fl.mu.Unlock()
+	}()
+
+	for {
+		conn, err := fl.listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Forward listener accept error: %v", err)
+			}
+			break
+		}
+
+		fl.mu.Lock()
+		fl.conns[conn] = struct{}{}
+		fl.mu.Unlock()
+
+		fl.wg.Add(1)
+		go fl.handleConn(ctx, conn, output)
+	}
+
+	fl.wg.Wait()
+}
+
+// ReportStats adds the records and bytes received to a pipeline stats
+// snapshot under the "forward" source
+func (fl *ForwardListener) ReportStats(stats *models.PipelineStats) {
+	stats.AddSource("forward", models.SourceStats{
+		Lines:       atomic.LoadUint64(&fl.records),
+		Bytes:       atomic.LoadUint64(&fl.bytes),
+		ParseErrors: atomic.LoadUint64(&fl.mapErrors),
+	})
+}
+
+// handleConn decodes messages from a single connection until it is closed
+func (fl *ForwardListener) handleConn(ctx context.Context, conn net.Conn, output chan<- models.Message) {
+	defer func() {
+		conn.Close()
+		fl.mu.Lock()
+		delete(fl.conns, conn)
+		fl.mu.Unlock()
+		fl.wg.Done()
+	}()
+
+	// Read-ahead of the next message counts towards the current one, so the
+	// limit is approximate by up to the buffer size
+	limited := newLimitReader(countingReader{reader: conn, count: &fl.bytes}, fl.config.MaxMessageBytes)
+	decoder := msgpack.NewDecoder(bufio.NewReader(limited))
+	encoder := msgpack.NewEncoder(conn)
+
+	for {
+		limited.reset()
+		tag, entries, options, err := decodeForwardMessage(decoder, fl.config.MaxMessageBytes)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				log.Printf("Forward protocol error from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		for _, fe := range entries {
+			atomic.AddUint64(&fl.records, 1)
+			entry, err := forwardRecordToEntry(tag, fe)
+			if err != nil {
+				atomic.AddUint64(&fl.mapErrors, 1)
+				log.Printf("Failed to map forward record: %v", err)
+				continue
+			}
+
+			select {
+			case output <- models.NewLogMessage(entry):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// Acknowledge only after every entry has been handed to the pipeline
+		if chunk, ok := options["chunk"]; ok {
+			if err := encoder.Encode(map[string]interface{}{"ack": chunk}); err != nil {
+				log.Printf("Failed to send forward ack: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// decodeForwardMessage reads one message in any of the supported modes:
+//
+//	Message:       [tag, time, record, option?]
+//	Forward:       [tag, [[time, record], ...], option?]
+//	PackedForward: [tag, bin(concatenated [time, record]), option?]
+//
+// maxBytes bounds the entries declared by a Forward message and the
+// decompressed size of a PackedForward payload.
+func decodeForwardMessage(decoder *msgpack.Decoder, maxBytes int64) (string, []forwardEntry, map[string]interface{}, error) {
+	arrayLen, err := decoder.DecodeArrayLen()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if arrayLen < 2 || arrayLen > 4 {
+		return "", nil, nil, fmt.Errorf("unexpected message length %d", arrayLen)
+	}
+
+	tag, err := decoder.DecodeString()
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("invalid tag: %w", err)
+	}
+
+	code, err := decoder.PeekCode()
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	var entries []forwardEntry
+	remaining := arrayLen - 2
+
+	switch {
+	case msgpcode.IsFixedArray(code) || code == msgpcode.Array16 || code == msgpcode.Array32:
+		// Forward mode
+		entries, err = decodeForwardEntries(decoder, maxBytes)
+
+	case msgpcode.IsBin(code) || msgpcode.IsString(code):
+		// PackedForward mode; the payload may be compressed, which is only
+		// known once the option map has been read
+		var packed []byte
+		if packed, err = decoder.DecodeBytes(); err != nil {
+			break
+		}
+		options, optErr := decodeForwardOptions(decoder, remaining)
+		if optErr != nil {
+			return "", nil, nil, optErr
+		}
+		entries, err = decodePackedEntries(packed, options["compressed"] == "gzip", maxBytes)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		return tag, entries, options, nil
+
+	default:
+		// Message mode
+		if arrayLen < 3 {
+			return "", nil, nil, errors.New("message mode requires time and record")
+		}
+		var fe forwardEntry
+		if fe, err = decodeTimeAndRecord(decoder); err == nil {
+			entries = []forwardEntry{fe}
+		}
+		remaining--
+	}
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	options, err := decodeForwardOptions(decoder, remaining)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return tag, entries, options, nil
+}
+
+// decodeForwardOptions reads the optional trailing option map
+func decodeForwardOptions(decoder *msgpack.Decoder, remaining int) (map[string]interface{}, error) {
+	if remaining <= 0 {
+		return nil, nil
+	}
+
+	code, err := decoder.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+	if code == msgpcode.Nil {
+		return nil, decoder.DecodeNil()
+	}
+
+	options, err := decoder.DecodeMap()
+	if err != nil {
+		return nil, fmt.Errorf("invalid option: %w", err)
+	}
+	return options, nil
+}
+
+// decodeForwardEntries reads an array of [time, record] pairs
+func decodeForwardEntries(decoder *msgpack.Decoder, maxBytes int64) ([]forwardEntry, error) {
+	count, err := decoder.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	if int64(count) > maxBytes/minForwardEntryBytes {
+		return nil, fmt.Errorf("%d entries: %w", count, errForwardMessageTooLarge)
+	}
+
+	entries := make([]forwardEntry, 0, min(count, forwardEntriesPrealloc))
+	for i := 0; i < count; i++ {
+		if _, err := decoder.DecodeArrayLen(); err != nil {
+			return nil, err
+		}
+		fe, err := decodeTimeAndRecord(decoder)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fe)
+	}
+	return entries, nil
+}
+
+// decodePackedEntries reads a stream of concatenated [time, record] pairs
+// of at most maxBytes once decompressed
+func decodePackedEntries(packed []byte, compressed bool, maxBytes int64) ([]forwardEntry, error) {
+	var reader io.Reader = bytes.NewReader(packed)
+	if compressed {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compressed payload: %w", err)
+		}
+		defer gz.Close()
+		reader = newLimitReader(gz, maxBytes)
+	}
+
+	decoder := msgpack.NewDecoder(reader)
+	var entries []forwardEntry
+	for {
+		if _, err := decoder.DecodeArrayLen(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			return nil, err
+		}
+		fe, err := decodeTimeAndRecord(decoder)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fe)
+	}
+}
+
+func decodeTimeAndRecord(decoder *msgpack.Decoder) (forwardEntry, error) {
+	eventTime, err := decodeEventTime(decoder)
+	if err != nil {
+		return forwardEntry{}, err
+	}
+
+	record, err := decoder.DecodeMap()
+	if err != nil {
+		return forwardEntry{}, fmt.Errorf("invalid record: %w", err)
+	}
+
+	return forwardEntry{time: eventTime, record: record}, nil
+}
+
+// decodeEventTime reads either an integer/float Unix time or the EventTime
+// extension (big-endian uint32 seconds followed by uint32 nanoseconds)
+func decodeEventTime(decoder *msgpack.Decoder) (time.Time, error) {
+	code, err := decoder.PeekCode()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch {
+	case msgpcode.IsExt(code):
+		extID, extLen, err := decoder.DecodeExtHeader()
+		if err != nil {
+			return time.Time{}, err
+		}
+		// Checked before reading, so the declared length is never allocated
+		if extID != eventTimeExtType || extLen != 8 {
+			return time.Time{}, fmt.Errorf("unsupported time extension %d (length %d)", extID, extLen)
+		}
+		var buf [8]byte
+		if err := decoder.ReadFull(buf[:]); err != nil {
+			return time.Time{}, err
+		}
+		sec := binary.BigEndian.Uint32(buf[:4])
+		nsec := binary.BigEndian.Uint32(buf[4:])
+		return time.Unix(int64(sec), int64(nsec)), nil
+
+	case code == msgpcode.Float || code == msgpcode.Double:
+		seconds, err := decoder.DecodeFloat64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+
+	default:
+		seconds, err := decoder.DecodeInt64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid event time: %w", err)
+		}
+		return time.Unix(seconds, 0), nil
+	}
+}
+
+// forwardRecordToEntry maps a record onto a LogEntry with the JSON field rules.
+// The Fluent tag becomes the source and the event time the timestamp when the
+// record does not carry its own.
+func forwardRecordToEntry(tag string, fe forwardEntry) (*models.LogEntry, error) {
+	record := normalizeForwardValue(fe.record).(map[string]interface{})
+
+	// Fluent Bit's tail and docker inputs put the raw line under "log"
+	if _, ok := record["message"]; !ok {
+		if line, ok := record["log"].(string); ok {
+			record["message"] = line
+		}
+	}
+
+	entry, err := parser.ParseFields(record)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = fe.time
+	}
+	if entry.Source == "" {
+		entry.Source = tag
+	}
+
+	return entry, nil
+}
+
+// normalizeForwardValue converts msgpack binary strings to Go strings so the
+// record marshals to JSON the way a JSON log line would look
+func normalizeForwardValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeForwardValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeForwardValue(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
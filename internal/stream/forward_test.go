@@ -0,0 +1,221 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// startTestForwardListener starts a listener on a random local port
+func startTestForwardListener(t *testing.T) (net.Conn, chan models.Message) {
+	t.Helper()
+
+	listener := NewForwardListener(config.ForwardConfig{Enabled: true, Host: "127.0.0.1", Port: 0})
+	if err := listener.Listen(); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	output := make(chan models.Message, 100)
+	done := make(chan struct{})
+	go func() {
+		listener.Start(ctx, output)
+		close(done)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		<-done
+	})
+
+	return conn, output
+}
+
+// encodeEventTime writes a Fluent EventTime extension value
+func encodeEventTime(t *testing.T, encoder *msgpack.Encoder, ts time.Time) {
+	t.Helper()
+
+	if err := encoder.EncodeExtHeader(eventTimeExtType, 8); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint32(buf[:4], uint32(ts.Unix()))
+	binary.BigEndian.PutUint32(buf[4:], uint32(ts.Nanosecond()))
+	encoder.Writer().Write(buf)
+}
+
+func receiveEntry(t *testing.T, output chan models.Message) *models.LogEntry {
+	t.Helper()
+
+	select {
+	case message := <-output:
+		entry, ok := message.LogEntry()
+		if !ok {
+			t.Fatalf("Expected log message, got %s", message.Kind)
+		}
+		return entry
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for log entry")
+		return nil
+	}
+}
+
+// TestForwardListener_MessageMode tests [tag, time, record]
+func TestForwardListener_MessageMode(t *testing.T) {
+	conn, output := startTestForwardListener(t)
+	encoder := msgpack.NewEncoder(conn)
+
+	eventTime := time.Unix(1760600000, 123456789)
+	encoder.EncodeArrayLen(3)
+	encoder.EncodeString("app.access")
+	encodeEventTime(t, encoder, eventTime)
+	encoder.Encode(map[string]interface{}{
+		"log":         "GET /health",
+		"status_code": 503,
+		"level":       "error",
+	})
+
+	entry := receiveEntry(t, output)
+	if entry.Source != "app.access" {
+		t.Errorf("Expected tag as source, got %q", entry.Source)
+	}
+	if !entry.Timestamp.Equal(eventTime) {
+		t.Errorf("Expected timestamp %v, got %v", eventTime, entry.Timestamp)
+	}
+	if entry.Message != "GET /health" {
+		t.Errorf("Expected message from log key, got %q", entry.Message)
+	}
+	if entry.StatusCode != 503 || entry.Level != "error" {
+		t.Errorf("Unexpected entry fields: %+v", entry)
+	}
+}
+
+// TestForwardListener_ForwardModeWithAck tests [tag, [[time, record]...], option]
+func TestForwardListener_ForwardModeWithAck(t *testing.T) {
+	conn, output := startTestForwardListener(t)
+	encoder := msgpack.NewEncoder(conn)
+
+	encoder.EncodeArrayLen(3)
+	encoder.EncodeString("app")
+	encoder.EncodeArrayLen(2)
+	for i := 0; i < 2; i++ {
+		encoder.EncodeArrayLen(2)
+		encoder.EncodeInt(1760600000)
+		encoder.Encode(map[string]interface{}{"message": "hello", "source": "worker"})
+	}
+	encoder.Encode(map[string]interface{}{"chunk": "abc123"})
+
+	for i := 0; i < 2; i++ {
+		entry := receiveEntry(t, output)
+		if entry.Source != "worker" {
+			t.Errorf("Expected record source to win over tag, got %q", entry.Source)
+		}
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var ack map[string]interface{}
+	if err := msgpack.NewDecoder(conn).Decode(&ack); err != nil {
+		t.Fatalf("Failed to read ack: %v", err)
+	}
+	if ack["ack"] != "abc123" {
+		t.Errorf("Expected ack for chunk abc123, got %v", ack)
+	}
+}
+
+// TestForwardListener_CompressedPackedForward tests gzip PackedForward payloads
+func TestForwardListener_CompressedPackedForward(t *testing.T) {
+	conn, output := startTestForwardListener(t)
+
+	var packed bytes.Buffer
+	gz := gzip.NewWriter(&packed)
+	entryEncoder := msgpack.NewEncoder(gz)
+	for _, msg := range []string{"first", "second", "third"} {
+		entryEncoder.EncodeArrayLen(2)
+		encodeEventTime(t, entryEncoder, time.Unix(1760600000, 0))
+		entryEncoder.Encode(map[string]interface{}{"message": msg})
+	}
+	gz.Close()
+
+	encoder := msgpack.NewEncoder(conn)
+	encoder.EncodeArrayLen(3)
+	encoder.EncodeString("app")
+	encoder.EncodeBytes(packed.Bytes())
+	encoder.Encode(map[string]interface{}{"size": 3, "compressed": "gzip"})
+
+	for _, want := range []string{"first", "second", "third"} {
+		entry := receiveEntry(t, output)
+		if entry.Message != want {
+			t.Errorf("Expected message %q, got %q", want, entry.Message)
+		}
+	}
+}
+
+// TestDecodeForwardMessage_Malformed tests that sizes declared by a frame are
+// checked before anything is allocated for them
+func TestDecodeForwardMessage_Malformed(t *testing.T) {
+	const limit = 64 << 10
+
+	var message bytes.Buffer
+	encoder := msgpack.NewEncoder(&message)
+	encoder.EncodeArrayLen(3)
+	encoder.EncodeString("app")
+	encoder.EncodeInt(1760600000)
+	encoder.Encode(map[string]interface{}{"message": strings.Repeat("a", 2*limit)})
+
+	var bomb bytes.Buffer
+	gz := gzip.NewWriter(&bomb)
+	entryEncoder := msgpack.NewEncoder(gz)
+	for i := 0; i < 64; i++ {
+		entryEncoder.EncodeArrayLen(2)
+		entryEncoder.EncodeInt(1760600000)
+		entryEncoder.Encode(map[string]interface{}{"message": strings.Repeat("a", limit/16)})
+	}
+	gz.Close()
+	var packed bytes.Buffer
+	encoder = msgpack.NewEncoder(&packed)
+	encoder.EncodeArrayLen(3)
+	encoder.EncodeString("app")
+	encoder.EncodeBytes(bomb.Bytes())
+	encoder.Encode(map[string]interface{}{"compressed": "gzip"})
+
+	tests := []struct {
+		name     string
+		frame    []byte
+		tooLarge bool
+	}{
+		{"huge entry count", []byte{0x93, 0xa1, 'a', 0xdd, 0xff, 0xff, 0xff, 0xff}, true},
+		{"huge time extension", []byte{0x93, 0xa1, 'a', 0xc9, 0xff, 0xff, 0xff, 0xf0, 0x00}, false},
+		{"huge truncated payload", []byte{0x92, 0xa1, 'a', 0xc6, 0xff, 0xff, 0xff, 0xff, 'x'}, false},
+		{"oversized message", message.Bytes(), true},
+		{"oversized decompressed payload", packed.Bytes(), true},
+	}
+
+	for _, tt := range tests {
+		decoder := msgpack.NewDecoder(bufio.NewReader(newLimitReader(bytes.NewReader(tt.frame), limit)))
+		_, _, _, err := decodeForwardMessage(decoder, limit)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+		if tt.tooLarge && !errors.Is(err, errForwardMessageTooLarge) {
+			t.Errorf("%s: expected the size limit error, got %v", tt.name, err)
+		}
+	}
+}
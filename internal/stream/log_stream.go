@@ -7,10 +7,12 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
 	"github.com/justin4957/logflow-anomaly-detector/internal/parser"
 	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
 )
@@ -21,26 +23,52 @@ type LogStream struct {
 	logFormat string
 	parser    parser.LogParser
 	tailer    FileTailer
+	watcher   *DirectoryWatcher // Set in directory watch mode instead of tailer
+
+	statsMu     sync.Mutex
+	sources     map[string]*models.SourceStats // Keyed by file name
+	parseErrors uint64
+	badLines    []string // Most recent unparseable lines
 }
 
+// maxSampleLength truncates unparseable lines kept as samples
+const maxSampleLength = 512
+
 // FileTailer interface for tailing files
 type FileTailer interface {
 	Start(ctx context.Context, path string) (<-chan string, error)
 	Stop() error
 }
 
-// NewLogStream creates a new log stream
-func NewLogStream(logPath, logFormat string) *LogStream {
+// NewLogStream creates a new log stream. logFormat names the parser's format
+// in parse errors; create the parser with parser.New.
+func NewLogStream(logPath, logFormat string, logParser parser.LogParser) *LogStream {
 	return &LogStream{
 		logPath:   logPath,
 		logFormat: logFormat,
-		parser:    parser.NewParser(logFormat),
+		parser:    logParser,
 		tailer:    NewTailer(),
 	}
 }
 
+// NewDirectoryLogStream creates a log stream that tails every matching file
+// in a directory, attaching to new files as they are created
+func NewDirectoryLogStream(watchCfg config.WatchConfig, logFormat string, logParser parser.LogParser) *LogStream {
+	return &LogStream{
+		logPath:   watchCfg.Dir,
+		logFormat: logFormat,
+		parser:    logParser,
+		watcher:   NewDirectoryWatcher(watchCfg),
+	}
+}
+
 // Start begins streaming and parsing logs
-func (ls *LogStream) Start(ctx context.Context, output chan<- interface{}) {
+func (ls *LogStream) Start(ctx context.Context, output chan<- models.Message) {
+	if ls.watcher != nil {
+		ls.startDirectory(ctx, output)
+		return
+	}
+
 	lineChan, err := ls.tailer.Start(ctx, ls.logPath)
 	if err != nil {
 		log.Printf("Failed to start log tailer: %v", err)
@@ -57,35 +85,176 @@ func (ls *LogStream) Start(ctx context.Context, output chan<- interface{}) {
 				return
 			}
 
-			logEntry, err := ls.parser.Parse(line)
-			if err != nil {
-				log.Printf("Failed to parse log line: %v", err)
-				continue
+			select {
+			case output <- ls.parseLine(line, ls.logPath):
+			case <-ctx.Done():
+				ls.tailer.Stop()
+				return
+			}
+		}
+	}
+}
+
+// startDirectory streams lines from every file attached by the directory watcher
+func (ls *LogStream) startDirectory(ctx context.Context, output chan<- models.Message) {
+	lineChan, err := ls.watcher.Start(ctx)
+	if err != nil {
+		log.Printf("Failed to start directory watcher: %v", err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sourceLine, ok := <-lineChan:
+			if !ok {
+				return
 			}
 
-			output <- logEntry
+			select {
+			case output <- ls.parseLine(sourceLine.Line, sourceLine.Path):
+			case <-ctx.Done():
+				return
+			}
 		}
 	}
 }
 
+// parseLine parses a raw line from path into a log message, or a parse
+// error message when the line doesn't match the format. Entries without a
+// source are tagged with the file name.
+func (ls *LogStream) parseLine(line, path string) models.Message {
+	logEntry, err := ls.parser.Parse(line)
+	ls.recordLine(filepath.Base(path), line, err != nil)
+	if err != nil {
+		return models.NewParseErrorMessage(models.ParseError{
+			Source: filepath.Base(path),
+			Format: ls.logFormat,
+			Line:   line,
+			Error:  err.Error(),
+		})
+	}
+	if logEntry.Source == "" {
+		logEntry.Source = filepath.Base(path)
+	}
+	return models.NewLogMessage(logEntry)
+}
+
+// recordLine updates the throughput and parse error counters
+func (ls *LogStream) recordLine(source, line string, failed bool) {
+	ls.statsMu.Lock()
+	defer ls.statsMu.Unlock()
+
+	if ls.sources == nil {
+		ls.sources = make(map[string]*models.SourceStats)
+	}
+	stats, ok := ls.sources[source]
+	if !ok {
+		stats = &models.SourceStats{}
+		ls.sources[source] = stats
+	}
+	stats.Lines++
+	stats.Bytes += uint64(len(line)) + 1 // Include the newline
+
+	if failed {
+		stats.ParseErrors++
+		ls.parseErrors++
+		if len(line) > maxSampleLength {
+			line = line[:maxSampleLength]
+		}
+		ls.badLines = append(ls.badLines, line)
+		if len(ls.badLines) > models.MaxParseErrorSamples {
+			ls.badLines = ls.badLines[1:]
+		}
+	}
+}
+
+// ReportStats adds per-file throughput, parse errors, tailer lag and dropped
+// lines to a pipeline stats snapshot
+func (ls *LogStream) ReportStats(stats *models.PipelineStats) {
+	lags := make(map[string]int64)
+	var dropped uint64
+	if ls.watcher != nil {
+		for path, lag := range ls.watcher.Lags() {
+			lags[filepath.Base(path)] += lag
+		}
+		dropped = ls.watcher.Dropped()
+	} else if tailer, ok := ls.tailer.(*Tailer); ok {
+		lags[filepath.Base(ls.logPath)] = tailer.Lag()
+		dropped = tailer.Dropped()
+	}
+
+	ls.statsMu.Lock()
+	defer ls.statsMu.Unlock()
+
+	for source, lag := range lags {
+		if _, ok := ls.sources[source]; !ok {
+			stats.AddSource(source, models.SourceStats{LagBytes: lag})
+		}
+	}
+	for source, sourceStats := range ls.sources {
+		snapshot := *sourceStats
+		snapshot.LagBytes = lags[source]
+		stats.AddSource(source, snapshot)
+	}
+
+	samples := append([]string(nil), ls.badLines...)
+	if ls.parseErrors > 0 {
+		stats.AddParseErrors(ls.logFormat, ls.parseErrors, samples)
+	}
+	if dropped > 0 {
+		stats.Dropped["tailer"] += dropped
+	}
+}
+
 // Tailer implements FileTailer for real-time file tailing
 type Tailer struct {
 	watcher    *fsnotify.Watcher
+	events     chan fsnotify.Event // Events from a shared watcher; nil when the tailer creates its own
 	file       *os.File
 	reader     *bufio.Reader
 	lineChan   chan string
 	stopCh     chan struct{}
-	offset     int64
+	offset     int64 // End of the last complete line; excludes incomplete
 	mu         sync.RWMutex
 	path       string
 	incomplete string // Buffer for incomplete lines
+	startAt    int64  // Initial offset; negative means end of file
+	dropped    uint64 // Lines dropped because lineChan was full
 }
 
-// NewTailer creates a new file tailer
+// NewTailer creates a new file tailer that starts at the end of the file
 func NewTailer() *Tailer {
+	return newTailerAt(-1)
+}
+
+// newTailerAt creates a tailer that starts reading at the given offset.
+// A negative offset starts at the end of the file.
+func newTailerAt(offset int64) *Tailer {
 	return &Tailer{
 		lineChan: make(chan string, 100),
 		stopCh:   make(chan struct{}),
+		startAt:  offset,
+	}
+}
+
+// newSharedTailerAt creates a tailer that starts at the given offset and
+// is driven by events passed to notify instead of its own fsnotify watcher,
+// so a directory of files uses a single inotify instance
+func newSharedTailerAt(offset int64) *Tailer {
+	t := newTailerAt(offset)
+	t.events = make(chan fsnotify.Event, 1)
+	return t
+}
+
+// notify passes an event from a shared watcher to the tailer. Events are
+// dropped while one is pending, since a single write event reads all new
+// lines and the periodic read catches anything missed.
+func (t *Tailer) notify(event fsnotify.Event) {
+	select {
+	case t.events <- event:
+	default:
 	}
 }
 
@@ -102,8 +271,12 @@ func (t *Tailer) Start(ctx context.Context, path string) (<-chan string, error)
 	}
 	t.file = file
 
-	// Seek to end of file to start tailing new content
+	// Seek to end of file to start tailing new content, unless a start
+	// offset was requested that is still within the file
 	offset, err := file.Seek(0, io.SeekEnd)
+	if err == nil && t.startAt >= 0 && t.startAt < offset {
+		offset, err = file.Seek(t.startAt, io.SeekStart)
+	}
 	if err != nil {
 		file.Close()
 		return nil, fmt.Errorf("failed to seek file: %w", err)
@@ -111,6 +284,13 @@ func (t *Tailer) Start(ctx context.Context, path string) (<-chan string, error)
 	t.offset = offset
 	t.reader = bufio.NewReader(file)
 
+	// Tailers driven by a shared watcher need no watcher of their own
+	if t.events != nil {
+		log.Printf("Started tailing file: %s", path)
+		go t.tailLoop(ctx, t.events, nil)
+		return t.lineChan, nil
+	}
+
 	// Create fsnotify watcher
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -129,13 +309,15 @@ func (t *Tailer) Start(ctx context.Context, path string) (<-chan string, error)
 	log.Printf("Started tailing file: %s", path)
 
 	// Start the tailing goroutine
-	go t.tailLoop(ctx)
+	go t.tailLoop(ctx, watcher.Events, watcher.Errors)
 
 	return t.lineChan, nil
 }
 
-// tailLoop is the main loop that watches for file changes
-func (t *Tailer) tailLoop(ctx context.Context) {
+// tailLoop is the main loop that watches for file changes. The watcher's
+// channels are passed in because Stop clears t.watcher while the loop may
+// still be running; errors is nil for tailers driven by a shared watcher.
+func (t *Tailer) tailLoop(ctx context.Context, events <-chan fsnotify.Event, errors <-chan error) {
 	defer func() {
 		close(t.lineChan)
 		log.Printf("Tailer loop stopped")
@@ -155,7 +337,7 @@ func (t *Tailer) tailLoop(ctx context.Context) {
 			log.Printf("Stop signal received")
 			return
 
-		case event, ok := <-t.watcher.Events:
+		case event, ok := <-events:
 			if !ok {
 				return
 			}
@@ -182,7 +364,7 @@ func (t *Tailer) tailLoop(ctx context.Context) {
 				}
 			}
 
-		case err, ok := <-t.watcher.Errors:
+		case err, ok := <-errors:
 			if !ok {
 				return
 			}
@@ -195,7 +377,9 @@ func (t *Tailer) tailLoop(ctx context.Context) {
 	}
 }
 
-// readNewLines reads new lines from the file
+// readNewLines reads new lines from the file. A trailing partial line is
+// buffered in t.incomplete and not counted in t.offset until its newline is
+// read, so a tailer restarted at the offset reads the whole line again.
 func (t *Tailer) readNewLines() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -234,10 +418,9 @@ func (t *Tailer) readNewLines() {
 
 		if err != nil {
 			if err == io.EOF {
-				// Save incomplete line for next read
-				if line != "" {
-					t.incomplete = line
-				}
+				// Save incomplete line for next read; it may arrive
+				// over several writes
+				t.incomplete += line
 				break
 			}
 			log.Printf("Error reading file: %v", err)
@@ -250,6 +433,10 @@ func (t *Tailer) readNewLines() {
 			t.incomplete = ""
 		}
 
+		// Update offset, excluding data still buffered in the reader
+		newOffset, _ := t.file.Seek(0, io.SeekCurrent)
+		t.offset = newOffset - int64(t.reader.Buffered())
+
 		// Remove trailing newline
 		if len(line) > 0 && line[len(line)-1] == '\n' {
 			line = line[:len(line)-1]
@@ -265,19 +452,49 @@ func (t *Tailer) readNewLines() {
 			continue
 		}
 
-		// Update offset
-		newOffset, _ := t.file.Seek(0, io.SeekCurrent)
-		t.offset = newOffset
-
 		// Send line to channel (non-blocking)
 		select {
 		case t.lineChan <- line:
 		default:
+			t.dropped++
 			log.Printf("Line channel full, dropping line")
 		}
 	}
 }
 
+// Offset returns the position after the last complete line read. A partial
+// line at the end of the file is not included, so a tailer started at the
+// offset, e.g. when the directory watcher re-attaches a released file,
+// reads it again in full.
+func (t *Tailer) Offset() int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.offset
+}
+
+// Lag returns how many bytes of the file have not been read yet, including
+// a partial last line
+func (t *Tailer) Lag() int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.file == nil {
+		return 0
+	}
+	fileInfo, err := t.file.Stat()
+	if err != nil || fileInfo.Size() < t.offset {
+		return 0
+	}
+	return fileInfo.Size() - t.offset
+}
+
+// Dropped returns how many lines were dropped because the consumer fell behind
+func (t *Tailer) Dropped() uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.dropped
+}
+
 // handleFileRotation handles log rotation scenarios
 func (t *Tailer) handleFileRotation(ctx context.Context) {
 	log.Printf("Handling file rotation for: %s", t.path)
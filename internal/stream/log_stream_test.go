@@ -0,0 +1,115 @@
+package stream
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestTailer_Lag tests that an unfinished line counts as unread lag
+func TestTailer_Lag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("first\npartial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tailer := newTailerAt(0)
+	lines, err := tailer.Start(ctx, path)
+	if err != nil {
+		t.Fatalf("Failed to start tailer: %v", err)
+	}
+	defer tailer.Stop()
+
+	select {
+	case line := <-lines:
+		if line != "first" {
+			t.Fatalf("Expected first line, got %q", line)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timed out waiting for line")
+	}
+
+	if offset := tailer.Offset(); offset != int64(len("first\n")) {
+		t.Errorf("Expected offset %d, got %d", len("first\n"), offset)
+	}
+	if lag := tailer.Lag(); lag != int64(len("partial")) {
+		t.Errorf("Expected lag %d, got %d", len("partial"), lag)
+	}
+	if dropped := tailer.Dropped(); dropped != 0 {
+		t.Errorf("Expected no dropped lines, got %d", dropped)
+	}
+}
+
+// TestTailer_PartialLineHandoff tests that a line written in several parts
+// is delivered whole, and that a tailer started at the offset of a stopped
+// one reads a pending partial line from its start
+func TestTailer_PartialLineHandoff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("first\nhal"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tailer := newTailerAt(0)
+	lines, err := tailer.Start(ctx, path)
+	if err != nil {
+		t.Fatalf("Failed to start tailer: %v", err)
+	}
+	expectTailedLine(t, lines, "first")
+
+	appendPartial(t, path, "f")
+	time.Sleep(300 * time.Millisecond)
+	appendPartial(t, path, " line\n")
+	expectTailedLine(t, lines, "half line")
+
+	appendPartial(t, path, "next par")
+	time.Sleep(300 * time.Millisecond)
+	tailer.Stop()
+
+	offset := tailer.Offset()
+	if want := int64(len("first\nhalf line\n")); offset != want {
+		t.Fatalf("Expected offset %d at the end of the last complete line, got %d", want, offset)
+	}
+
+	appendPartial(t, path, "tial\n")
+	next := newTailerAt(offset)
+	lines, err = next.Start(ctx, path)
+	if err != nil {
+		t.Fatalf("Failed to start tailer: %v", err)
+	}
+	defer next.Stop()
+	expectTailedLine(t, lines, "next partial")
+}
+
+func appendPartial(t *testing.T, path, data string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectTailedLine(t *testing.T, lines <-chan string, want string) {
+	t.Helper()
+
+	select {
+	case line := <-lines:
+		if line != want {
+			t.Fatalf("Expected %q, got %q", want, line)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timed out waiting for %q", want)
+	}
+}
@@ -0,0 +1,250 @@
+// Package drain provides an online log template miner based on Drain (He et
+// al., 2017), used to group log messages that differ only in their variable
+// parts, such as "user <*> failed to log in".
+package drain
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Wildcard replaces the variable tokens of a template
+const Wildcard = "<*>"
+
+// Options configure a Miner
+type Options struct {
+	Depth               int     // Parse tree depth, including the root and token count levels (minimum 3)
+	SimilarityThreshold float64 // Share of matching tokens for a message to join a template (0-1)
+	MaxChildren         int     // Children per tree node before further tokens share a wildcard node
+	MaxTemplates        int     // Templates kept; the least recently seen is evicted for a new one
+}
+
+// DefaultOptions returns the options used for unset fields
+func DefaultOptions() Options {
+	return Options{
+		Depth:               4,
+		SimilarityThreshold: 0.4,
+		MaxChildren:         100,
+		MaxTemplates:        1000,
+	}
+}
+
+// Template is a group of similar messages. Its ID does not change as the
+// template's text generalizes.
+type Template struct {
+	ID    int    `json:"id"`
+	Text  string `json:"text"`
+	Count int    `json:"count"` // Messages matched since the template was created
+}
+
+// cluster is a template's state in the parse tree
+type cluster struct {
+	id       int
+	tokens   []string
+	count    int
+	lastSeen uint64
+	leaf     *node
+}
+
+// node is a parse tree node; leaves hold clusters
+type node struct {
+	children map[string]*node
+	clusters []*cluster
+}
+
+// Miner assigns messages to templates. Messages are routed through a fixed
+// depth tree by token count and leading tokens, then matched against the
+// templates at the leaf by token similarity. A Miner is not safe for
+// concurrent use.
+type Miner struct {
+	options  Options
+	root     *node
+	clusters map[int]*cluster
+	nextID   int
+	seen     uint64 // Messages added, used to order clusters by recency
+}
+
+// New creates an empty miner. Options that are unset or out of range use
+// DefaultOptions.
+func New(options Options) *Miner {
+	defaults := DefaultOptions()
+	if options.Depth < 3 {
+		options.Depth = defaults.Depth
+	}
+	if options.SimilarityThreshold <= 0 || options.SimilarityThreshold > 1 {
+		options.SimilarityThreshold = defaults.SimilarityThreshold
+	}
+	if options.MaxChildren <= 0 {
+		options.MaxChildren = defaults.MaxChildren
+	}
+	if options.MaxTemplates <= 0 {
+		options.MaxTemplates = defaults.MaxTemplates
+	}
+
+	return &Miner{
+		options:  options,
+		root:     &node{children: make(map[string]*node)},
+		clusters: make(map[int]*cluster),
+		nextID:   1,
+	}
+}
+
+// Add assigns a message to a template, creating one when no template is
+// similar enough. The boolean is true for a new template. Empty messages
+// return a zero Template.
+func (m *Miner) Add(message string) (Template, bool) {
+	tokens := tokenize(message)
+	if len(tokens) == 0 {
+		return Template{}, false
+	}
+	m.seen++
+
+	if c := m.match(tokens); c != nil {
+		for i, token := range tokens {
+			if c.tokens[i] != token {
+				c.tokens[i] = Wildcard
+			}
+		}
+		c.count++
+		c.lastSeen = m.seen
+		return c.template(), false
+	}
+
+	if len(m.clusters) >= m.options.MaxTemplates {
+		m.evict()
+	}
+	c := &cluster{id: m.nextID, tokens: tokens, count: 1, lastSeen: m.seen}
+	m.nextID++
+	m.clusters[c.id] = c
+	m.insert(c)
+	return c.template(), true
+}
+
+// Len returns the number of templates
+func (m *Miner) Len() int {
+	return len(m.clusters)
+}
+
+// Template returns a template by ID; the boolean is false once it has been
+// evicted
+func (m *Miner) Template(id int) (Template, bool) {
+	c, ok := m.clusters[id]
+	if !ok {
+		return Template{}, false
+	}
+	return c.template(), true
+}
+
+// match returns the most similar cluster at the message's leaf, if any is
+// above the similarity threshold
+func (m *Miner) match(tokens []string) *cluster {
+	current := m.root.children[strconv.Itoa(len(tokens))]
+	for i := 0; current != nil && i < m.prefixLength(tokens); i++ {
+		next, ok := current.children[tokens[i]]
+		if !ok {
+			next = current.children[Wildcard]
+		}
+		current = next
+	}
+	if current == nil {
+		return nil
+	}
+
+	var best *cluster
+	bestSimilarity := 0.0
+	for _, c := range current.clusters {
+		similarity := similarity(c.tokens, tokens)
+		if similarity > bestSimilarity {
+			best, bestSimilarity = c, similarity
+		}
+	}
+	if bestSimilarity < m.options.SimilarityThreshold {
+		return nil
+	}
+	return best
+}
+
+// insert adds a new cluster to the tree, creating the path to its leaf.
+// Once a node has MaxChildren children, further tokens share its wildcard
+// child.
+func (m *Miner) insert(c *cluster) {
+	current := m.child(m.root, strconv.Itoa(len(c.tokens)))
+	for i := 0; i < m.prefixLength(c.tokens); i++ {
+		token := c.tokens[i]
+		if _, ok := current.children[token]; !ok && len(current.children) >= m.options.MaxChildren {
+			token = Wildcard
+		}
+		current = m.child(current, token)
+	}
+	current.clusters = append(current.clusters, c)
+	c.leaf = current
+}
+
+// child returns the child for a token, creating it if needed
+func (m *Miner) child(parent *node, token string) *node {
+	child, ok := parent.children[token]
+	if !ok {
+		child = &node{children: make(map[string]*node)}
+		parent.children[token] = child
+	}
+	return child
+}
+
+// prefixLength returns how many leading tokens route a message
+func (m *Miner) prefixLength(tokens []string) int {
+	if levels := m.options.Depth - 2; levels < len(tokens) {
+		return levels
+	}
+	return len(tokens)
+}
+
+// evict removes the least recently seen cluster
+func (m *Miner) evict() {
+	var oldest *cluster
+	for _, c := range m.clusters {
+		if oldest == nil || c.lastSeen < oldest.lastSeen {
+			oldest = c
+		}
+	}
+	if oldest == nil {
+		return
+	}
+
+	delete(m.clusters, oldest.id)
+	leaf := oldest.leaf
+	for i, c := range leaf.clusters {
+		if c == oldest {
+			leaf.clusters = append(leaf.clusters[:i], leaf.clusters[i+1:]...)
+			break
+		}
+	}
+}
+
+func (c *cluster) template() Template {
+	return Template{ID: c.id, Text: strings.Join(c.tokens, " "), Count: c.count}
+}
+
+// similarity returns the share of positions where the template has the same
+// token as the message. Masked message tokens match template wildcards.
+func similarity(template, tokens []string) float64 {
+	matching := 0
+	for i, token := range template {
+		if token == tokens[i] {
+			matching++
+		}
+	}
+	return float64(matching) / float64(len(tokens))
+}
+
+// tokenize splits a message on whitespace and replaces tokens containing
+// digits, such as IDs, counts and addresses, with the wildcard
+func tokenize(message string) []string {
+	tokens := strings.Fields(message)
+	for i, token := range tokens {
+		if strings.IndexFunc(token, unicode.IsDigit) >= 0 {
+			tokens[i] = Wildcard
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,78 @@
+package drain
+
+import "testing"
+
+// TestMiner_GroupsMessages tests that messages differing in variable parts
+// share a template while other messages get their own
+func TestMiner_GroupsMessages(t *testing.T) {
+	m := New(DefaultOptions())
+
+	first, created := m.Add("user 42 failed to log in from 10.0.0.1")
+	if !created || first.Text != "user <*> failed to log in from <*>" {
+		t.Fatalf("Expected a new masked template, got %+v (created %v)", first, created)
+	}
+	second, created := m.Add("user 7 failed to log in from 10.0.0.2")
+	if created || second.ID != first.ID || second.Count != 2 {
+		t.Errorf("Expected the same template counted twice, got %+v (created %v)", second, created)
+	}
+
+	other, created := m.Add("connection pool exhausted waiting for database")
+	if !created || other.ID == first.ID {
+		t.Errorf("Expected a separate template, got %+v", other)
+	}
+	if m.Len() != 2 {
+		t.Errorf("Expected 2 templates, got %d", m.Len())
+	}
+	if template, _ := m.Add(""); template.ID != 0 {
+		t.Errorf("Expected no template for an empty message, got %+v", template)
+	}
+}
+
+// TestMiner_Generalizes tests that differing words become wildcards while
+// the template keeps its ID
+func TestMiner_Generalizes(t *testing.T) {
+	m := New(DefaultOptions())
+
+	first, _ := m.Add("cache miss for key session")
+	second, created := m.Add("cache miss for key profile")
+	if created || second.ID != first.ID {
+		t.Fatalf("Expected the same template, got %+v and %+v", first, second)
+	}
+	if second.Text != "cache miss for key <*>" {
+		t.Errorf("Expected generalized template, got %q", second.Text)
+	}
+
+	// Same leading tokens, but only 2 of 5 tokens match
+	strict := New(Options{SimilarityThreshold: 0.5})
+	strict.Add("cache miss for key session")
+	if _, created := strict.Add("cache miss during nightly warmup"); !created {
+		t.Error("Expected a message below the similarity threshold to get a new template")
+	}
+}
+
+// TestMiner_MaxTemplates tests that the least recently seen template is
+// evicted
+func TestMiner_MaxTemplates(t *testing.T) {
+	m := New(Options{MaxTemplates: 2})
+
+	a, _ := m.Add("disk almost full")
+	b, _ := m.Add("queue consumer restarted unexpectedly")
+	m.Add("disk almost full")
+	c, created := m.Add("payment gateway timed out after retries exhausted")
+	if !created {
+		t.Fatal("Expected a new template")
+	}
+
+	if m.Len() != 2 {
+		t.Errorf("Expected 2 templates, got %d", m.Len())
+	}
+	if _, ok := m.Template(b.ID); ok {
+		t.Error("Expected the least recently seen template to be evicted")
+	}
+	if _, ok := m.Template(a.ID); !ok {
+		t.Error("Expected the recently seen template to be kept")
+	}
+	if _, created := m.Add("queue consumer restarted unexpectedly"); !created || c.ID == b.ID {
+		t.Error("Expected the evicted message to get a new template")
+	}
+}
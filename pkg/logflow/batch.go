@@ -0,0 +1,25 @@
+package logflow
+
+import (
+	"github.com/justin4957/logflow-anomaly-detector/internal/analyzer"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// SHESDOptions configures Seasonal Hybrid ESD analysis
+type SHESDOptions = analyzer.SHESDOptions
+
+// DefaultSHESDOptions returns the default Seasonal Hybrid ESD options
+func DefaultSHESDOptions() SHESDOptions {
+	return analyzer.DefaultSHESDOptions()
+}
+
+// AnalyzeSeries runs Seasonal Hybrid ESD over a whole metrics series, such
+// as the snapshots collected with OnMetrics while replaying old logs, and
+// returns every anomaly in time order with the timestamp of its window
+func AnalyzeSeries(series []models.Metrics, options SHESDOptions) ([]models.Anomaly, error) {
+	detector, err := analyzer.NewSHESDDetector(options)
+	if err != nil {
+		return nil, err
+	}
+	return detector.Analyze(series), nil
+}
@@ -0,0 +1,528 @@
+// Package logflow embeds the LogFlow anomaly detection pipeline in other Go
+// programs. A Pipeline reads log entries from sources (or Push), runs them
+// through the configured filter, normalization, enrichment and redaction
+// stages into the anomaly detector, and fans metrics and anomalies out to
+// sinks, callbacks and subscribers.
+package logflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/analyzer"
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/internal/enrich"
+	"github.com/justin4957/logflow-anomaly-detector/internal/filter"
+	"github.com/justin4957/logflow-anomaly-detector/internal/normalize"
+	"github.com/justin4957/logflow-anomaly-detector/internal/parser"
+	"github.com/justin4957/logflow-anomaly-detector/internal/redact"
+	"github.com/justin4957/logflow-anomaly-detector/internal/registry"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// Config is the pipeline configuration, the same structure the CLI loads
+// from YAML
+type Config = config.Config
+
+// DefaultConfig returns the default configuration
+func DefaultConfig() *Config {
+	return config.DefaultConfig()
+}
+
+// LoadConfig loads configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	return config.LoadConfig(path)
+}
+
+const (
+	// channelBuffer is the capacity of the channels between stages
+	channelBuffer = 1000
+
+	// statsInterval is how often a stats message is sent to sinks and
+	// subscribers
+	statsInterval = 5 * time.Second
+)
+
+// ErrNotRunning is returned by Push when the pipeline isn't running
+var ErrNotRunning = errors.New("logflow: pipeline is not running")
+
+// Source produces messages, usually log entries, until ctx is cancelled
+type Source interface {
+	Start(ctx context.Context, output chan<- models.Message)
+}
+
+// Stage transforms messages between the sources and the detector. Stages
+// must pass through messages they don't handle.
+type Stage interface {
+	Start(ctx context.Context, input <-chan models.Message, output chan<- models.Message)
+}
+
+// Sink consumes the detector's output: metrics, anomalies and any other
+// messages passed through the pipeline
+type Sink interface {
+	Start(ctx context.Context, input <-chan models.Message)
+}
+
+// StatsReporter is implemented by sources, stages and sinks that contribute
+// counters to the pipeline's self-observability stats. ReportStats is called
+// from the stats goroutine and must be safe alongside Start.
+type StatsReporter interface {
+	ReportStats(stats *models.PipelineStats)
+}
+
+// Option customizes a Pipeline
+type Option func(*Pipeline)
+
+// WithSource adds a source. Entries can also be pushed directly with Push.
+func WithSource(source Source) Option {
+	return func(p *Pipeline) { p.sources = append(p.sources, source) }
+}
+
+// WithStage adds a stage after the configured ones, just before the detector
+func WithStage(stage Stage) Option {
+	return func(p *Pipeline) { p.extraStages = append(p.extraStages, stage) }
+}
+
+// WithSink adds a sink that receives every detector output message
+func WithSink(sink Sink) Option {
+	return func(p *Pipeline) { p.sinks = append(p.sinks, sink) }
+}
+
+// WithAlgorithm adds a detection algorithm that runs alongside the
+// configured one
+func WithAlgorithm(algo DetectionAlgorithm) Option {
+	return func(p *Pipeline) { p.algorithms = append(p.algorithms, algo) }
+}
+
+// OnMetrics registers a callback for each metrics snapshot. Callbacks run on
+// the pipeline's dispatch goroutine and should return quickly.
+func OnMetrics(fn func(*models.Metrics)) Option {
+	return func(p *Pipeline) { p.onMetrics = append(p.onMetrics, fn) }
+}
+
+// OnAnomaly registers a callback for each detected anomaly. With incidents
+// enabled it is called with the latest anomaly of each incident transition.
+func OnAnomaly(fn func(models.Anomaly)) Option {
+	return func(p *Pipeline) { p.onAnomaly = append(p.onAnomaly, fn) }
+}
+
+// OnIncident registers a callback for each incident transition
+func OnIncident(fn func(models.Incident)) Option {
+	return func(p *Pipeline) { p.onIncident = append(p.onIncident, fn) }
+}
+
+// Pipeline wires sources, stages, the anomaly detector and sinks together
+type Pipeline struct {
+	cfg         *Config
+	parser      parser.LogParser
+	builders    []func() // Options that need the parser, run by New
+	sources     []Source
+	stages      []Stage
+	stageNames  []string
+	extraStages []Stage
+	redactor    *redact.Redactor // Scrubs parse error samples in stats
+	algorithms  []DetectionAlgorithm
+	detector    *analyzer.AnomalyDetector
+	sinks       []Sink
+	closers     []io.Closer
+
+	onMetrics   []func(*models.Metrics)
+	onAnomaly   []func(models.Anomaly)
+	onIncident  []func(models.Incident)
+	subscribers []chan models.Message
+
+	input chan models.Message
+
+	mu       sync.RWMutex
+	running  bool
+	started  time.Time
+	channels []namedChannel
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// namedChannel is a pipeline channel whose depth is reported in stats
+type namedChannel struct {
+	name string
+	ch   chan models.Message
+}
+
+// New builds a pipeline from configuration. Stages are added for the
+// enabled features in this order: filter, path normalization, GeoIP and
+// user-agent enrichment, redaction.
+func New(cfg *Config, opts ...Option) (*Pipeline, error) {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	p := &Pipeline{
+		cfg:   cfg,
+		input: make(chan models.Message, channelBuffer),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	logParser, err := parser.New(cfg.LogFormat, registry.NodeDecoder(cfg.LogFormatOptions))
+	if err != nil {
+		return nil, fmt.Errorf("log_format: %w", err)
+	}
+	p.parser = logParser
+	for _, build := range p.builders {
+		build()
+	}
+
+	if err := p.buildSinks(); err != nil {
+		return nil, err
+	}
+
+	if err := p.buildStages(); err != nil {
+		p.closeResources()
+		return nil, err
+	}
+	for i, stage := range p.extraStages {
+		p.addStage(fmt.Sprintf("stage%d", i), stage)
+	}
+
+	p.detector, err = analyzer.NewAnomalyDetector(cfg.DetectorConfig)
+	if err != nil {
+		p.closeResources()
+		return nil, fmt.Errorf("detector: %w", err)
+	}
+	if cfg.GeoIPConfig.Enabled {
+		p.detector.AddAlgorithm(analyzer.NewCountryShiftDetector(cfg.GeoIPConfig.ShiftThreshold, cfg.GeoIPConfig.MinRequests))
+		p.detector.AddAlgorithm(analyzer.NewASNShiftDetector(cfg.GeoIPConfig.ShiftThreshold, cfg.GeoIPConfig.MinRequests))
+	}
+	if cfg.UserAgentConfig.Enabled {
+		p.detector.AddAlgorithm(analyzer.NewBotSurgeDetector(cfg.DetectorConfig.SensitivityLevel, cfg.UserAgentConfig.SurgeThreshold, cfg.UserAgentConfig.MinRequests))
+	}
+	for _, algo := range p.algorithms {
+		p.detector.AddAlgorithm(algo)
+	}
+
+	return p, nil
+}
+
+// buildStages creates the stages enabled in the configuration
+func (p *Pipeline) buildStages() error {
+	cfg := p.cfg
+
+	filterCfg := cfg.FilterConfig
+	if len(filterCfg.Include) > 0 || len(filterCfg.Exclude) > 0 || (filterCfg.SampleRate > 0 && filterCfg.SampleRate < 1) {
+		f, err := filter.NewFilter(filterCfg)
+		if err != nil {
+			return fmt.Errorf("filter: %w", err)
+		}
+		p.addStage("filter", f)
+	}
+
+	if cfg.NormalizeConfig.Enabled {
+		n, err := normalize.NewPathNormalizer(cfg.NormalizeConfig)
+		if err != nil {
+			return fmt.Errorf("normalize: %w", err)
+		}
+		p.addStage("normalize", n)
+	}
+
+	// Enrichment reads the IP and user agent, so it runs before redaction
+	if cfg.GeoIPConfig.Enabled {
+		g, err := enrich.NewGeoIPEnricher(cfg.GeoIPConfig)
+		if err != nil {
+			return fmt.Errorf("geoip: %w", err)
+		}
+		p.addStage("geoip", g)
+		p.closers = append(p.closers, g)
+	}
+
+	if cfg.UserAgentConfig.Enabled {
+		u, err := enrich.NewUserAgentEnricher(cfg.UserAgentConfig)
+		if err != nil {
+			return fmt.Errorf("user_agent: %w", err)
+		}
+		p.addStage("user_agent", u)
+	}
+
+	if cfg.RedactConfig.Enabled {
+		r, err := redact.NewRedactor(cfg.RedactConfig)
+		if err != nil {
+			return fmt.Errorf("redact: %w", err)
+		}
+		p.addStage("redact", r)
+		p.redactor = r
+	}
+
+	return nil
+}
+
+func (p *Pipeline) addStage(name string, stage Stage) {
+	p.stages = append(p.stages, stage)
+	p.stageNames = append(p.stageNames, name)
+}
+
+// buildSinks creates the sinks listed in the configuration
+func (p *Pipeline) buildSinks() error {
+	for i, sinkCfg := range p.cfg.Sinks {
+		sink, err := NewSink(sinkCfg.Type, p.cfg, registry.NodeDecoder(sinkCfg.Options))
+		if err != nil {
+			return fmt.Errorf("sinks[%d]: %w", i, err)
+		}
+		p.sinks = append(p.sinks, sink)
+		if closer, ok := sink.(io.Closer); ok {
+			p.closers = append(p.closers, closer)
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving every detector output message.
+// It must be called before Start and is closed by Stop. Sends block, so a
+// subscriber that stops reading stalls the pipeline.
+func (p *Pipeline) Subscribe(buffer int) <-chan models.Message {
+	ch := make(chan models.Message, buffer)
+	p.subscribers = append(p.subscribers, ch)
+	return ch
+}
+
+// Input returns the channel sources write to, for components such as the
+// dashboard's ingest endpoint that feed the pipeline themselves
+func (p *Pipeline) Input() chan<- models.Message {
+	return p.input
+}
+
+// Start launches the sources, stages, detector and sinks. It returns
+// immediately; call Stop to shut the pipeline down.
+func (p *Pipeline) Start(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return errors.New("logflow: pipeline already started")
+	}
+	if p.ctx != nil {
+		return errors.New("logflow: pipeline cannot be restarted")
+	}
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.running = true
+	p.started = time.Now()
+	p.channels = []namedChannel{{name: "input", ch: p.input}}
+
+	for _, source := range p.sources {
+		source := source
+		p.goRun(func() { source.Start(p.ctx, p.input) })
+	}
+
+	var current <-chan models.Message = p.input
+	for i, stage := range p.stages {
+		stage := stage
+		in := current
+		out := make(chan models.Message, channelBuffer)
+		p.channels = append(p.channels, namedChannel{name: p.stageNames[i], ch: out})
+		p.goRun(func() { stage.Start(p.ctx, in, out) })
+		current = out
+	}
+
+	detectorOut := make(chan models.Message, channelBuffer)
+	p.channels = append(p.channels, namedChannel{name: "detector", ch: detectorOut})
+	detectorIn := current
+	p.goRun(func() { p.detector.Start(p.ctx, detectorIn, detectorOut) })
+
+	sinkInputs := make([]chan models.Message, len(p.sinks))
+	for i, sink := range p.sinks {
+		sink := sink
+		sinkInputs[i] = make(chan models.Message, channelBuffer)
+		in := sinkInputs[i]
+		p.channels = append(p.channels, namedChannel{name: fmt.Sprintf("sink%d", i), ch: in})
+		p.goRun(func() { sink.Start(p.ctx, in) })
+	}
+
+	p.goRun(func() { p.dispatch(detectorOut, sinkInputs) })
+
+	return nil
+}
+
+// Run starts the pipeline and blocks until ctx is cancelled, then stops it
+func (p *Pipeline) Run(ctx context.Context) error {
+	if err := p.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	p.Stop()
+	return nil
+}
+
+// Push sends a log entry into the pipeline, blocking while it is full
+func (p *Pipeline) Push(ctx context.Context, entry *models.LogEntry) error {
+	p.mu.RLock()
+	running, pipelineCtx := p.running, p.ctx
+	p.mu.RUnlock()
+	if !running {
+		return ErrNotRunning
+	}
+
+	select {
+	case p.input <- models.NewLogMessage(entry):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-pipelineCtx.Done():
+		return ErrNotRunning
+	}
+}
+
+// Stats returns a snapshot of the pipeline's own throughput and health:
+// lines and bytes read per source, parse failures per format with sample
+// lines, stage counters, dropped entries, channel depths and detector
+// latency. Samples are redacted when redaction is enabled.
+func (p *Pipeline) Stats() models.PipelineStats {
+	stats := models.NewPipelineStats()
+	for _, reporter := range p.reporters() {
+		reporter.ReportStats(stats)
+	}
+
+	p.mu.RLock()
+	if !p.started.IsZero() {
+		stats.UptimeSeconds = time.Since(p.started).Seconds()
+	}
+	for _, c := range p.channels {
+		stats.Channels = append(stats.Channels, models.ChannelStats{Name: c.name, Depth: len(c.ch), Capacity: cap(c.ch)})
+	}
+	p.mu.RUnlock()
+
+	if p.redactor != nil {
+		for _, parseErrors := range stats.ParseErrors {
+			for i, sample := range parseErrors.Samples {
+				parseErrors.Samples[i] = p.redactor.RedactString(sample)
+			}
+		}
+	}
+
+	return *stats
+}
+
+// reporters returns every component that contributes to Stats
+func (p *Pipeline) reporters() []StatsReporter {
+	var reporters []StatsReporter
+	for _, source := range p.sources {
+		if reporter, ok := source.(StatsReporter); ok {
+			reporters = append(reporters, reporter)
+		}
+	}
+	for _, stage := range p.stages {
+		if reporter, ok := stage.(StatsReporter); ok {
+			reporters = append(reporters, reporter)
+		}
+	}
+	if p.detector != nil {
+		reporters = append(reporters, p.detector)
+	}
+	for _, sink := range p.sinks {
+		if reporter, ok := sink.(StatsReporter); ok {
+			reporters = append(reporters, reporter)
+		}
+	}
+	return reporters
+}
+
+// Stop cancels every component, waits for them to return, closes
+// subscriber channels and releases resources such as GeoIP databases
+func (p *Pipeline) Stop() {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.running = false
+	p.cancel()
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	for _, ch := range p.subscribers {
+		close(ch)
+	}
+	p.closeResources()
+}
+
+// dispatch fans detector output out to callbacks, sinks and subscribers,
+// adding a stats message every statsInterval
+func (p *Pipeline) dispatch(input <-chan models.Message, sinkInputs []chan models.Message) {
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			if !p.broadcast(models.NewStatsMessage(p.Stats()), sinkInputs) {
+				return
+			}
+		case message, ok := <-input:
+			if !ok {
+				return
+			}
+
+			if metrics, ok := message.Metrics(); ok {
+				for _, fn := range p.onMetrics {
+					fn(metrics)
+				}
+			} else if anomaly, ok := message.Anomaly(); ok {
+				for _, fn := range p.onAnomaly {
+					fn(anomaly)
+				}
+				if incident, ok := message.Incident(); ok {
+					for _, fn := range p.onIncident {
+						fn(incident)
+					}
+				}
+			}
+
+			if !p.broadcast(message, sinkInputs) {
+				return
+			}
+		}
+	}
+}
+
+// broadcast sends a message to every sink and subscriber
+func (p *Pipeline) broadcast(message models.Message, sinkInputs []chan models.Message) bool {
+	for _, out := range sinkInputs {
+		if !p.send(out, message) {
+			return false
+		}
+	}
+	for _, out := range p.subscribers {
+		if !p.send(out, message) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Pipeline) send(out chan<- models.Message, message models.Message) bool {
+	select {
+	case out <- message:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+func (p *Pipeline) goRun(fn func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		fn()
+	}()
+}
+
+func (p *Pipeline) closeResources() {
+	for _, closer := range p.closers {
+		closer.Close()
+	}
+	p.closers = nil
+}
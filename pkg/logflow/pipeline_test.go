@@ -0,0 +1,222 @@
+package logflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+// alwaysAnomalous reports one anomaly per evaluation with the request count
+type alwaysAnomalous struct{}
+
+func (alwaysAnomalous) Detect(current *models.Metrics, historical []models.Metrics) []models.Anomaly {
+	total := 0
+	for _, count := range current.StatusCodes {
+		total += count
+	}
+	return []models.Anomaly{{Type: models.AnomalyTypePattern, Metric: "test", ActualValue: float64(total)}}
+}
+
+// TestPipeline_PushAndSubscribe tests pushing entries through configured
+// stages and receiving detector output via callbacks and subscriptions
+func TestPipeline_PushAndSubscribe(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.FilterConfig.Exclude = []config.FilterRule{{Field: "path", Prefix: "/health"}}
+
+	var mu sync.Mutex
+	var anomalies []models.Anomaly
+	pipeline, err := New(cfg,
+		WithAlgorithm(alwaysAnomalous{}),
+		OnAnomaly(func(anomaly models.Anomaly) {
+			mu.Lock()
+			anomalies = append(anomalies, anomaly)
+			mu.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to build pipeline: %v", err)
+	}
+	messages := pipeline.Subscribe(10)
+
+	if err := pipeline.Push(context.Background(), &models.LogEntry{}); err != ErrNotRunning {
+		t.Errorf("Expected ErrNotRunning before Start, got %v", err)
+	}
+
+	if err := pipeline.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/api/users/1", "/api/users/2", "/health"} {
+		if err := pipeline.Push(context.Background(), &models.LogEntry{Path: path, StatusCode: 200}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var anomaly models.Anomaly
+	deadline := time.After(5 * time.Second)
+	for anomaly.Metric == "" {
+		select {
+		case message := <-messages:
+			if a, ok := message.Anomaly(); ok && a.ActualValue > 0 {
+				anomaly = a
+			}
+		case <-deadline:
+			t.Fatal("Timed out waiting for anomaly")
+		}
+	}
+	if anomaly.ActualValue != 2 {
+		t.Errorf("Expected 2 requests after filtering /health, got %v", anomaly.ActualValue)
+	}
+
+	pipeline.Stop()
+	for range messages {
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(anomalies) == 0 {
+		t.Error("Expected OnAnomaly callback to be called")
+	}
+
+	if err := pipeline.Push(context.Background(), &models.LogEntry{}); err != ErrNotRunning {
+		t.Errorf("Expected ErrNotRunning after Stop, got %v", err)
+	}
+}
+
+// TestNew_InvalidConfig tests stage configuration errors
+func TestNew_InvalidConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.NormalizeConfig.Routes = []string{"no-leading-slash"}
+
+	if _, err := New(cfg); err == nil {
+		t.Error("Expected error for invalid route template")
+	}
+}
+
+// TestNew_UnknownNames tests configuration errors for unregistered names
+func TestNew_UnknownNames(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DetectorConfig.Algorithm = "prophet"
+	if _, err := New(cfg); err == nil || !strings.Contains(err.Error(), `unknown detection algorithm "prophet" (available: `) {
+		t.Errorf("Expected unknown algorithm error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.LogFormat = "syslog"
+	if _, err := New(cfg); err == nil || !strings.Contains(err.Error(), `unknown log format "syslog"`) {
+		t.Errorf("Expected unknown format error, got %v", err)
+	}
+
+	cfg = DefaultConfig()
+	cfg.Sinks = []SinkConfig{{Type: "kafka"}}
+	if _, err := New(cfg); err == nil || !strings.Contains(err.Error(), `unknown sink type "kafka" (available: jsonl, log`) {
+		t.Errorf("Expected unknown sink error, got %v", err)
+	}
+}
+
+// TestRegisterAlgorithm_Options tests decoding algorithm options from YAML
+func TestRegisterAlgorithm_Options(t *testing.T) {
+	var gotLimit int
+	RegisterAlgorithm("test_static", func(cfg DetectorConfig, decode DecodeFunc) (DetectionAlgorithm, error) {
+		var options struct {
+			Limit int `yaml:"limit"`
+		}
+		if err := decode(&options); err != nil {
+			return nil, err
+		}
+		gotLimit = options.Limit
+		return alwaysAnomalous{}, nil
+	})
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yamlConfig := "detector:\n  algorithm: test_static\n  algorithm_options:\n    limit: 42\n"
+	if err := os.WriteFile(path, []byte(yamlConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(cfg); err != nil {
+		t.Fatalf("Failed to build pipeline: %v", err)
+	}
+	if gotLimit != 42 {
+		t.Errorf("Expected limit 42 from algorithm_options, got %d", gotLimit)
+	}
+}
+
+// badLineSource reports a parse failure containing an email address
+type badLineSource struct{}
+
+func (badLineSource) Start(ctx context.Context, output chan<- models.Message) {}
+
+func (badLineSource) ReportStats(stats *models.PipelineStats) {
+	stats.AddSource("test", models.SourceStats{Lines: 1, ParseErrors: 1})
+	stats.AddParseErrors("json", 1, []string{"broken line from alice@example.com"})
+}
+
+// TestPipeline_Stats tests the self-observability snapshot
+func TestPipeline_Stats(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.FilterConfig.Exclude = []config.FilterRule{{Name: "health", Field: "path", Prefix: "/health"}}
+	cfg.RedactConfig.Enabled = true
+
+	pipeline, err := New(cfg, WithSource(badLineSource{}))
+	if err != nil {
+		t.Fatalf("Failed to build pipeline: %v", err)
+	}
+	if err := pipeline.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer pipeline.Stop()
+
+	for _, path := range []string{"/api", "/health"} {
+		if err := pipeline.Push(context.Background(), &models.LogEntry{Path: path}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stats models.PipelineStats
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats = pipeline.Stats()
+		if stats.Detector.Evaluations > 0 && stats.Stages["filter"]["received"] == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for stats, last %+v", stats)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if stats.Stages["filter"]["excluded:health"] != 1 {
+		t.Errorf("Expected 1 entry excluded by the health rule, got %v", stats.Stages["filter"])
+	}
+	if stats.Sources["test"].ParseErrors != 1 {
+		t.Errorf("Expected source stats from the reporter, got %v", stats.Sources)
+	}
+	if sample := stats.ParseErrors["json"].Samples[0]; strings.Contains(sample, "alice@example.com") {
+		t.Errorf("Expected parse error sample to be redacted, got %q", sample)
+	}
+	if stats.UptimeSeconds <= 0 {
+		t.Errorf("Expected positive uptime, got %v", stats.UptimeSeconds)
+	}
+
+	var names []string
+	for _, channel := range stats.Channels {
+		names = append(names, channel.Name)
+		if channel.Capacity != channelBuffer {
+			t.Errorf("Expected capacity %d for %s, got %d", channelBuffer, channel.Name, channel.Capacity)
+		}
+	}
+	if got := strings.Join(names, ","); !strings.HasPrefix(got, "input,filter,") || !strings.Contains(got, ",redact,detector") {
+		t.Errorf("Unexpected channels: %s", got)
+	}
+}
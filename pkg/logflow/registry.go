@@ -0,0 +1,82 @@
+package logflow
+
+import (
+	"github.com/justin4957/logflow-anomaly-detector/internal/analyzer"
+	"github.com/justin4957/logflow-anomaly-detector/internal/config"
+	"github.com/justin4957/logflow-anomaly-detector/internal/parser"
+	"github.com/justin4957/logflow-anomaly-detector/internal/registry"
+)
+
+// DecodeFunc decodes a component's options block from the configuration
+// into v, usually a pointer to a struct with yaml tags
+type DecodeFunc = registry.DecodeFunc
+
+// Parser parses raw log lines
+type Parser = parser.LogParser
+
+// ParserFactory creates a parser for a log format
+type ParserFactory = parser.Factory
+
+// DetectionAlgorithm is implemented by anomaly detectors
+type DetectionAlgorithm = analyzer.DetectionAlgorithm
+
+// DetectorConfig holds the detector settings passed to algorithm factories
+type DetectorConfig = config.DetectorConfig
+
+// AlgorithmFactory creates a detection algorithm
+type AlgorithmFactory = analyzer.AlgorithmFactory
+
+// SinkConfig selects a registered sink in Config.Sinks
+type SinkConfig = config.SinkConfig
+
+// SinkFactory creates a sink from the pipeline configuration and the
+// sink's options block. Sinks implementing io.Closer are closed by Stop.
+type SinkFactory func(cfg *Config, decode DecodeFunc) (Sink, error)
+
+var sinks = registry.New[SinkFactory]("sink type")
+
+// RegisterParser makes a log format available as log_format. Options come
+// from log_format_options. It panics if the name is already registered.
+func RegisterParser(format string, factory ParserFactory) {
+	parser.Register(format, factory)
+}
+
+// RegisterAlgorithm makes a detection algorithm available as
+// detector.algorithm. Options come from detector.algorithm_options. It panics
+// if the name is already registered.
+func RegisterAlgorithm(name string, factory AlgorithmFactory) {
+	analyzer.RegisterAlgorithm(name, factory)
+}
+
+// RegisterSink makes a sink available as a sinks[].type. Options come from
+// the entry's options block. It panics if the name is already registered.
+func RegisterSink(name string, factory SinkFactory) {
+	sinks.Register(name, factory)
+}
+
+// Parsers returns the registered log format names
+func Parsers() []string {
+	return parser.Formats()
+}
+
+// Algorithms returns the registered detection algorithm names
+func Algorithms() []string {
+	return analyzer.Algorithms()
+}
+
+// Sinks returns the registered sink type names
+func Sinks() []string {
+	return sinks.Names()
+}
+
+// NewSink creates a registered sink
+func NewSink(name string, cfg *Config, decode DecodeFunc) (Sink, error) {
+	factory, err := sinks.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if decode == nil {
+		decode = registry.NoOptions
+	}
+	return factory(cfg, decode)
+}
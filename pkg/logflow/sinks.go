@@ -0,0 +1,125 @@
+package logflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/models"
+)
+
+func init() {
+	RegisterSink("log", newLogSink)
+	RegisterSink("jsonl", newJSONLinesSink)
+}
+
+// logSink writes anomalies to the standard logger
+type logSink struct {
+	minSeverity models.Severity
+}
+
+func newLogSink(_ *Config, decode DecodeFunc) (Sink, error) {
+	var options struct {
+		MinSeverity models.Severity `yaml:"min_severity"`
+	}
+	if err := decode(&options); err != nil {
+		return nil, err
+	}
+	if options.MinSeverity != "" && options.MinSeverity.Rank() == 0 {
+		return nil, fmt.Errorf("unknown min_severity %q", options.MinSeverity)
+	}
+	return &logSink{minSeverity: options.MinSeverity}, nil
+}
+
+func (s *logSink) Start(ctx context.Context, input <-chan models.Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-input:
+			if !ok {
+				return
+			}
+			if incident, ok := message.Incident(); ok {
+				if incident.PeakSeverity.Rank() >= s.minSeverity.Rank() {
+					anomaly := incident.Anomaly
+					log.Printf("Incident %s %s [%s] %s: %s (actual %.2f, expected %.2f, %d detections)",
+						incident.ID, incident.State, incident.PeakSeverity, incident.Type, anomaly.Description,
+						anomaly.ActualValue, anomaly.ExpectedValue, incident.Detections)
+				}
+				continue
+			}
+			anomaly, ok := message.Anomaly()
+			if !ok || anomaly.Severity.Rank() < s.minSeverity.Rank() {
+				continue
+			}
+			log.Printf("Anomaly [%s] %s: %s (actual %.2f, expected %.2f)",
+				anomaly.Severity, anomaly.Type, anomaly.Description, anomaly.ActualValue, anomaly.ExpectedValue)
+		}
+	}
+}
+
+// jsonLinesSink writes message envelopes as newline-delimited JSON
+type jsonLinesSink struct {
+	writer io.Writer
+	file   *os.File // Nil when writing to stdout
+	kinds  map[models.MessageKind]bool
+}
+
+func newJSONLinesSink(_ *Config, decode DecodeFunc) (Sink, error) {
+	var options struct {
+		Path  string   `yaml:"path"`  // Empty or "-" writes to stdout
+		Kinds []string `yaml:"kinds"` // Message kinds to write; empty writes all
+	}
+	if err := decode(&options); err != nil {
+		return nil, err
+	}
+
+	sink := &jsonLinesSink{writer: os.Stdout}
+	if len(options.Kinds) > 0 {
+		sink.kinds = make(map[models.MessageKind]bool, len(options.Kinds))
+		for _, kind := range options.Kinds {
+			sink.kinds[models.MessageKind(kind)] = true
+		}
+	}
+	if options.Path != "" && options.Path != "-" {
+		file, err := os.OpenFile(options.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", options.Path, err)
+		}
+		sink.writer = file
+		sink.file = file
+	}
+	return sink, nil
+}
+
+func (s *jsonLinesSink) Start(ctx context.Context, input <-chan models.Message) {
+	encoder := json.NewEncoder(s.writer)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-input:
+			if !ok {
+				return
+			}
+			if s.kinds != nil && !s.kinds[message.Kind] {
+				continue
+			}
+			if err := encoder.Encode(message); err != nil {
+				log.Printf("jsonl sink write error: %v", err)
+			}
+		}
+	}
+}
+
+// Close closes the output file
+func (s *jsonLinesSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,44 @@
+package logflow
+
+import (
+	"github.com/justin4957/logflow-anomaly-detector/internal/dashboard"
+	"github.com/justin4957/logflow-anomaly-detector/internal/stream"
+)
+
+// WithFileSource tails cfg.LogPath, or every matching file in
+// cfg.WatchConfig.Dir when set, parsing lines with the configured log format
+func WithFileSource() Option {
+	return func(p *Pipeline) {
+		p.builders = append(p.builders, func() {
+			var ls *stream.LogStream
+			if p.cfg.WatchConfig.Dir != "" {
+				ls = stream.NewDirectoryLogStream(p.cfg.WatchConfig, p.cfg.LogFormat, p.parser)
+			} else {
+				ls = stream.NewLogStream(p.cfg.LogPath, p.cfg.LogFormat, p.parser)
+			}
+			p.sources = append(p.sources, ls)
+		})
+	}
+}
+
+// WithForwardSource listens for the Fluent Forward protocol on the configured
+// address
+func WithForwardSource() Option {
+	return func(p *Pipeline) {
+		p.sources = append(p.sources, stream.NewForwardListener(p.cfg.ForwardConfig))
+	}
+}
+
+// WithDashboard serves the web dashboard as a sink. When ingestion is
+// enabled, entries posted to /api/ingest are parsed with the configured log
+// format and fed into the pipeline.
+func WithDashboard() Option {
+	return func(p *Pipeline) {
+		p.builders = append(p.builders, func() {
+			server := dashboard.NewServer(p.cfg.DashboardConfig)
+			server.EnableIngest(p.parser, p.input)
+			server.SetStatsProvider(p.Stats)
+			p.sinks = append(p.sinks, server)
+		})
+	}
+}
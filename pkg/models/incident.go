@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// IncidentState is the lifecycle state of an incident
+type IncidentState string
+
+const (
+	IncidentOpen     IncidentState = "open"     // First detection
+	IncidentOngoing  IncidentState = "ongoing"  // Detected again, or escalated to a higher severity
+	IncidentResolved IncidentState = "resolved" // Quiet for the configured period
+)
+
+// Incident groups consecutive anomalies with the same type, metric and
+// dimension key. It is emitted on state transitions rather than on every
+// detection.
+type Incident struct {
+	ID           string        `json:"id"`
+	State        IncidentState `json:"state"`
+	Type         AnomalyType   `json:"type"`
+	Metric       string        `json:"metric"`
+	Dimension    string        `json:"dimension,omitempty"`
+	Key          string        `json:"key,omitempty"`
+	StartedAt    time.Time     `json:"started_at"`
+	LastSeen     time.Time     `json:"last_seen"`
+	ResolvedAt   *time.Time    `json:"resolved_at,omitempty"`
+	PeakSeverity Severity      `json:"peak_severity"`
+	Detections   int           `json:"detections"` // Anomalies grouped so far
+	Anomaly      Anomaly       `json:"anomaly"`    // Latest detection
+}
@@ -1,7 +1,12 @@
 package models
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/justin4957/logflow-anomaly-detector/pkg/sketch"
 )
 
 // LogEntry represents a parsed log entry
@@ -16,20 +21,109 @@ type LogEntry struct {
 	ResponseTime float64          `json:"response_time,omitempty"`
 	Method      string            `json:"method,omitempty"`
 	Path        string            `json:"path,omitempty"`
+	RawPath     string            `json:"raw_path,omitempty"` // Original path when Path has been normalized
 	Extra       map[string]interface{} `json:"extra,omitempty"`
 }
 
+// Extra keys written by the GeoIP enrichment stage
+const (
+	ExtraGeoCountry = "geo_country"
+	ExtraGeoCity    = "geo_city"
+	ExtraGeoASN     = "geo_asn"
+	ExtraGeoASOrg   = "geo_as_org"
+)
+
+// Extra keys written by the user-agent enrichment stage
+const (
+	ExtraUABrowser = "ua_browser"
+	ExtraUAOS      = "ua_os"
+	ExtraUADevice  = "ua_device"
+	ExtraUAClass   = "ua_class" // "human", "crawler", "tool", "headless", "scanner" or "missing"
+	ExtraUABot     = "ua_bot"   // Bot or client name, e.g. "Googlebot" or "curl"
+)
+
+// FieldNames are the names Field accepts besides "extra.<key>"
+var FieldNames = []string{"level", "message", "source", "user_agent", "ip_address", "status_code", "method", "path", "raw_path"}
+
+// IsField reports whether Field accepts a name, so configured names can be
+// checked before any entry is seen
+func IsField(name string) bool {
+	if key, ok := strings.CutPrefix(name, "extra."); ok {
+		return key != ""
+	}
+	for _, field := range FieldNames {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Field returns a LogEntry field by its JSON name as a string, so rules and
+// dimensions can be configured by name. Values in Extra are addressed as
+// "extra.<key>". The boolean is false when the field is unknown or unset.
+func (e *LogEntry) Field(name string) (string, bool) {
+	switch name {
+	case "level":
+		return e.Level, e.Level != ""
+	case "message":
+		return e.Message, e.Message != ""
+	case "source":
+		return e.Source, e.Source != ""
+	case "user_agent":
+		return e.UserAgent, e.UserAgent != ""
+	case "ip_address":
+		return e.IPAddress, e.IPAddress != ""
+	case "status_code":
+		return strconv.Itoa(e.StatusCode), e.StatusCode != 0
+	case "method":
+		return e.Method, e.Method != ""
+	case "path":
+		return e.Path, e.Path != ""
+	case "raw_path":
+		return e.RawPath, e.RawPath != ""
+	}
+
+	if key, ok := strings.CutPrefix(name, "extra."); ok {
+		value, exists := e.Extra[key]
+		if !exists || value == nil {
+			return "", false
+		}
+		if str, isString := value.(string); isString {
+			return str, true
+		}
+		return fmt.Sprint(value), true
+	}
+
+	return "", false
+}
+
 // Anomaly represents a detected anomaly
 type Anomaly struct {
-	Timestamp     time.Time   `json:"timestamp"`
-	Type          AnomalyType `json:"type"`
-	Severity      Severity    `json:"severity"`
-	Description   string      `json:"description"`
-	Metric        string      `json:"metric"`
-	ActualValue   float64     `json:"actual_value"`
-	ExpectedValue float64     `json:"expected_value"`
-	Deviation     float64     `json:"deviation"`
-	RelatedLogs   []LogEntry  `json:"related_logs,omitempty"`
+	Timestamp     time.Time     `json:"timestamp"`
+	Type          AnomalyType   `json:"type"`
+	Severity      Severity      `json:"severity"`
+	Description   string        `json:"description"`
+	Metric        string        `json:"metric"`
+	ActualValue   float64       `json:"actual_value"`
+	ExpectedValue float64       `json:"expected_value"`
+	Deviation     float64       `json:"deviation"`
+	Probability   float64       `json:"probability,omitempty"`  // Set by detectors that estimate one, e.g. changepoint probability
+	Detectors     []string      `json:"detectors,omitempty"`    // Ensemble members that flagged the metric
+	Dimension     string        `json:"dimension,omitempty"`    // Set for per-dimension anomalies, e.g. "path"
+	Key           string        `json:"key,omitempty"`          // The dimension's value, e.g. "/api/users/{id}"
+	RelatedLogs   []LogEntry    `json:"related_logs,omitempty"` // Entries from the window that explain the anomaly
+	Contributors  []Contributor `json:"contributors,omitempty"` // Dimension values behind the anomaly, largest share first
+}
+
+// Contributor is a dimension value that accounts for part of an anomaly,
+// e.g. path /api/checkout for 82% of the extra errors
+type Contributor struct {
+	Dimension string  `json:"dimension"` // "path", "status", "ip", "user_agent" or "source"
+	Value     string  `json:"value"`
+	Share     float64 `json:"share"`    // Part of the dimension's excess (or deficit) over the baseline (0-1)
+	Actual    float64 `json:"actual"`   // Value's count or total response time in the window
+	Expected  float64 `json:"expected"` // Baseline mean, scaled to the window's traffic for errors and response time
 }
 
 // AnomalyType represents the type of anomaly detected
@@ -41,6 +135,8 @@ const (
 	AnomalyTypeResponseTime   AnomalyType = "response_time"
 	AnomalyTypePattern        AnomalyType = "pattern"
 	AnomalyTypeStatusCode     AnomalyType = "status_code"
+	AnomalyTypeGeoShift       AnomalyType = "geo_shift"
+	AnomalyTypeBotSurge       AnomalyType = "bot_surge"
 )
 
 // Severity represents anomaly severity
@@ -53,16 +149,70 @@ const (
 	SeverityCritical Severity = "critical"
 )
 
+// Rank orders severities from 1 (low) to 4 (critical); unknown values rank 0
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	case SeverityCritical:
+		return 4
+	}
+	return 0
+}
+
 // Metrics represents aggregated metrics
 type Metrics struct {
 	Timestamp       time.Time         `json:"timestamp"`
 	RequestsPerSec  float64           `json:"requests_per_sec"`
 	ErrorRate       float64           `json:"error_rate"`
 	AvgResponseTime float64           `json:"avg_response_time"`
+	ResponseTimeP50 float64           `json:"response_time_p50"`
+	ResponseTimeP90 float64           `json:"response_time_p90"`
+	ResponseTimeP99 float64           `json:"response_time_p99"`
+	ResponseTimes   *sketch.DDSketch  `json:"-"` // Response time distribution of the window, for other percentiles
 	StatusCodes     map[int]int       `json:"status_codes"`
 	TopPaths        []PathCount       `json:"top_paths"`
 	TopIPs          []IPCount         `json:"top_ips"`
 	TopUserAgents   []UserAgentCount  `json:"top_user_agents"`
+	TopCountries    []CountryCount    `json:"top_countries,omitempty"`
+	TopASNs         []ASNCount        `json:"top_asns,omitempty"`
+	Countries       map[string]int    `json:"-"` // Requests per country in the window, beyond the top 10
+	ASNs            map[string]int    `json:"-"` // Requests per ASN in the window, beyond the top 10
+	TopUAFamilies   []UserAgentCount  `json:"top_ua_families,omitempty"` // e.g. "Chrome / Windows / desktop"
+	ClientClasses   map[string]int    `json:"client_classes,omitempty"`
+	BotRate         float64           `json:"bot_rate"` // Share of classified requests not from a human browser
+	Samples         []LogEntry        `json:"-"` // Entries sampled from the window, biased toward errors and slow requests
+	Breakdowns      map[string][]BreakdownCount `json:"-"` // Top values per dimension ("path", "status", ...), for contributor analysis
+}
+
+// BreakdownCount is a dimension value's totals in a window
+type BreakdownCount struct {
+	Value        string  `json:"value"`
+	Requests     int     `json:"requests"`
+	Errors       int     `json:"errors"`
+	ResponseTime float64 `json:"response_time"` // Sum in milliseconds
+}
+
+// ResponseTimePercentile returns the p-th percentile (0-100) of response
+// times in the window. Without the sketch, e.g. for metrics decoded from
+// JSON, only p50, p90 and p99 are available.
+func (m *Metrics) ResponseTimePercentile(p float64) float64 {
+	if m.ResponseTimes != nil {
+		return m.ResponseTimes.Quantile(p / 100)
+	}
+	switch p {
+	case 50:
+		return m.ResponseTimeP50
+	case 90:
+		return m.ResponseTimeP90
+	case 99:
+		return m.ResponseTimeP99
+	}
+	return 0
 }
 
 // PathCount represents request count per path
@@ -82,3 +232,15 @@ type UserAgentCount struct {
 	UserAgent string `json:"user_agent"`
 	Count     int    `json:"count"`
 }
+
+// CountryCount represents request count per country (ISO code)
+type CountryCount struct {
+	Country string `json:"country"`
+	Count   int    `json:"count"`
+}
+
+// ASNCount represents request count per autonomous system
+type ASNCount struct {
+	ASN   string `json:"asn"`
+	Count int    `json:"count"`
+}
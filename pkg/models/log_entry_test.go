@@ -0,0 +1,24 @@
+package models
+
+import "testing"
+
+// TestIsField tests that every name in FieldNames is read by Field
+func TestIsField(t *testing.T) {
+	entry := &LogEntry{
+		Level: "error", Message: "m", Source: "s", UserAgent: "ua", IPAddress: "10.0.0.1",
+		StatusCode: 500, Method: "GET", Path: "/p", RawPath: "/p?q", Extra: map[string]interface{}{"k": 1},
+	}
+	for _, name := range append(FieldNames, "extra.k") {
+		if !IsField(name) {
+			t.Errorf("Expected %s to be a field", name)
+		}
+		if _, ok := entry.Field(name); !ok {
+			t.Errorf("Expected Field to read %s", name)
+		}
+	}
+	for _, name := range []string{"staus_code", "timestamp", "response_time", "extra.", ""} {
+		if IsField(name) {
+			t.Errorf("Expected %q not to be a field", name)
+		}
+	}
+}
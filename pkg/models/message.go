@@ -0,0 +1,150 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MessageKind identifies the payload carried by a Message
+type MessageKind string
+
+const (
+	MessageKindLog        MessageKind = "log"         // *LogEntry
+	MessageKindMetrics    MessageKind = "metrics"     // *Metrics
+	MessageKindAnomaly    MessageKind = "anomaly"     // Anomaly
+	MessageKindIncident   MessageKind = "incident"    // Incident
+	MessageKindStats      MessageKind = "stats"       // PipelineStats
+	MessageKindParseError MessageKind = "parse_error" // ParseError
+)
+
+// Message is the envelope passed between pipeline stages and sent to
+// dashboard clients over the WebSocket
+type Message struct {
+	Kind      MessageKind `json:"kind"`
+	Timestamp time.Time   `json:"timestamp"`
+	Source    string      `json:"source,omitempty"`
+	Payload   interface{} `json:"payload"`
+}
+
+// ParseError describes a line that could not be parsed
+type ParseError struct {
+	Source string `json:"source,omitempty"`
+	Format string `json:"format"`
+	Line   string `json:"line"`
+	Error  string `json:"error"`
+}
+
+// NewLogMessage wraps a log entry
+func NewLogMessage(entry *LogEntry) Message {
+	return Message{Kind: MessageKindLog, Timestamp: entry.Timestamp, Source: entry.Source, Payload: entry}
+}
+
+// NewMetricsMessage wraps a metrics snapshot
+func NewMetricsMessage(metrics *Metrics) Message {
+	return Message{Kind: MessageKindMetrics, Timestamp: metrics.Timestamp, Payload: metrics}
+}
+
+// NewAnomalyMessage wraps a detected anomaly
+func NewAnomalyMessage(anomaly Anomaly) Message {
+	return Message{Kind: MessageKindAnomaly, Timestamp: anomaly.Timestamp, Payload: anomaly}
+}
+
+// NewIncidentMessage wraps an incident state transition
+func NewIncidentMessage(incident Incident) Message {
+	return Message{Kind: MessageKindIncident, Timestamp: incident.LastSeen, Payload: incident}
+}
+
+// NewStatsMessage wraps a pipeline stats snapshot
+func NewStatsMessage(stats PipelineStats) Message {
+	return Message{Kind: MessageKindStats, Timestamp: stats.Timestamp, Payload: stats}
+}
+
+// NewParseErrorMessage wraps a parse failure
+func NewParseErrorMessage(parseErr ParseError) Message {
+	return Message{Kind: MessageKindParseError, Timestamp: time.Now(), Source: parseErr.Source, Payload: parseErr}
+}
+
+// LogEntry returns the payload of a log message
+func (m Message) LogEntry() (*LogEntry, bool) {
+	entry, ok := m.Payload.(*LogEntry)
+	return entry, ok && m.Kind == MessageKindLog
+}
+
+// Metrics returns the payload of a metrics message
+func (m Message) Metrics() (*Metrics, bool) {
+	metrics, ok := m.Payload.(*Metrics)
+	return metrics, ok && m.Kind == MessageKindMetrics
+}
+
+// Anomaly returns the payload of an anomaly message, or the latest detection
+// of an incident message, so anomaly consumers see one anomaly per incident
+// transition
+func (m Message) Anomaly() (Anomaly, bool) {
+	if incident, ok := m.Incident(); ok {
+		return incident.Anomaly, true
+	}
+	anomaly, ok := m.Payload.(Anomaly)
+	return anomaly, ok && m.Kind == MessageKindAnomaly
+}
+
+// Incident returns the payload of an incident message
+func (m Message) Incident() (Incident, bool) {
+	incident, ok := m.Payload.(Incident)
+	return incident, ok && m.Kind == MessageKindIncident
+}
+
+// UnmarshalJSON decodes the payload into the type matching Kind, so messages
+// read back from the WebSocket have the same payload types as in-process ones
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Kind      MessageKind     `json:"kind"`
+		Timestamp time.Time       `json:"timestamp"`
+		Source    string          `json:"source,omitempty"`
+		Payload   json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var payload interface{}
+	switch raw.Kind {
+	case MessageKindLog:
+		payload = &LogEntry{}
+	case MessageKindMetrics:
+		payload = &Metrics{}
+	case MessageKindAnomaly:
+		payload = &Anomaly{}
+	case MessageKindIncident:
+		payload = &Incident{}
+	case MessageKindStats:
+		payload = &PipelineStats{}
+	case MessageKindParseError:
+		payload = &ParseError{}
+	default:
+		return fmt.Errorf("unknown message kind %q", raw.Kind)
+	}
+	if len(raw.Payload) > 0 {
+		if err := json.Unmarshal(raw.Payload, payload); err != nil {
+			return fmt.Errorf("invalid %s payload: %w", raw.Kind, err)
+		}
+	}
+
+	// Value payloads are stored by value, matching their constructors
+	switch p := payload.(type) {
+	case *Anomaly:
+		payload = *p
+	case *Incident:
+		payload = *p
+	case *PipelineStats:
+		payload = *p
+	case *ParseError:
+		payload = *p
+	}
+
+	m.Kind = raw.Kind
+	m.Timestamp = raw.Timestamp
+	m.Source = raw.Source
+	m.Payload = payload
+	return nil
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestMessage_JSONRoundTrip tests that payloads decode to their kind's type
+func TestMessage_JSONRoundTrip(t *testing.T) {
+	timestamp := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	messages := []Message{
+		NewLogMessage(&LogEntry{Timestamp: timestamp, Source: "api", Message: "hello", StatusCode: 200}),
+		NewMetricsMessage(&Metrics{Timestamp: timestamp, RequestsPerSec: 12.5}),
+		NewAnomalyMessage(Anomaly{Timestamp: timestamp, Type: AnomalyTypeErrorRate, Severity: SeverityHigh}),
+		NewParseErrorMessage(ParseError{Source: "app.log", Format: "json", Line: "{", Error: "unexpected end"}),
+		NewIncidentMessage(Incident{ID: "20261017-120000-1", State: IncidentResolved, LastSeen: timestamp, ResolvedAt: &timestamp,
+			Anomaly: Anomaly{Timestamp: timestamp, Type: AnomalyTypeErrorRate, Severity: SeverityLow}}),
+	}
+
+	for _, original := range messages {
+		data, err := json.Marshal(original)
+		if err != nil {
+			t.Fatalf("Failed to marshal %s: %v", original.Kind, err)
+		}
+
+		var decoded Message
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal %s: %v", original.Kind, err)
+		}
+		if decoded.Kind != original.Kind || decoded.Source != original.Source {
+			t.Errorf("Envelope mismatch: got %+v, want %+v", decoded, original)
+		}
+
+		switch decoded.Kind {
+		case MessageKindLog:
+			if entry, ok := decoded.LogEntry(); !ok || entry.Message != "hello" {
+				t.Errorf("Unexpected log payload: %#v", decoded.Payload)
+			}
+		case MessageKindMetrics:
+			if metrics, ok := decoded.Metrics(); !ok || metrics.RequestsPerSec != 12.5 {
+				t.Errorf("Unexpected metrics payload: %#v", decoded.Payload)
+			}
+		case MessageKindAnomaly:
+			if anomaly, ok := decoded.Anomaly(); !ok || anomaly.Severity != SeverityHigh {
+				t.Errorf("Unexpected anomaly payload: %#v", decoded.Payload)
+			}
+		case MessageKindIncident:
+			incident, ok := decoded.Incident()
+			if !ok || incident.State != IncidentResolved || !incident.ResolvedAt.Equal(timestamp) {
+				t.Errorf("Unexpected incident payload: %#v", decoded.Payload)
+			}
+			// Anomaly consumers see the incident's latest detection
+			if anomaly, ok := decoded.Anomaly(); !ok || anomaly.Severity != SeverityLow {
+				t.Errorf("Expected the incident's anomaly, got %#v", anomaly)
+			}
+		case MessageKindParseError:
+			if parseErr, ok := decoded.Payload.(ParseError); !ok || parseErr.Error != "unexpected end" {
+				t.Errorf("Unexpected parse error payload: %#v", decoded.Payload)
+			}
+		}
+	}
+}
+
+// TestMessage_KindMismatch tests accessors and unknown kinds
+func TestMessage_KindMismatch(t *testing.T) {
+	message := Message{Kind: MessageKindMetrics, Payload: &LogEntry{}}
+	if _, ok := message.LogEntry(); ok {
+		t.Error("Expected LogEntry accessor to reject a metrics message")
+	}
+
+	var decoded Message
+	if err := json.Unmarshal([]byte(`{"kind":"bogus","payload":{}}`), &decoded); err == nil {
+		t.Error("Expected error for unknown message kind")
+	}
+}
@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// MaxParseErrorSamples is how many recent bad lines are kept per log format
+const MaxParseErrorSamples = 5
+
+// PipelineStats is a snapshot of logflow's own throughput and health
+type PipelineStats struct {
+	Timestamp     time.Time                    `json:"timestamp"`
+	UptimeSeconds float64                      `json:"uptime_seconds"`
+	Sources       map[string]SourceStats       `json:"sources"`
+	ParseErrors   map[string]ParseErrorStats   `json:"parse_errors"` // Keyed by log format
+	Stages        map[string]map[string]uint64 `json:"stages"`       // Stage counters, e.g. filter and redact
+	Dropped       map[string]uint64            `json:"dropped"`      // Entries lost to full buffers or throttling
+	Channels      []ChannelStats               `json:"channels"`
+	Detector      DetectorStats                `json:"detector"`
+}
+
+// SourceStats counts what an input has read
+type SourceStats struct {
+	Lines       uint64 `json:"lines"`
+	Bytes       uint64 `json:"bytes"`
+	ParseErrors uint64 `json:"parse_errors"`
+	LagBytes    int64  `json:"lag_bytes"` // Unread bytes (file size minus read offset) for tailed files
+}
+
+// ParseErrorStats counts parse failures for a log format, with recent
+// examples of the offending lines
+type ParseErrorStats struct {
+	Count   uint64   `json:"count"`
+	Samples []string `json:"samples"`
+}
+
+// ChannelStats reports how full a channel between pipeline stages is
+type ChannelStats struct {
+	Name     string `json:"name"`
+	Depth    int    `json:"depth"`
+	Capacity int    `json:"capacity"`
+}
+
+// DetectorStats reports anomaly detector evaluation timings
+type DetectorStats struct {
+	Evaluations   uint64  `json:"evaluations"`
+	LastLatencyMs float64 `json:"last_latency_ms"`
+	AvgLatencyMs  float64 `json:"avg_latency_ms"`
+	MaxLatencyMs  float64 `json:"max_latency_ms"`
+}
+
+// NewPipelineStats creates an empty snapshot
+func NewPipelineStats() *PipelineStats {
+	return &PipelineStats{
+		Timestamp:   time.Now(),
+		Sources:     make(map[string]SourceStats),
+		ParseErrors: make(map[string]ParseErrorStats),
+		Stages:      make(map[string]map[string]uint64),
+		Dropped:     make(map[string]uint64),
+	}
+}
+
+// AddSource merges counters for a source, so several readers can report
+// under the same name
+func (s *PipelineStats) AddSource(name string, source SourceStats) {
+	current := s.Sources[name]
+	current.Lines += source.Lines
+	current.Bytes += source.Bytes
+	current.ParseErrors += source.ParseErrors
+	current.LagBytes += source.LagBytes
+	s.Sources[name] = current
+}
+
+// AddParseErrors merges parse failures for a format, keeping the most recent
+// MaxParseErrorSamples samples
+func (s *PipelineStats) AddParseErrors(format string, count uint64, samples []string) {
+	current := s.ParseErrors[format]
+	current.Count += count
+	current.Samples = append(current.Samples, samples...)
+	if len(current.Samples) > MaxParseErrorSamples {
+		current.Samples = current.Samples[len(current.Samples)-MaxParseErrorSamples:]
+	}
+	s.ParseErrors[format] = current
+}
@@ -0,0 +1,32 @@
+package models
+
+import "testing"
+
+// TestPipelineStats_Merge tests merging counters from several reporters
+func TestPipelineStats_Merge(t *testing.T) {
+	stats := NewPipelineStats()
+	stats.AddSource("app.log", SourceStats{Lines: 3, Bytes: 30, LagBytes: 5})
+	stats.AddSource("app.log", SourceStats{Lines: 2, Bytes: 20, ParseErrors: 1})
+
+	if got := stats.Sources["app.log"]; got != (SourceStats{Lines: 5, Bytes: 50, ParseErrors: 1, LagBytes: 5}) {
+		t.Errorf("Unexpected merged source stats: %+v", got)
+	}
+
+	stats.AddParseErrors("json", 4, []string{"a", "b", "c", "d"})
+	stats.AddParseErrors("json", 2, []string{"e", "f"})
+
+	parseErrors := stats.ParseErrors["json"]
+	if parseErrors.Count != 6 {
+		t.Errorf("Expected 6 parse errors, got %d", parseErrors.Count)
+	}
+	want := []string{"b", "c", "d", "e", "f"}
+	if len(parseErrors.Samples) != len(want) {
+		t.Fatalf("Expected samples %v, got %v", want, parseErrors.Samples)
+	}
+	for i := range want {
+		if parseErrors.Samples[i] != want[i] {
+			t.Errorf("Expected samples %v, got %v", want, parseErrors.Samples)
+			break
+		}
+	}
+}
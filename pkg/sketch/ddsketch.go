@@ -0,0 +1,172 @@
+// Package sketch provides DDSketch, a mergeable quantile sketch with a
+// relative error guarantee, used for response time percentiles.
+package sketch
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultRelativeAccuracy keeps every quantile within 1% of the true value
+const DefaultRelativeAccuracy = 0.01
+
+// maxBins bounds the sketch size; when exceeded the lowest bins are merged,
+// so high quantiles such as p99 keep their accuracy
+const maxBins = 2048
+
+// minIndexable is the smallest value given its own bin; values at or below
+// it are counted as zero
+const minIndexable = 1e-9
+
+// DDSketch (Masson et al., 2019) maps each positive value to a bin whose
+// bounds grow geometrically by gamma = (1+a)/(1-a), so any quantile it
+// returns is within relative accuracy a of an actual value. Sketches with
+// the same accuracy merge exactly. A DDSketch is not safe for concurrent use.
+type DDSketch struct {
+	relativeAccuracy float64
+	gamma            float64
+	logGamma         float64
+
+	bins   []uint64 // Counts for consecutive keys starting at offset
+	offset int
+	zeros  uint64 // Values at or below minIndexable
+
+	count uint64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+// New creates an empty sketch. An accuracy outside (0, 1) uses
+// DefaultRelativeAccuracy.
+func New(relativeAccuracy float64) *DDSketch {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		relativeAccuracy = DefaultRelativeAccuracy
+	}
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &DDSketch{
+		relativeAccuracy: relativeAccuracy,
+		gamma:            gamma,
+		logGamma:         math.Log(gamma),
+		min:              math.Inf(1),
+		max:              math.Inf(-1),
+	}
+}
+
+// Add records a value
+func (s *DDSketch) Add(value float64) {
+	if math.IsNaN(value) {
+		return
+	}
+	s.count++
+	s.sum += value
+	s.min = math.Min(s.min, value)
+	s.max = math.Max(s.max, value)
+
+	if value <= minIndexable {
+		s.zeros++
+		return
+	}
+	s.addToBin(s.key(value), 1)
+}
+
+// Merge adds the values recorded by another sketch with the same accuracy
+func (s *DDSketch) Merge(other *DDSketch) error {
+	if other == nil {
+		return nil
+	}
+	if other.relativeAccuracy != s.relativeAccuracy {
+		return fmt.Errorf("cannot merge sketches with relative accuracy %v and %v", s.relativeAccuracy, other.relativeAccuracy)
+	}
+	if other.count == 0 {
+		return nil
+	}
+
+	for i, n := range other.bins {
+		if n > 0 {
+			s.addToBin(other.offset+i, n)
+		}
+	}
+	s.zeros += other.zeros
+	s.count += other.count
+	s.sum += other.sum
+	s.min = math.Min(s.min, other.min)
+	s.max = math.Max(s.max, other.max)
+	return nil
+}
+
+// Quantile returns the q quantile (0-1) of the recorded values, or 0 when
+// the sketch is empty
+func (s *DDSketch) Quantile(q float64) float64 {
+	if s.count == 0 || math.IsNaN(q) {
+		return 0
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+
+	rank := q * float64(s.count-1)
+	seen := float64(s.zeros)
+	if seen > rank {
+		return math.Max(s.min, 0)
+	}
+	for i, n := range s.bins {
+		seen += float64(n)
+		if seen > rank {
+			value := 2 * math.Pow(s.gamma, float64(s.offset+i)) / (s.gamma + 1)
+			return math.Min(math.Max(value, s.min), s.max)
+		}
+	}
+	return s.max
+}
+
+// Count returns the number of recorded values
+func (s *DDSketch) Count() uint64 {
+	return s.count
+}
+
+// Sum returns the exact sum of the recorded values
+func (s *DDSketch) Sum() float64 {
+	return s.sum
+}
+
+// Mean returns the exact mean of the recorded values, or 0 when empty
+func (s *DDSketch) Mean() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.sum / float64(s.count)
+}
+
+// key returns the bin of a positive value: gamma^(key-1) < value <= gamma^key
+func (s *DDSketch) key(value float64) int {
+	return int(math.Ceil(math.Log(value) / s.logGamma))
+}
+
+// addToBin grows the bins to cover key and folds the lowest ones together
+// when there are more than maxBins
+func (s *DDSketch) addToBin(key int, n uint64) {
+	switch {
+	case len(s.bins) == 0:
+		s.bins = []uint64{0}
+		s.offset = key
+	case key < s.offset:
+		grown := make([]uint64, s.offset-key+len(s.bins))
+		copy(grown[s.offset-key:], s.bins)
+		s.bins, s.offset = grown, key
+	case key >= s.offset+len(s.bins):
+		s.bins = append(s.bins, make([]uint64, key-s.offset-len(s.bins)+1)...)
+	}
+	s.bins[key-s.offset] += n
+
+	if excess := len(s.bins) - maxBins; excess > 0 {
+		for _, folded := range s.bins[:excess] {
+			s.bins[excess] += folded
+		}
+		s.bins = s.bins[excess:]
+		s.offset += excess
+	}
+}
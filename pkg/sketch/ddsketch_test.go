@@ -0,0 +1,94 @@
+package sketch
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// exactQuantile returns the quantile of sorted values with the rank used by
+// the sketch
+func exactQuantile(sorted []float64, q float64) float64 {
+	return sorted[int(q*float64(len(sorted)-1))]
+}
+
+// TestDDSketch_RelativeAccuracy tests quantiles of a long-tailed sample
+// against the exact values
+func TestDDSketch_RelativeAccuracy(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	s := New(0.01)
+	values := make([]float64, 20000)
+	for i := range values {
+		values[i] = math.Exp(rng.NormFloat64()*1.5 + 4) // Response times in ms
+		s.Add(values[i])
+	}
+	sort.Float64s(values)
+
+	for _, q := range []float64{0.01, 0.5, 0.9, 0.99, 0.999} {
+		expected := exactQuantile(values, q)
+		actual := s.Quantile(q)
+		if math.Abs(actual-expected) > 0.01*expected+1e-9 {
+			t.Errorf("Quantile %v: expected %v within 1%%, got %v", q, expected, actual)
+		}
+	}
+	if s.Quantile(0) != values[0] || s.Quantile(1) != values[len(values)-1] {
+		t.Errorf("Expected exact minimum and maximum, got %v and %v", s.Quantile(0), s.Quantile(1))
+	}
+	if s.Count() != 20000 {
+		t.Errorf("Expected count 20000, got %d", s.Count())
+	}
+}
+
+// TestDDSketch_Merge tests that merged sketches match one sketch of all
+// values
+func TestDDSketch_Merge(t *testing.T) {
+	whole, first, second := New(0.01), New(0.01), New(0.01)
+	for i := 1; i <= 1000; i++ {
+		value := float64(i)
+		whole.Add(value)
+		if i%3 == 0 {
+			first.Add(value)
+		} else {
+			second.Add(value)
+		}
+	}
+	first.Add(0)
+	whole.Add(0)
+
+	if err := first.Merge(second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, q := range []float64{0, 0.25, 0.5, 0.99, 1} {
+		if first.Quantile(q) != whole.Quantile(q) {
+			t.Errorf("Quantile %v: merged %v, whole %v", q, first.Quantile(q), whole.Quantile(q))
+		}
+	}
+	if first.Mean() != whole.Mean() {
+		t.Errorf("Expected mean %v, got %v", whole.Mean(), first.Mean())
+	}
+
+	if err := first.Merge(New(0.05)); err == nil {
+		t.Error("Expected error merging sketches of different accuracy")
+	}
+}
+
+// TestDDSketch_BoundedSize tests that the lowest bins are folded together
+// while high quantiles stay accurate
+func TestDDSketch_BoundedSize(t *testing.T) {
+	s := New(0.01)
+	for exponent := -300.0; exponent <= 300; exponent += 0.01 {
+		s.Add(math.Pow(10, exponent))
+	}
+
+	if len(s.bins) > maxBins {
+		t.Errorf("Expected at most %d bins, got %d", maxBins, len(s.bins))
+	}
+	expected := math.Pow(10, 0.99*600-300)
+	if actual := s.Quantile(0.99); math.Abs(actual-expected) > 0.02*expected {
+		t.Errorf("Expected p99 near %v, got %v", expected, actual)
+	}
+	if empty := New(0); empty.Quantile(0.5) != 0 || empty.relativeAccuracy != DefaultRelativeAccuracy {
+		t.Error("Expected an empty default sketch to return 0")
+	}
+}